@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Config holds the parameters of wallet and API key signature checks
+type Config struct {
+	ChainID   int64
+	MaxAge    time.Duration
+	StorePath string // file of issued API credentials; empty keeps them in memory
+
+	// Browser origins allowed to call the API and open the feed; "*" allows any
+	AllowedOrigins []string
+}
+
+// LoadConfig reads the auth configuration from environment variables
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		ChainID:   31337,
+		MaxAge:    300 * time.Second,
+		StorePath: "data/credentials.json",
+
+		AllowedOrigins: []string{"http://localhost:5173"},
+	}
+
+	if v := os.Getenv("CHAIN_ID"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid CHAIN_ID: %s (must be positive integer)", v)
+		}
+		cfg.ChainID = n
+	}
+
+	if v := os.Getenv("AUTH_MAX_AGE_S"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid AUTH_MAX_AGE_S: %s (must be positive integer)", v)
+		}
+		cfg.MaxAge = time.Duration(n) * time.Second
+	}
+
+	if v := os.Getenv("AUTH_STORE_PATH"); v != "" {
+		cfg.StorePath = v
+	}
+
+	if v, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok {
+		cfg.AllowedOrigins = nil
+		for _, origin := range strings.Split(v, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.AllowedOrigins = append(cfg.AllowedOrigins, strings.TrimSuffix(origin, "/"))
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// AttestMessage is the fixed message of every ClobAuth signature
+const AttestMessage = "This message attests that I control the given wallet"
+
+// Errors returned for signatures that do not authenticate
+var (
+	ErrInvalidAddress   = errors.New("invalid address")
+	ErrInvalidTimestamp = errors.New("timestamp outside the accepted window")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrInvalidAPIKey    = errors.New("invalid API key")
+)
+
+// WalletAuth is a wallet's EIP-712 signature over a ClobAuth message, the
+// same structured data Polymarket's L1 authentication signs
+type WalletAuth struct {
+	Address   string `json:"address"`
+	Timestamp string `json:"timestamp"` // unix seconds
+	Nonce     uint64 `json:"nonce"`
+	Signature string `json:"signature"`
+}
+
+var (
+	domainTypeHash   = crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId)"))
+	clobAuthTypeHash = crypto.Keccak256([]byte("ClobAuth(address address,string timestamp,uint256 nonce,string message)"))
+	domainName       = crypto.Keccak256([]byte("ClobAuthDomain"))
+	domainVersion    = crypto.Keccak256([]byte("1"))
+)
+
+// ClobAuthHash returns the EIP-712 digest a wallet signs to prove it controls address
+func ClobAuthHash(chainID int64, address common.Address, timestamp string, nonce uint64) common.Hash {
+	domain := crypto.Keccak256(
+		domainTypeHash,
+		domainName,
+		domainVersion,
+		common.LeftPadBytes(big.NewInt(chainID).Bytes(), 32),
+	)
+	message := crypto.Keccak256(
+		clobAuthTypeHash,
+		common.LeftPadBytes(address.Bytes(), 32),
+		crypto.Keccak256([]byte(timestamp)),
+		common.LeftPadBytes(new(big.Int).SetUint64(nonce).Bytes(), 32),
+		crypto.Keccak256([]byte(AttestMessage)),
+	)
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domain, message)
+}
+
+// Sign produces the WalletAuth of key, with the 27/28 recovery IDs wallets use
+func Sign(key *ecdsa.PrivateKey, chainID int64, timestamp string, nonce uint64) (WalletAuth, error) {
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	sig, err := crypto.Sign(ClobAuthHash(chainID, address, timestamp, nonce).Bytes(), key)
+	if err != nil {
+		return WalletAuth{}, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return WalletAuth{Address: address.Hex(), Timestamp: timestamp, Nonce: nonce, Signature: hexutil.Encode(sig)}, nil
+}
+
+// Verifier checks wallet signatures against the configured chain and clock
+type Verifier struct {
+	cfg Config
+	now func() time.Time
+}
+
+// NewVerifier creates a verifier
+func NewVerifier(cfg Config) *Verifier {
+	return &Verifier{cfg: cfg, now: time.Now}
+}
+
+// AllowsOrigin reports whether a browser page served from origin may call
+// the API. Requests without an Origin header do not come from a browser and
+// are not subject to it.
+func (v *Verifier) AllowsOrigin(origin string) bool {
+	for _, allowed := range v.cfg.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks that a was signed by its address with a timestamp within
+// MaxAge of the current time, and returns the address
+func (v *Verifier) Verify(a WalletAuth) (common.Address, error) {
+	if !common.IsHexAddress(a.Address) {
+		return common.Address{}, fmt.Errorf("%w: %q", ErrInvalidAddress, a.Address)
+	}
+	address := common.HexToAddress(a.Address)
+
+	if err := v.checkTimestamp(a.Timestamp); err != nil {
+		return address, err
+	}
+
+	sig, err := hexutil.Decode(a.Signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return address, fmt.Errorf("%w: must be 65 bytes of 0x-prefixed hex", ErrInvalidSignature)
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(ClobAuthHash(v.cfg.ChainID, address, a.Timestamp, a.Nonce).Bytes(), sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != address {
+		return address, fmt.Errorf("%w: not signed by %s", ErrInvalidSignature, address.Hex())
+	}
+	return address, nil
+}
+
+// checkTimestamp checks that timestamp, in unix seconds, is within MaxAge of
+// the current time
+func (v *Verifier) checkTimestamp(timestamp string) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q is not unix seconds", ErrInvalidTimestamp, timestamp)
+	}
+	if age := v.now().Sub(time.Unix(ts, 0)); age > v.cfg.MaxAge || age < -v.cfg.MaxAge {
+		return fmt.Errorf("%w: %s is more than %v from now", ErrInvalidTimestamp, timestamp, v.cfg.MaxAge)
+	}
+	return nil
+}
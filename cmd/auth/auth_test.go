@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerify(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	now := time.Unix(1_700_000_000, 0)
+	v := NewVerifier(Config{ChainID: 137, MaxAge: time.Minute})
+	v.now = func() time.Time { return now }
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	good, err := Sign(key, 137, ts, 0)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	addr, err := v.Verify(good)
+	if err != nil || addr != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("Verify = %s, %v", addr.Hex(), err)
+	}
+
+	stale, _ := Sign(key, 137, strconv.FormatInt(now.Unix()-120, 10), 0)
+	wrongChain, _ := Sign(key, 1, ts, 0)
+	impostor, _ := Sign(other, 137, ts, 0)
+	impostor.Address = good.Address
+	replayedNonce := good
+	replayedNonce.Nonce = 1
+
+	for _, tt := range []struct {
+		name string
+		auth WalletAuth
+		want error
+	}{
+		{"stale timestamp", stale, ErrInvalidTimestamp},
+		{"wrong chain", wrongChain, ErrInvalidSignature},
+		{"other signer", impostor, ErrInvalidSignature},
+		{"altered nonce", replayedNonce, ErrInvalidSignature},
+		{"bad address", WalletAuth{Address: "0x12", Timestamp: ts, Signature: good.Signature}, ErrInvalidAddress},
+		{"short signature", WalletAuth{Address: good.Address, Timestamp: ts, Signature: "0x1234"}, ErrInvalidSignature},
+	} {
+		if _, err := v.Verify(tt.auth); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAllowsOrigin(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com/, http://localhost:3000")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	v := NewVerifier(cfg)
+	for origin, want := range map[string]bool{
+		"https://app.example.com": true,
+		"http://localhost:3000":   true,
+		"http://localhost:5173":   false,
+		"https://evil.example":    false,
+		"null":                    false,
+	} {
+		if got := v.AllowsOrigin(origin); got != want {
+			t.Errorf("AllowsOrigin(%q) = %v, want %v", origin, got, want)
+		}
+	}
+
+	if !NewVerifier(Config{AllowedOrigins: []string{"*"}}).AllowsOrigin("https://evil.example") {
+		t.Errorf("expected * to allow any origin")
+	}
+	if NewVerifier(Config{}).AllowsOrigin("http://localhost:5173") {
+		t.Errorf("expected an empty list to allow no origin")
+	}
+}
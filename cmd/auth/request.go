@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Headers of L1 (wallet signature) and L2 (API key) authenticated requests,
+// as named by Polymarket's CLOB
+const (
+	HeaderAddress    = "POLY_ADDRESS"
+	HeaderSignature  = "POLY_SIGNATURE"
+	HeaderTimestamp  = "POLY_TIMESTAMP"
+	HeaderNonce      = "POLY_NONCE"
+	HeaderAPIKey     = "POLY_API_KEY"
+	HeaderPassphrase = "POLY_PASSPHRASE"
+)
+
+// WalletAuthFromHeader reads the L1 headers of a request. POLY_NONCE may be
+// omitted and defaults to 0.
+func WalletAuthFromHeader(h http.Header) (WalletAuth, error) {
+	a := WalletAuth{
+		Address:   h.Get(HeaderAddress),
+		Timestamp: h.Get(HeaderTimestamp),
+		Signature: h.Get(HeaderSignature),
+	}
+	if a.Address == "" || a.Timestamp == "" || a.Signature == "" {
+		return a, fmt.Errorf("%w: %s, %s and %s headers are required", ErrInvalidSignature, HeaderAddress, HeaderTimestamp, HeaderSignature)
+	}
+	if v := h.Get(HeaderNonce); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return a, fmt.Errorf("%w: invalid %s %q", ErrInvalidSignature, HeaderNonce, v)
+		}
+		a.Nonce = n
+	}
+	return a, nil
+}
+
+// SignRequest returns the L2 signature of a request: the base64url HMAC-SHA256,
+// keyed by the decoded secret, of timestamp, method, path and body concatenated
+func SignRequest(secret, timestamp, method, path string, body []byte) (string, error) {
+	key, err := base64.URLEncoding.DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("secret is not base64url: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp + method + path))
+	mac.Write(body)
+	return base64.URLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SetRequestHeaders sets the L2 headers signing a request with creds
+func SetRequestHeaders(h http.Header, creds Credentials, timestamp, method, path string, body []byte) error {
+	sig, err := SignRequest(creds.Secret, timestamp, method, path, body)
+	if err != nil {
+		return err
+	}
+	h.Set(HeaderAddress, creds.Address)
+	h.Set(HeaderSignature, sig)
+	h.Set(HeaderTimestamp, timestamp)
+	h.Set(HeaderAPIKey, creds.APIKey)
+	h.Set(HeaderPassphrase, creds.Passphrase)
+	return nil
+}
+
+// VerifyRequest checks the L2 headers of a request against the credentials in
+// store and returns the credentials it was signed with
+func (v *Verifier) VerifyRequest(store *Store, h http.Header, method, path string, body []byte) (Credentials, error) {
+	apiKey := h.Get(HeaderAPIKey)
+	if apiKey == "" {
+		return Credentials{}, fmt.Errorf("%w: %s header is required", ErrInvalidAPIKey, HeaderAPIKey)
+	}
+	creds, ok := store.Get(apiKey)
+	if !ok || subtle.ConstantTimeCompare([]byte(h.Get(HeaderPassphrase)), []byte(creds.Passphrase)) != 1 {
+		return Credentials{}, fmt.Errorf("%w: unknown key or wrong passphrase", ErrInvalidAPIKey)
+	}
+	if !strings.EqualFold(h.Get(HeaderAddress), creds.Address) {
+		return Credentials{}, fmt.Errorf("%w: %q does not own the API key", ErrInvalidAddress, h.Get(HeaderAddress))
+	}
+
+	timestamp := h.Get(HeaderTimestamp)
+	if err := v.checkTimestamp(timestamp); err != nil {
+		return Credentials{}, err
+	}
+
+	want, err := SignRequest(creds.Secret, timestamp, method, path, body)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if !hmac.Equal([]byte(h.Get(HeaderSignature)), []byte(want)) {
+		return Credentials{}, fmt.Errorf("%w: HMAC does not match the request", ErrInvalidSignature)
+	}
+	return creds, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	now := time.Unix(1_700_000_000, 0)
+	v := NewVerifier(Config{ChainID: 137, MaxAge: time.Minute})
+	v.now = func() time.Time { return now }
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	store, _ := OpenStore("")
+	creds, err := store.Create(crypto.PubkeyToAddress(key.PublicKey), 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	body := []byte(`{"maker":"0x..."}`)
+	signed := func(c Credentials, timestamp string) http.Header {
+		h := http.Header{}
+		if err := SetRequestHeaders(h, c, timestamp, http.MethodPost, "/orders", body); err != nil {
+			t.Fatalf("SetRequestHeaders failed: %v", err)
+		}
+		return h
+	}
+
+	got, err := v.VerifyRequest(store, signed(creds, ts), http.MethodPost, "/orders", body)
+	if err != nil || got != creds {
+		t.Fatalf("VerifyRequest = %+v, %v", got, err)
+	}
+
+	wrongPassphrase := creds
+	wrongPassphrase.Passphrase = "00"
+	wrongAddress := signed(creds, ts)
+	wrongAddress.Set(HeaderAddress, "0x0000000000000000000000000000000000000001")
+
+	for _, tt := range []struct {
+		name   string
+		header http.Header
+		method string
+		path   string
+		body   string
+		want   error
+	}{
+		{"tampered body", signed(creds, ts), http.MethodPost, "/orders", `{"maker":"0xother"}`, ErrInvalidSignature},
+		{"other method", signed(creds, ts), http.MethodDelete, "/orders", string(body), ErrInvalidSignature},
+		{"other path", signed(creds, ts), http.MethodPost, "/auth/api-key", string(body), ErrInvalidSignature},
+		{"stale timestamp", signed(creds, strconv.FormatInt(now.Unix()-120, 10)), http.MethodPost, "/orders", string(body), ErrInvalidTimestamp},
+		{"wrong passphrase", signed(wrongPassphrase, ts), http.MethodPost, "/orders", string(body), ErrInvalidAPIKey},
+		{"wrong address", wrongAddress, http.MethodPost, "/orders", string(body), ErrInvalidAddress},
+		{"no key", http.Header{}, http.MethodPost, "/orders", string(body), ErrInvalidAPIKey},
+	} {
+		if _, err := v.VerifyRequest(store, tt.header, tt.method, tt.path, []byte(tt.body)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// Revoked keys no longer authenticate
+	store.Revoke(creds.APIKey)
+	if _, err := v.VerifyRequest(store, signed(creds, ts), http.MethodPost, "/orders", body); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("revoked key: got %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestWalletAuthFromHeader(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	wa, _ := Sign(key, 137, "1700000000", 3)
+
+	h := http.Header{}
+	h.Set(HeaderAddress, wa.Address)
+	h.Set(HeaderTimestamp, wa.Timestamp)
+	h.Set(HeaderNonce, "3")
+	h.Set(HeaderSignature, wa.Signature)
+	if got, err := WalletAuthFromHeader(h); err != nil || got != wa {
+		t.Fatalf("WalletAuthFromHeader = %+v, %v, want %+v", got, err, wa)
+	}
+
+	h.Del(HeaderSignature)
+	if _, err := WalletAuthFromHeader(h); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("missing signature: got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Errors returned by the credential store
+var (
+	ErrKeyExists   = errors.New("API key already exists for this nonce")
+	ErrKeyNotFound = errors.New("API key not found")
+)
+
+// Credentials are the L2 credentials issued to a wallet. The secret is
+// base64url encoded and keys the HMAC of every signed request.
+type Credentials struct {
+	APIKey     string `json:"apiKey"`
+	Secret     string `json:"secret"`
+	Passphrase string `json:"passphrase"`
+	Address    string `json:"address"` // checksummed address of the wallet that derived the key
+	Nonce      uint64 `json:"nonce"`   // ClobAuth nonce the key was derived with
+	CreatedAt  int64  `json:"createdAt"`
+}
+
+// Store keeps issued credentials in memory and, when it has a path, in a
+// JSON file that is rewritten atomically on every change
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	keys map[string]Credentials // by API key
+}
+
+// OpenStore loads the credentials in path, which may not exist yet. An empty
+// path keeps credentials in memory only.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path, keys: make(map[string]Credentials)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential store: %w", err)
+	}
+	var creds []Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("invalid credential store %s: %w", path, err)
+	}
+	for _, c := range creds {
+		s.keys[c.APIKey] = c
+	}
+	return s, nil
+}
+
+// Create issues new credentials to address for a ClobAuth nonce. Each nonce
+// holds one live key; revoke it before creating another with the same nonce.
+func (s *Store) Create(address common.Address, nonce uint64) (Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.find(address, nonce); ok {
+		return Credentials{}, ErrKeyExists
+	}
+
+	creds, err := newCredentials(address, nonce)
+	if err != nil {
+		return Credentials{}, err
+	}
+	s.keys[creds.APIKey] = creds
+	if err := s.save(); err != nil {
+		delete(s.keys, creds.APIKey)
+		return Credentials{}, err
+	}
+	return creds, nil
+}
+
+// Derive returns the live credentials of address for a ClobAuth nonce
+func (s *Store) Derive(address common.Address, nonce uint64) (Credentials, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	creds, ok := s.find(address, nonce)
+	if !ok {
+		return Credentials{}, ErrKeyNotFound
+	}
+	return creds, nil
+}
+
+// Get returns the credentials of an API key
+func (s *Store) Get(apiKey string) (Credentials, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	creds, ok := s.keys[apiKey]
+	return creds, ok
+}
+
+// Revoke deletes an API key; requests signed with it are rejected from then on
+func (s *Store) Revoke(apiKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	creds, ok := s.keys[apiKey]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	delete(s.keys, apiKey)
+	if err := s.save(); err != nil {
+		s.keys[apiKey] = creds
+		return err
+	}
+	return nil
+}
+
+// Keys returns the API keys of address, oldest first
+func (s *Store) Keys(address common.Address) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var creds []Credentials
+	for _, c := range s.keys {
+		if strings.EqualFold(c.Address, address.Hex()) {
+			creds = append(creds, c)
+		}
+	}
+	sort.Slice(creds, func(i, j int) bool {
+		if creds[i].CreatedAt != creds[j].CreatedAt {
+			return creds[i].CreatedAt < creds[j].CreatedAt
+		}
+		return creds[i].APIKey < creds[j].APIKey
+	})
+
+	keys := make([]string, len(creds))
+	for i, c := range creds {
+		keys[i] = c.APIKey
+	}
+	return keys
+}
+
+// find returns the credentials of address for nonce; the caller must hold s.mu
+func (s *Store) find(address common.Address, nonce uint64) (Credentials, bool) {
+	for _, c := range s.keys {
+		if c.Nonce == nonce && strings.EqualFold(c.Address, address.Hex()) {
+			return c, true
+		}
+	}
+	return Credentials{}, false
+}
+
+// save atomically rewrites the store file; the caller must hold s.mu
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	creds := make([]Credentials, 0, len(s.keys))
+	for _, c := range s.keys {
+		creds = append(creds, c)
+	}
+	sort.Slice(creds, func(i, j int) bool { return creds[i].APIKey < creds[j].APIKey })
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create credential store directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create credential store: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write credential store: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to sync credential store: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to close credential store: %w", err)
+	}
+
+	// Rename is atomic, so a crash leaves either the old or the new store
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename credential store: %w", err)
+	}
+	return nil
+}
+
+// newCredentials generates a random API key (a UUID), secret and passphrase
+func newCredentials(address common.Address, nonce uint64) (Credentials, error) {
+	buf := make([]byte, 16+32+32)
+	if _, err := rand.Read(buf); err != nil {
+		return Credentials{}, fmt.Errorf("failed to generate credentials: %w", err)
+	}
+
+	id := buf[:16]
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	h := hex.EncodeToString(id)
+
+	return Credentials{
+		APIKey:     h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:],
+		Secret:     base64.URLEncoding.EncodeToString(buf[16:48]),
+		Passphrase: hex.EncodeToString(buf[48:]),
+		Address:    address.Hex(),
+		Nonce:      nonce,
+		CreatedAt:  time.Now().Unix(),
+	}, nil
+}
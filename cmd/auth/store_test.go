@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestStore(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	path := filepath.Join(t.TempDir(), "creds", "credentials.json")
+
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	first, err := s.Create(address, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := s.Create(address, 0); !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("second Create with nonce 0: got %v, want ErrKeyExists", err)
+	}
+	second, err := s.Create(address, 1)
+	if err != nil {
+		t.Fatalf("Create with nonce 1 failed: %v", err)
+	}
+	if derived, err := s.Derive(address, 1); err != nil || derived != second {
+		t.Fatalf("Derive = %+v, %v, want %+v", derived, err, second)
+	}
+
+	// Credentials survive a restart, and revocation is persisted
+	if err := s.Revoke(first.APIKey); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := s.Revoke(first.APIKey); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("second Revoke: got %v, want ErrKeyNotFound", err)
+	}
+	reopened, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if _, ok := reopened.Get(first.APIKey); ok {
+		t.Fatalf("revoked key is still stored")
+	}
+	if got, ok := reopened.Get(second.APIKey); !ok || got != second {
+		t.Fatalf("Get = %+v, %v, want %+v", got, ok, second)
+	}
+	if _, err := reopened.Derive(address, 0); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Derive of revoked nonce: got %v, want ErrKeyNotFound", err)
+	}
+	if keys := reopened.Keys(address); !reflect.DeepEqual(keys, []string{second.APIKey}) {
+		t.Fatalf("Keys = %v", keys)
+	}
+
+	// A revoked nonce can be used again
+	if _, err := reopened.Create(address, 0); err != nil {
+		t.Fatalf("Create after revoke failed: %v", err)
+	}
+}
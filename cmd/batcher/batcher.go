@@ -0,0 +1,240 @@
+package batcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+)
+
+// Config holds the thresholds at which an open batch is cut
+type Config struct {
+	MaxFills int
+	MaxDelay time.Duration
+	MaxBytes int
+}
+
+// LoadConfig reads the batch cutting thresholds from environment variables
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		MaxFills: 100,
+		MaxDelay: 2000 * time.Millisecond,
+		MaxBytes: 64 * 1024,
+	}
+
+	if v := os.Getenv("BATCH_MAX_FILLS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid BATCH_MAX_FILLS: %s (must be positive integer)", v)
+		}
+		cfg.MaxFills = n
+	}
+
+	if v := os.Getenv("BATCH_MAX_DELAY_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid BATCH_MAX_DELAY_MS: %s (must be positive integer)", v)
+		}
+		cfg.MaxDelay = time.Duration(n) * time.Millisecond
+	}
+
+	if v := os.Getenv("BATCH_MAX_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid BATCH_MAX_BYTES: %s (must be positive integer)", v)
+		}
+		cfg.MaxBytes = n
+	}
+
+	return cfg, nil
+}
+
+// Publisher receives batches as they are cut
+type Publisher interface {
+	Publish(ctx context.Context, b pipeline.Batch) error
+}
+
+// CutFunc is called with each batch and its fills after it is cut and before it is published
+type CutFunc func(b pipeline.Batch, fills []matcher.Fill) error
+
+// Batcher collects fills across orders and cuts a batch when it reaches
+// MaxFills fills, MaxBytes encoded bytes or MaxDelay age, whichever comes first
+type Batcher struct {
+	cfg   Config
+	pub   Publisher
+	onCut CutFunc
+
+	mu           sync.Mutex
+	pending      []matcher.Fill
+	pendingBytes int
+	nextID       uint64
+	timer        *time.Timer
+}
+
+// New creates a batcher whose first batch is assigned firstID. firstID should be
+// BatchSettlement.totalBatchesSubmitted()+1 so batch IDs match the on-chain counter;
+// callers must not guess it when the counter cannot be read.
+func New(cfg Config, firstID uint64, pub Publisher) *Batcher {
+	if firstID == 0 {
+		firstID = 1
+	}
+
+	return &Batcher{
+		cfg:    cfg,
+		pub:    pub,
+		nextID: firstID,
+	}
+}
+
+// OnCut registers a hook run for every cut batch. It is called with the
+// batcher's lock held and must not call back into the batcher.
+func (b *Batcher) OnCut(fn CutFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onCut = fn
+}
+
+// Add appends fills to the open batch, cutting it whenever a threshold is
+// reached. It returns the ID of the batch each fill was assigned to; a fill
+// in the open batch keeps its ID when the batch is cut later.
+func (b *Batcher) Add(fills []matcher.Fill) ([]uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]uint64, 0, len(fills))
+	for _, fill := range fills {
+		size, err := encodedSize(fill)
+		if err != nil {
+			return ids, err
+		}
+
+		// Cut first if this fill would push the batch past the byte limit
+		if len(b.pending) > 0 && b.batchBytes(size) > b.cfg.MaxBytes {
+			if err := b.cut("size"); err != nil {
+				return ids, err
+			}
+		}
+
+		if len(b.pending) == 0 {
+			b.armTimer(b.nextID)
+		}
+		b.pending = append(b.pending, fill)
+		b.pendingBytes += size
+		ids = append(ids, b.nextID)
+
+		if len(b.pending) >= b.cfg.MaxFills {
+			if err := b.cut("count"); err != nil {
+				return ids, err
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// Flush cuts the open batch immediately if it holds any fills
+func (b *Batcher) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 {
+		return nil
+	}
+	return b.cut("flush")
+}
+
+// NextID returns the ID the currently open batch will be assigned
+func (b *Batcher) NextID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nextID
+}
+
+// Pending returns the number of fills in the open batch
+func (b *Batcher) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// armTimer schedules a time-based cut of the batch with the given ID
+func (b *Batcher) armTimer(id uint64) {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.timer = time.AfterFunc(b.cfg.MaxDelay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		// The batch may already have been cut by count or size
+		if b.nextID != id || len(b.pending) == 0 {
+			return
+		}
+		if err := b.cut("timeout"); err != nil {
+			log.Printf("Error cutting batch %d on timeout: %v", id, err)
+		}
+	})
+}
+
+// batchBytes returns the encoded size of the open batch after adding a fill of the given size
+func (b *Batcher) batchBytes(size int) int {
+	// JSON array brackets plus one comma between each element
+	return 2 + b.pendingBytes + size + len(b.pending)
+}
+
+// cut builds the open batch and publishes it; the caller must hold b.mu
+func (b *Batcher) cut(reason string) error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	fills := b.pending
+	b.pending = nil
+	b.pendingBytes = 0
+
+	root, fillsBytes, err := matcher.BuildBatch(fills)
+	if err != nil {
+		return fmt.Errorf("failed to build batch %d: %w", b.nextID, err)
+	}
+
+	batch := pipeline.Batch{
+		ID:    b.nextID,
+		Root:  root,
+		Fills: fillsBytes,
+	}
+	b.nextID++
+
+	log.Printf("✂️  Batch %d cut (%s) - Fills: %d, Bytes: %d, Root: %s",
+		batch.ID, reason, len(fills), len(fillsBytes), root)
+
+	if b.onCut != nil {
+		if err := b.onCut(batch, fills); err != nil {
+			log.Printf("Error in cut hook for batch %d: %v", batch.ID, err)
+		}
+	}
+
+	// Publishing under b.mu keeps batches in ID order; a full queue blocks
+	// further cuts, which propagates backpressure to callers of Add
+	if err := b.pub.Publish(context.Background(), batch); err != nil {
+		return fmt.Errorf("failed to publish batch %d: %w", batch.ID, err)
+	}
+
+	return nil
+}
+
+// encodedSize returns the JSON-encoded size of a single fill
+func encodedSize(fill matcher.Fill) (int, error) {
+	data, err := json.Marshal(fill)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal fill: %w", err)
+	}
+	return len(data), nil
+}
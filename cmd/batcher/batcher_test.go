@@ -0,0 +1,115 @@
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+)
+
+// recorder is a Publisher that records every batch it receives
+type recorder struct {
+	mu      sync.Mutex
+	batches []pipeline.Batch
+}
+
+func (r *recorder) Publish(ctx context.Context, b pipeline.Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, b)
+	return nil
+}
+
+func (r *recorder) published() []pipeline.Batch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]pipeline.Batch(nil), r.batches...)
+}
+
+func testFills(n int) []matcher.Fill {
+	fills := make([]matcher.Fill, n)
+	for i := range fills {
+		fills[i] = matcher.Fill{
+			MakerHash: fmt.Sprintf("maker%02d", i),
+			TakerHash: fmt.Sprintf("taker%02d", i),
+			Quantity:  "1.00000000",
+		}
+	}
+	return fills
+}
+
+func TestBatcherCutsOnFillCount(t *testing.T) {
+	rec := &recorder{}
+	b := New(Config{MaxFills: 3, MaxDelay: time.Hour, MaxBytes: 1 << 20}, 7, rec)
+
+	ids, err := b.Add(testFills(7))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if want := []uint64{7, 7, 7, 8, 8, 8, 9}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("fills assigned to batches %v, want %v", ids, want)
+	}
+
+	got := rec.published()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(got))
+	}
+	if got[0].ID != 7 || got[1].ID != 8 {
+		t.Errorf("expected sequential IDs 7 and 8, got %d and %d", got[0].ID, got[1].ID)
+	}
+	if b.Pending() != 1 || b.NextID() != 9 {
+		t.Errorf("expected 1 pending fill in batch 9, got %d in batch %d", b.Pending(), b.NextID())
+	}
+}
+
+func TestBatcherCutsOnByteSize(t *testing.T) {
+	size, err := encodedSize(testFills(1)[0])
+	if err != nil {
+		t.Fatalf("encodedSize failed: %v", err)
+	}
+
+	rec := &recorder{}
+	// Room for exactly two fills: brackets, two fills and one comma
+	b := New(Config{MaxFills: 100, MaxDelay: time.Hour, MaxBytes: 2 + 2*size + 1}, 1, rec)
+
+	if _, err := b.Add(testFills(5)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	got := rec.published()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(got))
+	}
+	for _, batch := range got {
+		if len(batch.Fills) > 2+2*size+1 {
+			t.Errorf("batch %d is %d bytes, over the limit", batch.ID, len(batch.Fills))
+		}
+	}
+}
+
+func TestBatcherCutsOnDelay(t *testing.T) {
+	rec := &recorder{}
+	b := New(Config{MaxFills: 100, MaxDelay: 20 * time.Millisecond, MaxBytes: 1 << 20}, 1, rec)
+
+	if _, err := b.Add(testFills(2)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if len(rec.published()) != 0 {
+		t.Fatalf("batch cut before the delay elapsed")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.published()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	got := rec.published()
+	if len(got) != 1 || got[0].ID != 1 {
+		t.Fatalf("expected batch 1 to be cut on delay, got %v", got)
+	}
+}
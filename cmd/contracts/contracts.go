@@ -0,0 +1,58 @@
+package contracts
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// batchSettlementJSON is the BatchSettlement ABI as emitted by forge build
+//
+//go:embed abi/BatchSettlement.json
+var batchSettlementJSON string
+
+// disputeGameJSON is the DisputeGame ABI as emitted by forge build
+//
+//go:embed abi/DisputeGame.json
+var disputeGameJSON string
+
+// erc20JSON is the subset of the ERC-20 ABI used to read collateral balances
+//
+//go:embed abi/ERC20.json
+var erc20JSON string
+
+// erc1155JSON is the subset of the ERC-1155 ABI used to read outcome token balances
+//
+//go:embed abi/ERC1155.json
+var erc1155JSON string
+
+// nonceManagerJSON is the nonce interface of Polymarket's CTF Exchange
+//
+//go:embed abi/NonceManager.json
+var nonceManagerJSON string
+
+// BatchSettlementABI is the full BatchSettlement ABI including events and custom errors
+var BatchSettlementABI = mustParseABI("BatchSettlement", batchSettlementJSON)
+
+// DisputeGameABI is the full DisputeGame ABI including events and custom errors
+var DisputeGameABI = mustParseABI("DisputeGame", disputeGameJSON)
+
+// ERC20ABI covers balanceOf, allowance, decimals and the Transfer and Approval events
+var ERC20ABI = mustParseABI("ERC20", erc20JSON)
+
+// ERC1155ABI covers balanceOf, isApprovedForAll and the transfer and approval events
+var ERC1155ABI = mustParseABI("ERC1155", erc1155JSON)
+
+// NonceManagerABI covers the exchange's nonces view and incrementNonce
+var NonceManagerABI = mustParseABI("NonceManager", nonceManagerJSON)
+
+// mustParseABI parses an embedded ABI, panicking if the artifact is malformed
+func mustParseABI(name, raw string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse embedded %s ABI: %v", name, err))
+	}
+	return parsed
+}
@@ -0,0 +1,102 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Sentinel errors for the BatchSettlement custom errors
+var (
+	ErrDuplicateBatchRoot  = errors.New("batch root already submitted")
+	ErrEmptyFillsData      = errors.New("fills data is empty")
+	ErrInvalidBLSSignature = errors.New("invalid BLS signature")
+	ErrInsufficientQuorum  = errors.New("insufficient quorum")
+)
+
+// ErrReverted is returned for reverts that do not map to a known custom error
+var ErrReverted = errors.New("execution reverted")
+
+// permanentErrors are reverts that resubmitting the same batch can never fix
+var permanentErrors = []error{
+	ErrDuplicateBatchRoot,
+	ErrEmptyFillsData,
+	ErrInvalidBLSSignature,
+	ErrInsufficientQuorum,
+	ErrReverted,
+}
+
+// IsPermanent reports whether err is a deterministic BatchSettlement revert,
+// decoded or not, rather than a transient RPC, nonce, gas pricing or timeout error
+func IsPermanent(err error) bool {
+	for _, permanent := range permanentErrors {
+		if errors.Is(err, permanent) {
+			return true
+		}
+	}
+	return false
+}
+
+// batchSettlementErrors maps BatchSettlement custom error names to sentinel errors
+var batchSettlementErrors = map[string]error{
+	"DuplicateBatchRoot":  ErrDuplicateBatchRoot,
+	"EmptyFillsData":      ErrEmptyFillsData,
+	"InvalidBLSSignature": ErrInvalidBLSSignature,
+	"InsufficientQuorum":  ErrInsufficientQuorum,
+}
+
+// RevertData extracts the raw revert payload from an eth_call or eth_estimateGas error
+func RevertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil || len(data) == 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+// DecodeBatchSettlementRevert decodes BatchSettlement revert data into a sentinel error.
+// Unknown custom errors and Error(string) reasons are wrapped in ErrReverted.
+func DecodeBatchSettlementRevert(data []byte) error {
+	if len(data) < 4 {
+		return ErrReverted
+	}
+
+	var selector [4]byte
+	copy(selector[:], data[:4])
+
+	if customErr, err := BatchSettlementABI.ErrorByID(selector); err == nil {
+		if sentinel, ok := batchSettlementErrors[customErr.Name]; ok {
+			return sentinel
+		}
+		return fmt.Errorf("%w: %s", ErrReverted, customErr.Name)
+	}
+
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return fmt.Errorf("%w: %s", ErrReverted, reason)
+	}
+
+	return fmt.Errorf("%w: %s", ErrReverted, hexutil.Encode(data))
+}
+
+// DecodeBatchSettlementError returns a sentinel error for err if it carries
+// BatchSettlement revert data, or err unchanged otherwise
+func DecodeBatchSettlementError(err error) error {
+	data, ok := RevertData(err)
+	if !ok {
+		return err
+	}
+	return fmt.Errorf("%w (%v)", DecodeBatchSettlementRevert(data), err)
+}
@@ -0,0 +1,75 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// rpcRevert mimics the JSON-RPC error returned by eth_call and eth_estimateGas on revert
+type rpcRevert struct {
+	data string
+}
+
+func (e rpcRevert) Error() string          { return "execution reverted" }
+func (e rpcRevert) ErrorCode() int         { return 3 }
+func (e rpcRevert) ErrorData() interface{} { return e.data }
+
+func TestDecodeBatchSettlementRevert(t *testing.T) {
+	tests := []struct {
+		name string
+		err  string
+		want error
+	}{
+		{"duplicate root", "DuplicateBatchRoot", ErrDuplicateBatchRoot},
+		{"empty fills", "EmptyFillsData", ErrEmptyFillsData},
+		{"invalid signature", "InvalidBLSSignature", ErrInvalidBLSSignature},
+		{"insufficient quorum", "InsufficientQuorum", ErrInsufficientQuorum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := BatchSettlementABI.Errors[tt.err].ID.Bytes()[:4]
+
+			if got := DecodeBatchSettlementRevert(selector); !errors.Is(got, tt.want) {
+				t.Errorf("DecodeBatchSettlementRevert() = %v, want %v", got, tt.want)
+			}
+
+			wrapped := fmt.Errorf("failed to estimate gas: %w", rpcRevert{data: hexutil.Encode(selector)})
+			if got := DecodeBatchSettlementError(wrapped); !errors.Is(got, tt.want) {
+				t.Errorf("DecodeBatchSettlementError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBatchSettlementRevertUnknown(t *testing.T) {
+	got := DecodeBatchSettlementRevert([]byte{0xde, 0xad, 0xbe, 0xef})
+	if !errors.Is(got, ErrReverted) {
+		t.Errorf("expected ErrReverted for unknown selector, got %v", got)
+	}
+
+	plain := errors.New("connection refused")
+	if got := DecodeBatchSettlementError(plain); got != plain {
+		t.Errorf("expected non-revert error to pass through, got %v", got)
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("transaction 0xabc reverted: %w", ErrInvalidBLSSignature), true},
+		{DecodeBatchSettlementRevert([]byte{0xde, 0xad, 0xbe, 0xef}), true},
+		{fmt.Errorf("failed to estimate gas: %w", ErrInsufficientQuorum), true},
+		{errors.New("connection refused"), false},
+		{fmt.Errorf("failed to get pending nonce: %w", errors.New("timeout")), false},
+	} {
+		if got := IsPermanent(tt.err); got != tt.want {
+			t.Errorf("IsPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
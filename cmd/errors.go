@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/auth"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/exposure"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/sequencer"
+)
+
+// Machine-readable error codes returned in API error responses
+const (
+	CodeMethodNotAllowed      = "METHOD_NOT_ALLOWED"
+	CodeInvalidJSON           = "INVALID_JSON"
+	CodeMissingField          = "MISSING_FIELD"
+	CodeInvalidField          = "INVALID_FIELD"
+	CodeInvalidAmount         = "INVALID_AMOUNT"
+	CodeInvalidPrice          = "INVALID_PRICE"
+	CodeInvalidTick           = "INVALID_TICK"
+	CodeBelowMinSize          = "BELOW_MIN_SIZE"
+	CodeFeeRateTooLow         = "FEE_RATE_TOO_LOW"
+	CodeInvalidNonce          = "INVALID_NONCE"
+	CodeOrderExpired          = "ORDER_EXPIRED"
+	CodeInvalidSignature      = "INVALID_SIGNATURE"
+	CodeInvalidQuery          = "INVALID_QUERY"
+	CodeInsufficientBalance   = "INSUFFICIENT_BALANCE"
+	CodeInsufficientAllowance = "INSUFFICIENT_ALLOWANCE"
+	CodeOrderNotFound         = "ORDER_NOT_FOUND"
+	CodeDuplicateOrder        = "DUPLICATE_ORDER"
+	CodeUnauthorized          = "UNAUTHORIZED"
+	CodeForbidden             = "FORBIDDEN"
+	CodeAPIKeyExists          = "API_KEY_EXISTS"
+	CodeAPIKeyNotFound        = "API_KEY_NOT_FOUND"
+	CodeRateLimited           = "RATE_LIMITED"
+	CodeTooManyOrders         = "TOO_MANY_ORDERS"
+	CodeQueueFull             = "QUEUE_FULL"
+	CodeBalanceUnavailable    = "BALANCE_UNAVAILABLE"
+	CodeInternal              = "INTERNAL_ERROR"
+)
+
+// APIError is the body of every error response
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// newAPIError creates an API error; field names the request field at fault, if any
+func newAPIError(status int, code, field, format string, args ...interface{}) *APIError {
+	return &APIError{Status: status, Code: code, Message: fmt.Sprintf(format, args...), Field: field}
+}
+
+// errMethodNotAllowed is returned for requests with an unsupported method
+var errMethodNotAllowed = newAPIError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "", "method not allowed")
+
+// ruleCodes maps matcher market rule violations to error codes
+var ruleCodes = []struct {
+	err  error
+	code string
+}{
+	{matcher.ErrPriceOutOfRange, CodeInvalidPrice},
+	{matcher.ErrInvalidTick, CodeInvalidTick},
+	{matcher.ErrBelowMinSize, CodeBelowMinSize},
+	{matcher.ErrFeeRateTooLow, CodeFeeRateTooLow},
+	{matcher.ErrInvalidAmount, CodeInvalidAmount},
+	{matcher.ErrInvalidSide, CodeInvalidField},
+	{matcher.ErrInvalidType, CodeInvalidField},
+}
+
+// toAPIError converts an error from the matcher, sequencer or submission
+// pipeline into the API error returned to the client. Errors it does not
+// recognize become a 500 without leaking internal details.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var rule *matcher.RuleError
+	if errors.As(err, &rule) {
+		for _, rc := range ruleCodes {
+			if errors.Is(err, rc.err) {
+				return newAPIError(http.StatusBadRequest, rc.code, rule.Field, "%s", rule.Msg)
+			}
+		}
+	}
+
+	for _, authErr := range []error{auth.ErrInvalidAddress, auth.ErrInvalidTimestamp, auth.ErrInvalidSignature, auth.ErrInvalidAPIKey} {
+		if errors.Is(err, authErr) {
+			return newAPIError(http.StatusUnauthorized, CodeUnauthorized, "", "%s", err.Error())
+		}
+	}
+	if errors.Is(err, auth.ErrKeyExists) {
+		return newAPIError(http.StatusConflict, CodeAPIKeyExists, "", "API key already exists for this nonce; derive it or revoke it first")
+	}
+	if errors.Is(err, auth.ErrKeyNotFound) {
+		return newAPIError(http.StatusNotFound, CodeAPIKeyNotFound, "", "no API key for this nonce")
+	}
+
+	switch {
+	case errors.Is(err, exposure.ErrMissingSide):
+		return newAPIError(http.StatusBadRequest, CodeMissingField, "side", "side is required to check the maker's balance")
+	case errors.Is(err, exposure.ErrInvalidAsset):
+		return newAPIError(http.StatusBadRequest, CodeInvalidField, "takerAsset", "%s", err.Error())
+	case errors.Is(err, exposure.ErrInsufficientBalance):
+		return newAPIError(http.StatusBadRequest, CodeInsufficientBalance, "makeAmount", "%s", err.Error())
+	case errors.Is(err, exposure.ErrInsufficientAllowance):
+		return newAPIError(http.StatusBadRequest, CodeInsufficientAllowance, "makeAmount", "%s", err.Error())
+	case errors.Is(err, exposure.ErrAlreadyReserved):
+		return newAPIError(http.StatusConflict, CodeDuplicateOrder, "", "order was already submitted")
+	case errors.Is(err, exposure.ErrUnavailable):
+		return newAPIError(http.StatusServiceUnavailable, CodeBalanceUnavailable, "", "balances cannot be read from the chain, retry later")
+	}
+
+	if errors.Is(err, sequencer.ErrOrderNotFound) {
+		return newAPIError(http.StatusNotFound, CodeOrderNotFound, "orderHash", "order not found")
+	}
+	if errors.Is(err, sequencer.ErrInvalidNonce) {
+		return newAPIError(http.StatusBadRequest, CodeInvalidNonce, "nonce", "%s", err.Error())
+	}
+	if errors.Is(err, sequencer.ErrOrderExpired) {
+		return newAPIError(http.StatusBadRequest, CodeOrderExpired, "expiration", "%s", err.Error())
+	}
+	if errors.Is(err, sequencer.ErrDuplicateOrder) {
+		return newAPIError(http.StatusConflict, CodeDuplicateOrder, "", "%s", err.Error())
+	}
+	if errors.Is(err, sequencer.ErrTooManyOrders) {
+		return newAPIError(http.StatusTooManyRequests, CodeTooManyOrders, "", "%s", err.Error())
+	}
+
+	return newAPIError(http.StatusInternalServerError, CodeInternal, "", "internal error")
+}
+
+// writeError writes err as a JSON error response
+func writeError(w http.ResponseWriter, err error) {
+	apiErr := toAPIError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(apiErr)
+}
@@ -0,0 +1,565 @@
+package exposure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Chain is the subset of the Ethereum client used by the exposure manager
+type Chain interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// Config holds the tokens whose balances back orders and the cache parameters
+type Config struct {
+	Collateral         common.Address // ERC-20 spent by buy orders; zero disables the checks
+	ConditionalTokens  common.Address // ERC-1155 outcome tokens sold by sell orders
+	Exchange           common.Address // spender whose allowance and approval are checked
+	CollateralDecimals int
+	TokenDecimals      int
+	CacheTTL           time.Duration
+	PollInterval       time.Duration
+	BlockRange         uint64
+}
+
+// LoadConfig reads the exposure configuration from environment variables
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		CollateralDecimals: 6,
+		TokenDecimals:      6,
+		CacheTTL:           30 * time.Second,
+		PollInterval:       2000 * time.Millisecond,
+		BlockRange:         1000,
+	}
+
+	v := os.Getenv("COLLATERAL_ADDRESS")
+	if v == "" {
+		return cfg, nil
+	}
+	if !common.IsHexAddress(v) {
+		return cfg, fmt.Errorf("invalid COLLATERAL_ADDRESS: %s", v)
+	}
+	cfg.Collateral = common.HexToAddress(v)
+
+	v = os.Getenv("CONDITIONAL_TOKENS_ADDRESS")
+	if !common.IsHexAddress(v) {
+		return cfg, fmt.Errorf("invalid CONDITIONAL_TOKENS_ADDRESS: %q (required with COLLATERAL_ADDRESS)", v)
+	}
+	cfg.ConditionalTokens = common.HexToAddress(v)
+
+	v = os.Getenv("EXCHANGE_ADDRESS")
+	if v == "" {
+		v = os.Getenv("CONTRACT_ADDRESS")
+	}
+	if v == "" {
+		v = os.Getenv("BATCH_SETTLEMENT_ADDRESS")
+	}
+	if !common.IsHexAddress(v) {
+		return cfg, fmt.Errorf("invalid EXCHANGE_ADDRESS: %q", v)
+	}
+	cfg.Exchange = common.HexToAddress(v)
+
+	for _, d := range []struct {
+		name  string
+		value *int
+	}{
+		{"COLLATERAL_DECIMALS", &cfg.CollateralDecimals},
+		{"OUTCOME_TOKEN_DECIMALS", &cfg.TokenDecimals},
+	} {
+		if v := os.Getenv(d.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > 36 {
+				return cfg, fmt.Errorf("invalid %s: %s (must be 0-36)", d.name, v)
+			}
+			*d.value = n
+		}
+	}
+
+	if v := os.Getenv("BALANCE_CACHE_TTL_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid BALANCE_CACHE_TTL_MS: %s (must be positive integer)", v)
+		}
+		cfg.CacheTTL = time.Duration(n) * time.Millisecond
+	}
+
+	if v := os.Getenv("EXPOSURE_POLL_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid EXPOSURE_POLL_MS: %s (must be positive integer)", v)
+		}
+		cfg.PollInterval = time.Duration(n) * time.Millisecond
+	}
+
+	if v := os.Getenv("EXPOSURE_BLOCK_RANGE"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid EXPOSURE_BLOCK_RANGE: %s (must be positive integer)", v)
+		}
+		cfg.BlockRange = n
+	}
+
+	return cfg, nil
+}
+
+// Errors returned for orders the maker cannot back
+var (
+	ErrMissingSide           = errors.New("order side is required")
+	ErrInvalidAsset          = errors.New("takerAsset is not an outcome token ID")
+	ErrInsufficientBalance   = errors.New("insufficient balance")
+	ErrInsufficientAllowance = errors.New("insufficient allowance")
+	ErrUnavailable           = errors.New("balance unavailable")
+	ErrAlreadyReserved       = errors.New("order already reserved")
+)
+
+// Events that change a cached balance, allowance or approval
+var (
+	transferEvent       = contracts.ERC20ABI.Events["Transfer"]
+	approvalEvent       = contracts.ERC20ABI.Events["Approval"]
+	transferSingleEvent = contracts.ERC1155ABI.Events["TransferSingle"]
+	transferBatchEvent  = contracts.ERC1155ABI.Events["TransferBatch"]
+	approvalForAllEvent = contracts.ERC1155ABI.Events["ApprovalForAll"]
+)
+
+// holding is the balance of one token, identified by its outcome token ID or
+// "" for collateral
+type holding struct {
+	token string
+	id    *big.Int
+}
+
+// cached is a balance and allowance read from the chain
+type cached struct {
+	balance   *big.Int
+	allowance *big.Int
+	fetched   time.Time
+}
+
+// reservation is the amount an open order commits, in token base units
+type reservation struct {
+	owner  common.Address
+	token  string
+	side   matcher.Side
+	price  *big.Rat
+	amount *big.Int
+}
+
+// Manager checks that makers hold and have approved the tokens their orders
+// commit. Buy orders commit price × makeAmount of collateral and sell orders
+// commit makeAmount outcome tokens of their takerAsset. Balances and
+// allowances are read with eth_call, cached, and dropped from the cache when
+// a Transfer or Approval log touches their owner; the amounts committed to
+// open orders are reserved until the orders fill or are cancelled.
+type Manager struct {
+	cfg   Config
+	chain Chain
+	now   func() time.Time
+
+	mu       sync.Mutex
+	cache    map[common.Address]map[string]cached // by owner, then token
+	orders   map[string]*reservation              // by order hash
+	reserved map[common.Address]map[string]*big.Int
+	next     uint64 // next block to scan for logs; 0 starts at the head
+}
+
+// New creates a manager; with a zero Collateral address it accepts every order
+func New(cfg Config, chain Chain) *Manager {
+	if cfg.BlockRange == 0 {
+		cfg.BlockRange = 1
+	}
+	return &Manager{
+		cfg:      cfg,
+		chain:    chain,
+		now:      time.Now,
+		cache:    make(map[common.Address]map[string]cached),
+		orders:   make(map[string]*reservation),
+		reserved: make(map[common.Address]map[string]*big.Int),
+	}
+}
+
+// Enabled reports whether orders are checked against balances
+func (m *Manager) Enabled() bool {
+	return m.cfg.Collateral != (common.Address{})
+}
+
+// Reserve checks that the maker of o can back it on top of their open orders
+// and reserves the amount it commits under the order's hash. An order that
+// already holds a reservation is rejected with ErrAlreadyReserved, so only
+// the caller whose Reserve succeeded may Release it.
+func (m *Manager) Reserve(ctx context.Context, o matcher.Order) error {
+	if !m.Enabled() {
+		return nil
+	}
+
+	owner := common.HexToAddress(o.Maker)
+	r, h, err := m.commitment(owner, o)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	c, ok := m.cache[owner][h.token]
+	m.mu.Unlock()
+	if !ok || m.now().Sub(c.fetched) >= m.cfg.CacheTTL {
+		if c, err = m.fetch(ctx, owner, h); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cache[owner] == nil {
+		m.cache[owner] = make(map[string]cached)
+	}
+	m.cache[owner][h.token] = c
+
+	hash := matcher.OrderHash(o)
+	if _, ok := m.orders[hash]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyReserved, hash)
+	}
+	need := new(big.Int).Add(m.reservedLocked(owner, h.token), r.amount)
+	if c.balance.Cmp(need) < 0 {
+		return fmt.Errorf("%w: %s holds %s of %s, open orders and this one need %s",
+			ErrInsufficientBalance, owner.Hex(), c.balance, m.tokenName(h.token), need)
+	}
+	if c.allowance.Cmp(need) < 0 {
+		return fmt.Errorf("%w: %s has approved %s of %s for %s, open orders and this one need %s",
+			ErrInsufficientAllowance, owner.Hex(), c.allowance, m.tokenName(h.token), m.cfg.Exchange.Hex(), need)
+	}
+	m.addLocked(hash, r)
+	return nil
+}
+
+// Release drops the reservation of an order that did not reach the book
+func (m *Manager) Release(hash string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.releaseLocked(hash)
+}
+
+// Track reserves the amounts committed by orders already on the book, such
+// as those recovered from the WAL, without checking balances
+func (m *Manager) Track(orders []matcher.Order) {
+	if !m.Enabled() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, o := range orders {
+		r, _, err := m.commitment(common.HexToAddress(o.Maker), o)
+		if err != nil {
+			continue
+		}
+		if _, ok := m.orders[o.Hash]; !ok {
+			m.addLocked(o.Hash, r)
+		}
+	}
+}
+
+// Reserved returns the amount of a token committed to the open orders of
+// owner, in base units; token is an outcome token ID or "" for collateral
+func (m *Manager) Reserved(owner common.Address, token string) *big.Int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return new(big.Int).Set(m.reservedLocked(owner, token))
+}
+
+// Observe releases what fills and cancellations free up. It implements
+// matcher.Observer.
+func (m *Manager) Observe(e matcher.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch e.Type {
+	case matcher.EventFill:
+		for _, hash := range []string{e.Fill.MakerHash, e.Fill.TakerHash} {
+			r, ok := m.orders[hash]
+			if !ok {
+				continue
+			}
+			filled, err := m.units(r.side, e.Fill.Quantity, r.price)
+			if err != nil {
+				continue
+			}
+			if filled.Cmp(r.amount) > 0 {
+				filled.Set(r.amount)
+			}
+			r.amount.Sub(r.amount, filled)
+			m.reserved[r.owner][r.token].Sub(m.reserved[r.owner][r.token], filled)
+		}
+	case matcher.EventOrderFilled, matcher.EventOrderCancelled, matcher.EventOrderExpired, matcher.EventInvalidAmount:
+		m.releaseLocked(e.Order.Hash)
+	}
+}
+
+// Run follows Transfer and Approval logs of the collateral and outcome
+// tokens until ctx is cancelled, dropping the cached balances they touch
+func (m *Manager) Run(ctx context.Context) {
+	if !m.Enabled() {
+		return
+	}
+	log.Printf("Exposure manager started - Collateral: %s, Conditional tokens: %s, Exchange: %s",
+		m.cfg.Collateral.Hex(), m.cfg.ConditionalTokens.Hex(), m.cfg.Exchange.Hex())
+
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			caughtUp, err := m.poll(ctx)
+			if err != nil {
+				log.Printf("Exposure poll error: %v", err)
+				break
+			}
+			if caughtUp {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll scans the next block range for logs, reporting whether it reached the head
+func (m *Manager) poll(ctx context.Context) (bool, error) {
+	head, err := m.chain.BlockNumber(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get head block: %w", err)
+	}
+
+	m.mu.Lock()
+	if m.next == 0 {
+		m.next = head + 1
+	}
+	from := m.next
+	m.mu.Unlock()
+
+	if from > head {
+		return true, nil
+	}
+	to := from + m.cfg.BlockRange - 1
+	if to > head {
+		to = head
+	}
+
+	logs, err := m.chain.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{m.cfg.Collateral, m.cfg.ConditionalTokens},
+		Topics: [][]common.Hash{{
+			transferEvent.ID, approvalEvent.ID, transferSingleEvent.ID, transferBatchEvent.ID, approvalForAllEvent.ID,
+		}},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to filter logs for blocks %d-%d: %w", from, to, err)
+	}
+
+	m.mu.Lock()
+	for _, lg := range logs {
+		m.invalidateLocked(lg)
+	}
+	m.next = to + 1
+	m.mu.Unlock()
+
+	return to == head, nil
+}
+
+// invalidateLocked drops the cached balances of every address indexed by a
+// token log; the caller must hold m.mu
+func (m *Manager) invalidateLocked(lg types.Log) {
+	if len(lg.Topics) == 0 {
+		return
+	}
+	switch lg.Topics[0] {
+	case transferEvent.ID, approvalEvent.ID, transferSingleEvent.ID, transferBatchEvent.ID, approvalForAllEvent.ID:
+	default:
+		return
+	}
+	for _, topic := range lg.Topics[1:] {
+		delete(m.cache, common.BytesToAddress(topic.Bytes()))
+	}
+}
+
+// commitment returns the reservation o needs and the holding it draws on
+func (m *Manager) commitment(owner common.Address, o matcher.Order) (*reservation, holding, error) {
+	var h holding
+	switch o.Side {
+	case matcher.SideBuy:
+	case matcher.SideSell:
+		id, ok := new(big.Int).SetString(o.TakerAsset, 0)
+		if !ok || id.Sign() < 0 {
+			return nil, h, fmt.Errorf("%w: %q", ErrInvalidAsset, o.TakerAsset)
+		}
+		h = holding{token: id.String(), id: id}
+	default:
+		return nil, h, ErrMissingSide
+	}
+
+	price, ok := new(big.Rat).SetString(strconv.FormatFloat(o.Price, 'f', -1, 64))
+	if !ok {
+		return nil, h, fmt.Errorf("invalid price %v", o.Price)
+	}
+	if o.IsMarket() && o.Side == matcher.SideBuy {
+		// A market buy's makeAmount is already the collateral it spends
+		price.SetInt64(1)
+	}
+	// The matcher fills resting asks by takeAmount and everything else by
+	// makeAmount, so reserve the size it will fill
+	size := o.MakeAmount
+	if o.Side == matcher.SideSell && !o.IsMarket() {
+		size = o.TakeAmount
+	}
+	amount, err := m.units(o.Side, size, price)
+	if err != nil {
+		return nil, h, err
+	}
+	return &reservation{owner: owner, token: h.token, side: o.Side, price: price, amount: amount}, h, nil
+}
+
+// units converts a size in shares to base units of the token it commits:
+// size × price of collateral for buys and size of outcome tokens for sells,
+// rounded up
+func (m *Manager) units(side matcher.Side, size string, price *big.Rat) (*big.Int, error) {
+	v, ok := new(big.Rat).SetString(size)
+	if !ok || v.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", size)
+	}
+	decimals := m.cfg.TokenDecimals
+	if side == matcher.SideBuy {
+		v.Mul(v, price)
+		decimals = m.cfg.CollateralDecimals
+	}
+	v.Mul(v, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+
+	q, r := new(big.Int).QuoRem(v.Num(), v.Denom(), new(big.Int))
+	if r.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q, nil
+}
+
+// fetch reads the balance and allowance of a holding from the chain. The
+// allowance of an outcome token is unlimited if the exchange is approved for
+// all of the owner's tokens and zero otherwise.
+func (m *Manager) fetch(ctx context.Context, owner common.Address, h holding) (cached, error) {
+	c := cached{fetched: m.now()}
+	var err error
+	if h.id == nil {
+		if c.balance, err = m.callUint(ctx, contracts.ERC20ABI, m.cfg.Collateral, "balanceOf", owner); err != nil {
+			return c, err
+		}
+		c.allowance, err = m.callUint(ctx, contracts.ERC20ABI, m.cfg.Collateral, "allowance", owner, m.cfg.Exchange)
+		return c, err
+	}
+
+	if c.balance, err = m.callUint(ctx, contracts.ERC1155ABI, m.cfg.ConditionalTokens, "balanceOf", owner, h.id); err != nil {
+		return c, err
+	}
+	out, err := m.call(ctx, contracts.ERC1155ABI, m.cfg.ConditionalTokens, "isApprovedForAll", owner, m.cfg.Exchange)
+	if err != nil {
+		return c, err
+	}
+	c.allowance = new(big.Int)
+	if approved, ok := out[0].(bool); ok && approved {
+		c.allowance.Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	}
+	return c, nil
+}
+
+// callUint calls a view method that returns a single uint256
+func (m *Manager) callUint(ctx context.Context, contract abi.ABI, to common.Address, method string, args ...interface{}) (*big.Int, error) {
+	out, err := m.call(ctx, contract, to, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := out[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s returned %T", ErrUnavailable, method, out[0])
+	}
+	return v, nil
+}
+
+// call calls a view method with eth_call at the latest block
+func (m *Manager) call(ctx context.Context, contract abi.ABI, to common.Address, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := contract.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+	}
+	res, err := m.chain.CallContract(ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s on %s: %v", ErrUnavailable, method, to.Hex(), err)
+	}
+	out, err := contract.Unpack(method, res)
+	if err != nil || len(out) == 0 {
+		return nil, fmt.Errorf("%w: cannot decode %s from %s: %v", ErrUnavailable, method, to.Hex(), err)
+	}
+	return out, nil
+}
+
+// addLocked records a reservation; the caller must hold m.mu
+func (m *Manager) addLocked(hash string, r *reservation) {
+	m.orders[hash] = r
+	if m.reserved[r.owner] == nil {
+		m.reserved[r.owner] = make(map[string]*big.Int)
+	}
+	if m.reserved[r.owner][r.token] == nil {
+		m.reserved[r.owner][r.token] = new(big.Int)
+	}
+	m.reserved[r.owner][r.token].Add(m.reserved[r.owner][r.token], r.amount)
+}
+
+// releaseLocked drops a reservation; the caller must hold m.mu
+func (m *Manager) releaseLocked(hash string) {
+	r, ok := m.orders[hash]
+	if !ok {
+		return
+	}
+	delete(m.orders, hash)
+
+	total := m.reserved[r.owner][r.token]
+	total.Sub(total, r.amount)
+	if total.Sign() <= 0 {
+		delete(m.reserved[r.owner], r.token)
+		if len(m.reserved[r.owner]) == 0 {
+			delete(m.reserved, r.owner)
+		}
+	}
+}
+
+// reservedLocked returns the committed amount of a holding; the caller must hold m.mu
+func (m *Manager) reservedLocked(owner common.Address, token string) *big.Int {
+	if v := m.reserved[owner][token]; v != nil {
+		return v
+	}
+	return new(big.Int)
+}
+
+// tokenName describes a holding in error messages
+func (m *Manager) tokenName(token string) string {
+	if token == "" {
+		return "collateral"
+	}
+	return "outcome token " + token
+}
@@ -0,0 +1,301 @@
+package exposure
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	collateral = common.HexToAddress("0x00000000000000000000000000000000000000c0")
+	ctf        = common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	exchange   = common.HexToAddress("0x00000000000000000000000000000000000000e0")
+	alice      = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+)
+
+// fakeChain serves token balances and allowances from maps and counts calls
+type fakeChain struct {
+	balances   map[string]*big.Int // by owner and token, "" for collateral
+	allowances map[common.Address]*big.Int
+	approved   map[common.Address]bool
+	logs       []types.Log
+	head       uint64
+	calls      int
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		balances:   make(map[string]*big.Int),
+		allowances: make(map[common.Address]*big.Int),
+		approved:   make(map[common.Address]bool),
+		head:       10,
+	}
+}
+
+func (c *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func (c *fakeChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, lg := range c.logs {
+		if lg.BlockNumber >= q.FromBlock.Uint64() && lg.BlockNumber <= q.ToBlock.Uint64() {
+			logs = append(logs, lg)
+		}
+	}
+	return logs, nil
+}
+
+func (c *fakeChain) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calls++
+	contract := contracts.ERC20ABI
+	if *msg.To == ctf {
+		contract = contracts.ERC1155ABI
+	}
+	method, err := contract.MethodById(msg.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	owner := args[0].(common.Address)
+
+	switch method.Name {
+	case "balanceOf":
+		key := owner.Hex()
+		if len(args) == 2 {
+			key += "/" + args[1].(*big.Int).String()
+		}
+		return pack(method, orZero(c.balances[key]))
+	case "allowance":
+		return pack(method, orZero(c.allowances[owner]))
+	case "isApprovedForAll":
+		return pack(method, c.approved[owner])
+	}
+	return nil, errors.New("unexpected method " + method.Name)
+}
+
+func pack(method *abi.Method, v interface{}) ([]byte, error) {
+	return method.Outputs.Pack(v)
+}
+
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+func testManager(chain Chain) *Manager {
+	return New(Config{
+		Collateral:         collateral,
+		ConditionalTokens:  ctf,
+		Exchange:           exchange,
+		CollateralDecimals: 6,
+		TokenDecimals:      6,
+		CacheTTL:           time.Hour,
+		BlockRange:         100,
+	}, chain)
+}
+
+func testOrder(side matcher.Side, price float64, size string, ts int64) matcher.Order {
+	o := matcher.Order{
+		Maker:      alice.Hex(),
+		TakerAsset: "0x2a",
+		MakeAmount: size,
+		TakeAmount: size,
+		Price:      price,
+		Timestamp:  ts,
+		Side:       side,
+		Signature:  "0xsig",
+	}
+	o.Hash = matcher.OrderHash(o)
+	return o
+}
+
+// emit adds a token log indexing addresses in the next block and polls it
+func emit(t *testing.T, m *Manager, c *fakeChain, token common.Address, event common.Hash, addresses ...common.Address) {
+	t.Helper()
+	if _, err := m.poll(context.Background()); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	c.head++
+	lg := types.Log{Address: token, BlockNumber: c.head, Topics: []common.Hash{event}}
+	for _, a := range addresses {
+		lg.Topics = append(lg.Topics, common.BytesToHash(a.Bytes()))
+	}
+	c.logs = append(c.logs, lg)
+	if _, err := m.poll(context.Background()); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+}
+
+func TestReserveChecksBalanceAndAllowance(t *testing.T) {
+	chain := newFakeChain()
+	chain.balances[alice.Hex()] = big.NewInt(10_000_000) // 10 USDC
+	chain.allowances[alice] = big.NewInt(6_000_000)
+	m := testManager(chain)
+	ctx := context.Background()
+
+	// 10 shares at 0.5 commit 5 USDC
+	first := testOrder(matcher.SideBuy, 0.5, "10", 1)
+	if err := m.Reserve(ctx, first); err != nil {
+		t.Fatalf("first order: %v", err)
+	}
+	if got := m.Reserved(alice, ""); got.Cmp(big.NewInt(5_000_000)) != 0 {
+		t.Fatalf("reserved %s, want 5000000", got)
+	}
+
+	// Resubmitting the same order neither reserves again nor passes as reserved
+	if err := m.Reserve(ctx, first); !errors.Is(err, ErrAlreadyReserved) {
+		t.Fatalf("resubmitted order: got %v, want ErrAlreadyReserved", err)
+	}
+	if got := m.Reserved(alice, ""); got.Cmp(big.NewInt(5_000_000)) != 0 {
+		t.Fatalf("reserved after resubmission %s, want 5000000", got)
+	}
+
+	// Another 5 USDC is within the balance but beyond the allowance
+	if err := m.Reserve(ctx, testOrder(matcher.SideBuy, 0.5, "10", 2)); !errors.Is(err, ErrInsufficientAllowance) {
+		t.Fatalf("second order: got %v, want ErrInsufficientAllowance", err)
+	}
+	chain.allowances[alice] = big.NewInt(100_000_000)
+	emit(t, m, chain, collateral, approvalEvent.ID, alice, exchange)
+	if err := m.Reserve(ctx, testOrder(matcher.SideBuy, 0.5, "12", 3)); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("order beyond balance: got %v, want ErrInsufficientBalance", err)
+	}
+
+	// A partial fill of 4 shares frees 2 USDC, and the cancel frees the rest
+	m.Observe(matcher.Event{Type: matcher.EventFill, Fill: matcher.Fill{MakerHash: first.Hash, Quantity: "4.00000000"}})
+	if got := m.Reserved(alice, ""); got.Cmp(big.NewInt(3_000_000)) != 0 {
+		t.Fatalf("reserved after fill %s, want 3000000", got)
+	}
+	m.Observe(matcher.Event{Type: matcher.EventOrderCancelled, Order: first})
+	if got := m.Reserved(alice, ""); got.Sign() != 0 {
+		t.Fatalf("reserved after cancel %s, want 0", got)
+	}
+
+	if err := m.Reserve(ctx, testOrder(matcher.SideBuy, 0.5, "12", 4)); err != nil {
+		t.Fatalf("order after cancel: %v", err)
+	}
+}
+
+func TestReserveSellChecksOutcomeTokens(t *testing.T) {
+	chain := newFakeChain()
+	chain.balances[alice.Hex()+"/42"] = big.NewInt(3_000_000)
+	m := testManager(chain)
+	ctx := context.Background()
+
+	sell := testOrder(matcher.SideSell, 0.5, "2", 1)
+	if err := m.Reserve(ctx, sell); !errors.Is(err, ErrInsufficientAllowance) {
+		t.Fatalf("without approval: got %v, want ErrInsufficientAllowance", err)
+	}
+
+	// Approving the exchange emits ApprovalForAll, which drops the cached approval
+	chain.approved[alice] = true
+	emit(t, m, chain, ctf, approvalForAllEvent.ID, alice, exchange)
+	if err := m.Reserve(ctx, sell); err != nil {
+		t.Fatalf("after approval: %v", err)
+	}
+	if err := m.Reserve(ctx, testOrder(matcher.SideSell, 0.5, "1.5", 2)); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("beyond token balance: got %v, want ErrInsufficientBalance", err)
+	}
+
+	// Cached balances are reused until a log touches their owner
+	calls := chain.calls
+	if err := m.Reserve(ctx, testOrder(matcher.SideSell, 0.5, "1", 3)); err != nil {
+		t.Fatalf("within balance: %v", err)
+	}
+	if chain.calls != calls {
+		t.Errorf("cached balance was refetched")
+	}
+
+	if err := m.Reserve(ctx, testOrder("", 0.5, "1", 4)); !errors.Is(err, ErrMissingSide) {
+		t.Fatalf("order without side: got %v, want ErrMissingSide", err)
+	}
+}
+
+func TestReserveSellCommitsItsTakeAmount(t *testing.T) {
+	chain := newFakeChain()
+	chain.balances[alice.Hex()+"/42"] = big.NewInt(3_000_000)
+	chain.approved[alice] = true
+	m := testManager(chain)
+
+	// The matcher fills an ask by takeAmount, whatever its makeAmount
+	sell := testOrder(matcher.SideSell, 0.5, "1", 1)
+	sell.TakeAmount = "2"
+	if err := m.Reserve(context.Background(), sell); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if got := m.Reserved(alice, "42"); got.Cmp(big.NewInt(2_000_000)) != 0 {
+		t.Fatalf("reserved %s, want 2000000", got)
+	}
+
+	oversized := testOrder(matcher.SideSell, 0.5, "1", 2)
+	oversized.TakeAmount = "1.5"
+	if err := m.Reserve(context.Background(), oversized); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("takeAmount beyond token balance: got %v, want ErrInsufficientBalance", err)
+	}
+}
+
+func TestFillsReleaseAcrossPartialFills(t *testing.T) {
+	chain := newFakeChain()
+	chain.balances[alice.Hex()] = big.NewInt(10_000_000)
+	chain.allowances[alice] = big.NewInt(10_000_000)
+	m := testManager(chain)
+
+	// 10 shares at 0.5 commit 5 USDC; two sells fill 4 and then 3 of them
+	bid := testOrder(matcher.SideBuy, 0.5, "10", 1)
+	if err := m.Reserve(context.Background(), bid); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	book := []matcher.Order{bid}
+	for i, size := range []string{"4", "3"} {
+		var events []matcher.Event
+		book, events = matcher.Execute(book, testOrder(matcher.SideSell, 0.5, size, int64(i+2)), 100, matcher.Markets{})
+		for _, e := range events {
+			m.Observe(e)
+		}
+	}
+	if got := m.Reserved(alice, ""); got.Cmp(big.NewInt(1_500_000)) != 0 {
+		t.Fatalf("reserved after two partial fills %s, want 1500000", got)
+	}
+}
+
+func TestReserveMarketBuyCommitsItsAmount(t *testing.T) {
+	chain := newFakeChain()
+	chain.balances[alice.Hex()] = big.NewInt(10_000_000)
+	chain.allowances[alice] = big.NewInt(10_000_000)
+	m := testManager(chain)
+
+	// A market buy spends makeAmount of collateral whatever its worst price
+	o := testOrder(matcher.SideBuy, 0, "4", 1)
+	o.Type = matcher.OrderMarket
+	if err := m.Reserve(context.Background(), o); err != nil {
+		t.Fatalf("market buy: %v", err)
+	}
+	if got := m.Reserved(alice, ""); got.Cmp(big.NewInt(4_000_000)) != 0 {
+		t.Fatalf("reserved %s, want 4000000", got)
+	}
+	m.Observe(matcher.Event{Type: matcher.EventOrderCancelled, Order: o})
+	if got := m.Reserved(alice, ""); got.Sign() != 0 {
+		t.Fatalf("reserved after cancel %s, want 0", got)
+	}
+}
+
+func TestDisabledManagerAcceptsEverything(t *testing.T) {
+	m := New(Config{}, nil)
+	if err := m.Reserve(context.Background(), testOrder("", 0.5, "1000000", 1)); err != nil {
+		t.Fatalf("disabled manager rejected an order: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/auth"
+	"github.com/gorilla/websocket"
+)
+
+// maxRequestSize limits the size of a client request
+const maxRequestSize = 64 * 1024
+
+// Channels and operations of client requests
+const (
+	ChannelMarket = "market"
+	ChannelUser   = "user"
+
+	OpSubscribe   = "subscribe"
+	OpUnsubscribe = "unsubscribe"
+)
+
+// Request is a message from a client. A market channel request names the
+// assets to subscribe to, and later requests add or remove assets with
+// Operation. A user channel request carries the wallet signature that
+// authenticates the connection. The text message PING is answered with PONG.
+type Request struct {
+	Type      string           `json:"type,omitempty"`
+	Operation string           `json:"operation,omitempty"`
+	AssetsIDs []string         `json:"assets_ids,omitempty"`
+	Auth      *auth.WalletAuth `json:"auth,omitempty"`
+}
+
+// checkOrigin lets browsers connect from the origins the HTTP API allows.
+// Clients that send no Origin header are not browsers and always connect.
+func (f *Feed) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || (f.verifier != nil && f.verifier.AllowsOrigin(origin))
+}
+
+// client is one WebSocket connection
+type client struct {
+	conn *websocket.Conn
+	addr string
+	send chan []byte
+
+	// Guarded by Feed.mu; slow is set before send is closed
+	assets map[string]struct{}
+	user   string // lowercase address the connection authenticated as
+	slow   bool
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves it
+// until the client disconnects or is dropped
+func (f *Feed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     f.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an HTTP error
+		log.Printf("Feed: upgrade failed for %s: %v", r.RemoteAddr, err)
+		return
+	}
+
+	c := &client{
+		conn:   conn,
+		addr:   r.RemoteAddr,
+		send:   make(chan []byte, f.cfg.SendBuffer),
+		assets: make(map[string]struct{}),
+	}
+	f.add(c)
+	go f.writeLoop(c)
+	f.readLoop(c)
+}
+
+// readLoop handles client requests. Any message, or a pong, resets the read
+// deadline of two ping intervals.
+func (f *Feed) readLoop(c *client) {
+	defer f.remove(c)
+
+	wait := 2 * f.cfg.PingInterval
+	c.conn.SetReadLimit(maxRequestSize)
+	c.conn.SetReadDeadline(time.Now().Add(wait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(wait))
+
+		if string(data) == "PING" {
+			f.reply(c, []byte("PONG"))
+			continue
+		}
+		if err := f.handle(c, data); err != nil {
+			msg, _ := json.Marshal(ErrorMessage{EventType: EventError, Message: err.Error()})
+			f.reply(c, msg)
+		}
+	}
+}
+
+// handle applies one subscription request
+func (f *Feed) handle(c *client, data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("invalid request: %v", err)
+	}
+	switch req.Type {
+	case ChannelUser:
+		return f.authenticate(c, req)
+	case "", ChannelMarket:
+	default:
+		return fmt.Errorf("unknown channel %q", req.Type)
+	}
+
+	if len(req.AssetsIDs) == 0 {
+		return fmt.Errorf("assets_ids cannot be empty")
+	}
+
+	switch req.Operation {
+	case "", OpSubscribe:
+		f.subscribe(c, req.AssetsIDs)
+	case OpUnsubscribe:
+		f.unsubscribe(c, req.AssetsIDs)
+	default:
+		return fmt.Errorf("unknown operation %q", req.Operation)
+	}
+	return nil
+}
+
+// writeLoop writes queued messages and keepalive pings. When the queue is
+// closed it sends a close frame, telling dropped slow consumers to retry
+// later, and closes the connection.
+func (f *Feed) writeLoop(c *client) {
+	ticker := time.NewTicker(f.cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			if !ok {
+				code, reason := websocket.CloseNormalClosure, ""
+				if c.slow {
+					code, reason = websocket.CloseTryAgainLater, "slow consumer"
+				}
+				c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(f.cfg.WriteTimeout))
+				return
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(f.cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				f.remove(c)
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(f.cfg.WriteTimeout)); err != nil {
+				f.remove(c)
+				return
+			}
+		}
+	}
+}
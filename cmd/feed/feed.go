@@ -0,0 +1,484 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/auth"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/indexer"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+)
+
+// Config holds the connection parameters of the feed
+type Config struct {
+	SendBuffer   int           // messages queued per connection before it is dropped as a slow consumer
+	WriteTimeout time.Duration // deadline for writing one message to a connection
+	PingInterval time.Duration // interval between keepalive pings; connections silent for two intervals are closed
+}
+
+// LoadConfig reads the feed configuration from environment variables
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		SendBuffer:   256,
+		WriteTimeout: 10000 * time.Millisecond,
+		PingInterval: 30000 * time.Millisecond,
+	}
+
+	if v := os.Getenv("FEED_SEND_BUFFER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid FEED_SEND_BUFFER: %s (must be positive integer)", v)
+		}
+		cfg.SendBuffer = n
+	}
+
+	if v := os.Getenv("FEED_WRITE_TIMEOUT_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid FEED_WRITE_TIMEOUT_MS: %s (must be positive integer)", v)
+		}
+		cfg.WriteTimeout = time.Duration(n) * time.Millisecond
+	}
+
+	if v := os.Getenv("FEED_PING_INTERVAL_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid FEED_PING_INTERVAL_MS: %s (must be positive integer)", v)
+		}
+		cfg.PingInterval = time.Duration(n) * time.Millisecond
+	}
+
+	return cfg, nil
+}
+
+// Event types sent on the market channel
+const (
+	EventBook           = "book"             // L2 snapshot sent on subscribe
+	EventPriceChange    = "price_change"     // price levels that changed since the previous message
+	EventLastTradePrice = "last_trade_price" // a fill
+	EventBatch          = "batch"            // a batch moved to a new status
+	EventError          = "error"            // a request could not be handled
+)
+
+// BookMessage is the L2 snapshot of a market. Seq is the sequence number of
+// the last message of the market; the next price_change or last_trade_price
+// carries Seq+1.
+type BookMessage struct {
+	EventType string          `json:"event_type"`
+	AssetID   string          `json:"asset_id"`
+	Seq       uint64          `json:"seq"`
+	Bids      []matcher.Level `json:"bids"`
+	Asks      []matcher.Level `json:"asks"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// PriceChange is the new total size at one price level; a size of zero
+// removes the level. Bids are on the buy side and asks on the sell side.
+type PriceChange struct {
+	Price string       `json:"price"`
+	Side  matcher.Side `json:"side"`
+	Size  string       `json:"size"`
+}
+
+// PriceChangeMessage carries the price levels of a market that changed
+type PriceChangeMessage struct {
+	EventType string        `json:"event_type"`
+	AssetID   string        `json:"asset_id"`
+	Seq       uint64        `json:"seq"`
+	Changes   []PriceChange `json:"changes"`
+	Timestamp int64         `json:"timestamp"`
+}
+
+// TradeMessage is a trade print. Price is the maker's price and Side the
+// side of the taker.
+type TradeMessage struct {
+	EventType string       `json:"event_type"`
+	AssetID   string       `json:"asset_id"`
+	Seq       uint64       `json:"seq"`
+	Price     string       `json:"price"`
+	Size      string       `json:"size"`
+	Side      matcher.Side `json:"side"`
+	Timestamp int64        `json:"timestamp"`
+}
+
+// BatchStatus is a step in the life of a batch
+type BatchStatus string
+
+const (
+	BatchCut       BatchStatus = "cut"       // fills were cut into a batch and sequenced
+	BatchSigned    BatchStatus = "signed"    // operators signed the batch root
+	BatchSubmitted BatchStatus = "submitted" // the batch was submitted on-chain
+	BatchFinalized BatchStatus = "finalized" // the settling block is beyond the indexer's reorg depth
+	BatchFailed    BatchStatus = "failed"    // submission failed permanently; later batches are held back
+	BatchHeld      BatchStatus = "held"      // not submitted because an earlier batch failed
+)
+
+// BatchMessage reports a batch status change. Batches span markets, so
+// batch messages go to every subscribed or authenticated connection.
+type BatchMessage struct {
+	EventType   string      `json:"event_type"`
+	BatchID     uint64      `json:"batch_id"`
+	Status      BatchStatus `json:"status"`
+	Root        string      `json:"root"`
+	Fills       int         `json:"fills,omitempty"`
+	TxHash      string      `json:"tx_hash,omitempty"`
+	BlockNumber uint64      `json:"block_number,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	Timestamp   int64       `json:"timestamp"`
+}
+
+// ErrorMessage reports a request the feed could not handle
+type ErrorMessage struct {
+	EventType string `json:"event_type"`
+	Message   string `json:"message"`
+}
+
+// market is the last published state of one market and its subscribers
+type market struct {
+	seq  uint64
+	bids []matcher.Level
+	asks []matcher.Level
+	subs map[*client]struct{}
+}
+
+// Feed publishes market data and private user events to WebSocket
+// connections. The sequencer feeds it book changes, fills and cancels, and
+// the batcher, pipeline and indexer feed it batch status changes.
+type Feed struct {
+	cfg      Config
+	verifier *auth.Verifier
+	now      func() time.Time
+
+	mu      sync.Mutex
+	markets map[string]*market
+	users   map[string]map[*client]struct{} // authenticated connections by lowercase address
+	clients map[*client]struct{}
+}
+
+// New creates a feed with no subscribers. The user channel authenticates
+// wallets with verifier; without one it is disabled.
+func New(cfg Config, verifier *auth.Verifier) *Feed {
+	if cfg.SendBuffer < 1 {
+		cfg.SendBuffer = 1
+	}
+	return &Feed{
+		cfg:      cfg,
+		verifier: verifier,
+		now:      time.Now,
+		markets:  make(map[string]*market),
+		users:    make(map[string]map[*client]struct{}),
+		clients:  make(map[*client]struct{}),
+	}
+}
+
+// market returns the state of the market for asset, creating it if needed;
+// the caller must hold f.mu
+func (f *Feed) market(asset string) *market {
+	m, ok := f.markets[asset]
+	if !ok {
+		m = &market{subs: make(map[*client]struct{})}
+		f.markets[asset] = m
+	}
+	return m
+}
+
+// Update publishes the price levels that changed in every market, given the
+// whole order book. It seeds the feed with the recovered book; later changes
+// go through UpdateMarkets.
+func (f *Feed) Update(book []matcher.Order) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, o := range book {
+		f.market(o.TakerAsset)
+	}
+	assets := make([]string, 0, len(f.markets))
+	for asset := range f.markets {
+		assets = append(assets, asset)
+	}
+	f.publishLevels(book, assets)
+}
+
+// UpdateMarkets publishes the price levels that changed in markets, the
+// assets an input touched, given the whole order book. Other markets are not
+// recomputed. Calls must be made in sequence order, as the sequencer's book
+// hook does.
+func (f *Feed) UpdateMarkets(book []matcher.Order, markets []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.publishLevels(book, markets)
+}
+
+// publishLevels diffs the price levels of each of assets against book and
+// publishes the changes; the caller must hold f.mu
+func (f *Feed) publishLevels(book []matcher.Order, assets []string) {
+	now := f.now().UnixMilli()
+	for _, asset := range assets {
+		m := f.market(asset)
+		bids, asks := matcher.Depth(book, asset)
+		changes := diffLevels(m.bids, bids, matcher.SideBuy)
+		changes = append(changes, diffLevels(m.asks, asks, matcher.SideSell)...)
+		if len(changes) == 0 {
+			f.prune(asset, m)
+			continue
+		}
+
+		m.bids, m.asks = bids, asks
+		m.seq++
+		f.broadcast(m.subs, PriceChangeMessage{
+			EventType: EventPriceChange,
+			AssetID:   asset,
+			Seq:       m.seq,
+			Changes:   changes,
+			Timestamp: now,
+		})
+		f.prune(asset, m)
+	}
+}
+
+// zeroSize is the size of a removed price level
+const zeroSize = "0.00000000"
+
+// diffLevels returns the changes that turn the levels old into new
+func diffLevels(old, new []matcher.Level, side matcher.Side) []PriceChange {
+	prev := make(map[string]string, len(old))
+	for _, l := range old {
+		prev[l.Price] = l.Size
+	}
+
+	var changes []PriceChange
+	for _, l := range new {
+		if prev[l.Price] != l.Size {
+			changes = append(changes, PriceChange{Price: l.Price, Side: side, Size: l.Size})
+		}
+		delete(prev, l.Price)
+	}
+	for _, l := range old {
+		if _, removed := prev[l.Price]; removed {
+			changes = append(changes, PriceChange{Price: l.Price, Side: side, Size: zeroSize})
+		}
+	}
+	return changes
+}
+
+// Observe publishes market and user channel messages for matching and
+// cancellation events. It implements matcher.Observer.
+func (f *Feed) Observe(e matcher.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now().UnixMilli()
+	if e.Type == matcher.EventFill {
+		f.publishTrade(e, now)
+	}
+	f.publishUserEvent(e, now)
+}
+
+// publishTrade publishes the trade print of a fill event, in the market of
+// its maker; the caller must hold f.mu
+func (f *Feed) publishTrade(e matcher.Event, now int64) {
+	maker := e.Bid
+	if e.Fill.Side == matcher.SideBuy {
+		maker = e.Ask
+	}
+
+	m := f.market(maker.TakerAsset)
+	m.seq++
+	f.broadcast(m.subs, TradeMessage{
+		EventType: EventLastTradePrice,
+		AssetID:   maker.TakerAsset,
+		Seq:       m.seq,
+		Price:     e.Fill.Price,
+		Size:      e.Fill.Quantity,
+		Side:      e.Fill.Side,
+		Timestamp: now,
+	})
+	f.prune(maker.TakerAsset, m)
+}
+
+// BatchCut publishes a newly cut batch, and the Merkle proof of each fill to
+// its maker and taker on the user channel. It has the signature of a batcher
+// cut hook without the error, so it can run after the sequencer's.
+func (f *Feed) BatchCut(b pipeline.Batch, fills []matcher.Fill) {
+	f.publishBatch(BatchMessage{BatchID: b.ID, Status: BatchCut, Root: b.Root, Fills: len(fills)})
+	f.publishProofs(b, fills)
+}
+
+// BatchStage publishes a batch that was signed, submitted, failed or held
+// back. It is a pipeline stage hook.
+func (f *Feed) BatchStage(b pipeline.Batch, stage pipeline.Stage, detail string) {
+	msg := BatchMessage{BatchID: b.ID, Status: BatchSigned, Root: b.Root}
+	switch stage {
+	case pipeline.StageSubmitted:
+		msg.Status, msg.TxHash = BatchSubmitted, detail
+	case pipeline.StageFailed:
+		msg.Status, msg.Error = BatchFailed, detail
+	case pipeline.StageHeld:
+		msg.Status = BatchHeld
+	}
+	f.publishBatch(msg)
+}
+
+// BatchFinalized publishes a batch whose settlement is final. It is an indexer finality hook.
+func (f *Feed) BatchFinalized(b indexer.Batch) {
+	f.publishBatch(BatchMessage{BatchID: b.BatchID, Status: BatchFinalized, Root: b.Root, TxHash: b.TxHash, BlockNumber: b.BlockNumber})
+}
+
+// publishBatch sends a batch message to every connection with a subscription
+func (f *Feed) publishBatch(msg BatchMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	msg.EventType = EventBatch
+	msg.Timestamp = f.now().UnixMilli()
+	subs := make(map[*client]struct{})
+	for c := range f.clients {
+		if len(c.assets) > 0 || c.user != "" {
+			subs[c] = struct{}{}
+		}
+	}
+	f.broadcast(subs, msg)
+}
+
+// subscribe adds assets to a connection's subscriptions and queues a book
+// snapshot for each; holding f.mu keeps the snapshot and deltas in order
+func (f *Feed) subscribe(c *client, assets []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.clients[c]; !ok {
+		return
+	}
+	now := f.now().UnixMilli()
+	for _, asset := range assets {
+		m := f.market(asset)
+		m.subs[c] = struct{}{}
+		c.assets[asset] = struct{}{}
+		bids, asks := m.bids, m.asks
+		if bids == nil {
+			bids = []matcher.Level{}
+		}
+		if asks == nil {
+			asks = []matcher.Level{}
+		}
+		f.send(c, BookMessage{EventType: EventBook, AssetID: asset, Seq: m.seq, Bids: bids, Asks: asks, Timestamp: now})
+	}
+}
+
+// unsubscribe removes assets from a connection's subscriptions
+func (f *Feed) unsubscribe(c *client, assets []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, asset := range assets {
+		if m, ok := f.markets[asset]; ok {
+			delete(m.subs, c)
+			f.prune(asset, m)
+		}
+		delete(c.assets, asset)
+	}
+}
+
+// prune forgets a market nobody subscribes to that has no resting orders, so
+// subscriptions to unknown assets do not accumulate; the caller must hold f.mu
+func (f *Feed) prune(asset string, m *market) {
+	if len(m.subs) == 0 && len(m.bids) == 0 && len(m.asks) == 0 {
+		delete(f.markets, asset)
+	}
+}
+
+// reply queues encoded data for one connection
+func (f *Feed) reply(c *client, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enqueue(c, data)
+}
+
+// broadcast queues a message for every connection in subs; the caller must hold f.mu
+func (f *Feed) broadcast(subs map[*client]struct{}, msg interface{}) {
+	if len(subs) == 0 {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Feed: failed to encode message: %v", err)
+		return
+	}
+	for c := range subs {
+		f.enqueue(c, data)
+	}
+}
+
+// send queues a message for one connection; the caller must hold f.mu
+func (f *Feed) send(c *client, msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Feed: failed to encode message: %v", err)
+		return
+	}
+	f.enqueue(c, data)
+}
+
+// enqueue queues encoded data without blocking. A connection whose queue is
+// full has fallen behind; it is dropped rather than slowing down the
+// sequencer or the other subscribers. The caller must hold f.mu.
+func (f *Feed) enqueue(c *client, data []byte) {
+	if _, ok := f.clients[c]; !ok {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		log.Printf("Feed: dropping slow consumer %s after %d queued messages", c.addr, cap(c.send))
+		c.slow = true
+		f.removeLocked(c)
+	}
+}
+
+// add registers a connection
+func (f *Feed) add(c *client) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clients[c] = struct{}{}
+}
+
+// remove unregisters a connection and closes its queue
+func (f *Feed) remove(c *client) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeLocked(c)
+}
+
+// removeLocked is remove for callers holding f.mu
+func (f *Feed) removeLocked(c *client) {
+	if _, ok := f.clients[c]; !ok {
+		return
+	}
+	for asset := range c.assets {
+		if m, ok := f.markets[asset]; ok {
+			delete(m.subs, c)
+			f.prune(asset, m)
+		}
+	}
+	if c.user != "" {
+		delete(f.users[c.user], c)
+		if len(f.users[c.user]) == 0 {
+			delete(f.users, c.user)
+		}
+	}
+	delete(f.clients, c)
+	close(c.send)
+}
+
+// Connections returns the number of open connections
+func (f *Feed) Connections() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.clients)
+}
@@ -0,0 +1,335 @@
+package feed
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/auth"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/indexer"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/websocket"
+)
+
+func testConfig() Config {
+	return Config{SendBuffer: 16, WriteTimeout: time.Second, PingInterval: time.Minute}
+}
+
+func testOrder(maker, asset string, side matcher.Side, price float64, amount string) matcher.Order {
+	o := matcher.Order{Maker: maker, TakerAsset: asset, MakeAmount: amount, TakeAmount: amount, Price: price, Side: side, Signature: "0xsig"}
+	o.Hash = matcher.OrderHash(o)
+	return o
+}
+
+// dial connects a client to the feed and sends req
+func dial(t *testing.T, f *Feed, req Request) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	return conn
+}
+
+// next reads the next message into v and returns its event type
+func next(t *testing.T, conn *websocket.Conn, v interface{}) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	var head struct {
+		EventType string `json:"event_type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		t.Fatalf("invalid message %s: %v", data, err)
+	}
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("invalid %s message %s: %v", head.EventType, data, err)
+		}
+	}
+	return head.EventType
+}
+
+func TestDiffLevels(t *testing.T) {
+	old := []matcher.Level{{Price: "0.60000000", Size: "10.00000000"}, {Price: "0.50000000", Size: "5.00000000"}}
+	new := []matcher.Level{{Price: "0.60000000", Size: "4.00000000"}, {Price: "0.40000000", Size: "1.00000000"}}
+
+	got := diffLevels(old, new, matcher.SideBuy)
+	want := []PriceChange{
+		{Price: "0.60000000", Side: matcher.SideBuy, Size: "4.00000000"},
+		{Price: "0.40000000", Side: matcher.SideBuy, Size: "1.00000000"},
+		{Price: "0.50000000", Side: matcher.SideBuy, Size: zeroSize},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffLevels = %+v, want %+v", got, want)
+	}
+	if changes := diffLevels(new, new, matcher.SideSell); len(changes) != 0 {
+		t.Fatalf("unchanged levels produced %+v", changes)
+	}
+}
+
+func TestMarketChannel(t *testing.T) {
+	f := New(testConfig(), nil)
+	resting := testOrder("0xaaaaaaaaaa", "0xasset", matcher.SideBuy, 0.4, "10")
+	f.Update([]matcher.Order{resting})
+
+	conn := dial(t, f, Request{Type: ChannelMarket, AssetsIDs: []string{"0xasset"}})
+
+	// The snapshot carries the book as published so far
+	var book BookMessage
+	if typ := next(t, conn, &book); typ != EventBook {
+		t.Fatalf("first message is %s, want book", typ)
+	}
+	if book.Seq != 1 || !reflect.DeepEqual(book.Bids, []matcher.Level{{Price: "0.40000000", Size: "10.00000000"}}) || len(book.Asks) != 0 {
+		t.Fatalf("unexpected snapshot %+v", book)
+	}
+
+	// A taker sells 4 into the resting bid
+	taker := testOrder("0xbbbbbbbbbb", "0xasset", matcher.SideSell, 0.3, "4")
+	taker.Timestamp = 1 // arrives after the resting bid
+	taker.Hash = matcher.OrderHash(taker)
+	book2, events := matcher.Execute([]matcher.Order{resting}, taker, 10, matcher.Markets{})
+	for _, e := range events {
+		f.Observe(e)
+	}
+	f.UpdateMarkets(book2, []string{"0xasset"})
+
+	var trade TradeMessage
+	if typ := next(t, conn, &trade); typ != EventLastTradePrice {
+		t.Fatalf("got %s, want last_trade_price", typ)
+	}
+	if trade.Seq != 2 || trade.Price != "0.40000000" || trade.Size != "4.00000000" || trade.Side != matcher.SideSell {
+		t.Fatalf("unexpected trade %+v", trade)
+	}
+
+	var change PriceChangeMessage
+	if typ := next(t, conn, &change); typ != EventPriceChange {
+		t.Fatalf("got %s, want price_change", typ)
+	}
+	want := []PriceChange{{Price: "0.40000000", Side: matcher.SideBuy, Size: "6.00000000"}}
+	if change.Seq != 3 || !reflect.DeepEqual(change.Changes, want) {
+		t.Fatalf("unexpected price change %+v", change)
+	}
+
+	// Batch lifecycle events reach every subscriber
+	b := pipeline.Batch{ID: 7, Root: "0xroot"}
+	f.BatchCut(b, matcher.Fills(events))
+	f.BatchStage(b, pipeline.StageSigned, "")
+	f.BatchStage(b, pipeline.StageSubmitted, "0xtx")
+	f.BatchFinalized(indexer.Batch{BatchID: 7, Root: "0xroot", TxHash: "0xtx", BlockNumber: 12})
+	for _, status := range []BatchStatus{BatchCut, BatchSigned, BatchSubmitted, BatchFinalized} {
+		var msg BatchMessage
+		if typ := next(t, conn, &msg); typ != EventBatch || msg.Status != status || msg.BatchID != 7 {
+			t.Fatalf("got %s %+v, want batch %s", typ, msg, status)
+		}
+	}
+
+	// A permanently failed batch reports its error and holds back the next one
+	f.BatchStage(pipeline.Batch{ID: 8, Root: "0xbad"}, pipeline.StageFailed, "invalid BLS signature")
+	f.BatchStage(pipeline.Batch{ID: 9, Root: "0xnext"}, pipeline.StageHeld, "")
+	var failed, held BatchMessage
+	next(t, conn, &failed)
+	next(t, conn, &held)
+	if failed.Status != BatchFailed || failed.BatchID != 8 || failed.Error != "invalid BLS signature" {
+		t.Fatalf("got %+v, want batch 8 failed with its error", failed)
+	}
+	if held.Status != BatchHeld || held.BatchID != 9 {
+		t.Fatalf("got %+v, want batch 9 held", held)
+	}
+
+	// Other markets are not delivered, and an unsubscribed market goes quiet
+	f.UpdateMarkets(append(book2, testOrder("0xcccccccccc", "0xother", matcher.SideBuy, 0.2, "1")), []string{"0xother"})
+	conn.WriteJSON(Request{Operation: OpUnsubscribe, AssetsIDs: []string{"0xasset"}})
+	ping(t, conn)
+	f.UpdateMarkets(nil, []string{"0xasset", "0xother"})
+	ping(t, conn)
+}
+
+func TestUpdateMarketsDiffsOnlyTouchedMarkets(t *testing.T) {
+	f := New(testConfig(), nil)
+	f.Update([]matcher.Order{
+		testOrder("0xaaaaaaaaaa", "0xasset", matcher.SideBuy, 0.4, "10"),
+		testOrder("0xaaaaaaaaaa", "0xother", matcher.SideBuy, 0.3, "10"),
+	})
+
+	// Only the market the input touched is recomputed, so the other keeps
+	// the levels it was last published with
+	f.UpdateMarkets([]matcher.Order{testOrder("0xaaaaaaaaaa", "0xother", matcher.SideBuy, 0.3, "10")}, []string{"0xother"})
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if m := f.markets["0xasset"]; m == nil || len(m.bids) != 1 || m.seq != 1 {
+		t.Fatalf("untouched market was recomputed: %+v", m)
+	}
+	if m := f.markets["0xother"]; m == nil || m.seq != 1 {
+		t.Fatalf("unchanged market published again: %+v", m)
+	}
+}
+
+// ping checks that the next message is the reply to a PING
+func ping(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	conn.WriteMessage(websocket.TextMessage, []byte("PING"))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "PONG" {
+		t.Fatalf("got %q (%v), want PONG", data, err)
+	}
+}
+
+func TestRejectsInvalidRequests(t *testing.T) {
+	f := New(testConfig(), nil)
+	for _, req := range []Request{
+		{Type: "user", AssetsIDs: []string{"0xasset"}},
+		{Type: ChannelMarket},
+		{Operation: "resubscribe", AssetsIDs: []string{"0xasset"}},
+	} {
+		conn := dial(t, f, req)
+		var msg ErrorMessage
+		if typ := next(t, conn, &msg); typ != EventError || msg.Message == "" {
+			t.Errorf("request %+v: got %s %+v, want error", req, typ, msg)
+		}
+	}
+}
+
+func TestChecksBrowserOrigin(t *testing.T) {
+	f := New(testConfig(), auth.NewVerifier(auth.Config{AllowedOrigins: []string{"https://app.example.com"}}))
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for origin, want := range map[string]bool{
+		"https://app.example.com": true,
+		"https://evil.example":    false,
+	} {
+		conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {origin}})
+		if (err == nil) != want {
+			t.Errorf("dial from %s: err %v, want allowed %v", origin, err, want)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
+
+func TestDropsSlowConsumer(t *testing.T) {
+	cfg := testConfig()
+	cfg.SendBuffer = 1
+	f := New(cfg, nil)
+
+	// Queue messages faster than the client reads them
+	conn := dial(t, f, Request{AssetsIDs: []string{"0xasset"}})
+	next(t, conn, nil)
+	deadline := time.Now().Add(2 * time.Second)
+	for f.Connections() == 1 && time.Now().Before(deadline) {
+		f.mu.Lock()
+		for c := range f.clients {
+			for i := 0; i < 64; i++ {
+				f.enqueue(c, []byte(`{}`))
+			}
+		}
+		f.mu.Unlock()
+	}
+	if f.Connections() != 0 {
+		t.Fatalf("slow consumer was not dropped")
+	}
+
+	// The client sees its queued messages and then a close frame
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+				t.Fatalf("expected a try-again-later close, got %v", err)
+			}
+			return
+		}
+	}
+}
+
+func TestUserChannel(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	user := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	f := New(testConfig(), auth.NewVerifier(auth.Config{ChainID: 1, MaxAge: time.Minute}))
+
+	// A signature from another chain is rejected
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	wrongChain, _ := auth.Sign(key, 137, ts, 0)
+	var errMsg ErrorMessage
+	if typ := next(t, dial(t, f, Request{Type: ChannelUser, Auth: &wrongChain}), &errMsg); typ != EventError {
+		t.Fatalf("got %s, want error", typ)
+	}
+
+	wa, _ := auth.Sign(key, 1, ts, 0)
+	conn := dial(t, f, Request{Type: ChannelUser, Auth: &wa})
+	ping(t, conn)
+
+	// The user's order rests, then a taker from another wallet partially fills it
+	resting := testOrder(user, "0xasset", matcher.SideBuy, 0.4, "10")
+	resting.Seq = 1
+	book, events := matcher.Execute(nil, resting, 10, matcher.Markets{})
+	taker := testOrder("0xbbbbbbbbbb", "0xasset", matcher.SideSell, 0.3, "4")
+	taker.Seq = 2
+	book, fillEvents := matcher.Execute(book, taker, 10, matcher.Markets{})
+	for _, e := range append(events, fillEvents...) {
+		f.Observe(e)
+	}
+
+	var placed OrderMessage
+	if typ := next(t, conn, &placed); typ != EventOrder || placed.Type != OrderPlacement || placed.OrderHash != resting.Hash || placed.Status != matcher.StatusLive {
+		t.Fatalf("got %s %+v, want placement", typ, placed)
+	}
+	var update OrderMessage
+	next(t, conn, &update)
+	if update.Type != OrderUpdate || update.Side != matcher.SideBuy || update.SizeMatched != "4.00000000" ||
+		update.MakeAmount != "6.00000000" || update.Status != matcher.StatusPartiallyFilled {
+		t.Fatalf("unexpected update %+v", update)
+	}
+	var matched UserTradeMessage
+	next(t, conn, &matched)
+	if matched.Status != TradeMatched || matched.Role != RoleMaker || matched.AssetID != "0xasset" || matched.Fill.Quantity != "4.00000000" {
+		t.Fatalf("unexpected trade %+v", matched)
+	}
+
+	// Once the fill is batched, the user receives its Merkle proof
+	fills := matcher.Fills(fillEvents)
+	root, _, _ := matcher.BuildBatch(fills)
+	f.BatchCut(pipeline.Batch{ID: 3, Root: root}, fills)
+	var batch BatchMessage
+	if typ := next(t, conn, &batch); typ != EventBatch || batch.Status != BatchCut {
+		t.Fatalf("got %s %+v, want batch cut", typ, batch)
+	}
+	var batched UserTradeMessage
+	next(t, conn, &batched)
+	if batched.Status != TradeBatched || batched.BatchID != 3 || batched.Root != root || batched.Proof == nil || !batched.Proof.Verify(fills[0], root) {
+		t.Fatalf("unexpected batched trade %+v", batched)
+	}
+
+	// Cancelling the remainder
+	f.Observe(matcher.Event{Type: matcher.EventOrderCancelled, Order: book[0]})
+	var cancelled OrderMessage
+	if next(t, conn, &cancelled); cancelled.Type != OrderCancellation || cancelled.OrderHash != resting.Hash {
+		t.Fatalf("unexpected cancellation %+v", cancelled)
+	}
+
+	// An order of the user expiring
+	f.Observe(matcher.Event{Type: matcher.EventOrderExpired, Order: resting})
+	var expired OrderMessage
+	if next(t, conn, &expired); expired.Type != OrderExpiration || expired.OrderHash != resting.Hash {
+		t.Fatalf("unexpected expiration %+v", expired)
+	}
+}
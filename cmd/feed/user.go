@@ -0,0 +1,225 @@
+package feed
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+)
+
+// Event types sent on the user channel
+const (
+	EventOrder = "order" // an order of the user changed
+	EventTrade = "trade" // a fill of the user was matched or batched
+)
+
+// OrderEventType is what happened to an order
+type OrderEventType string
+
+const (
+	OrderPlacement    OrderEventType = "placement"    // the order was accepted by the sequencer
+	OrderUpdate       OrderEventType = "update"       // the order was partially or fully filled
+	OrderCancellation OrderEventType = "cancellation" // the order was removed from the book without filling
+	OrderExpiration   OrderEventType = "expiration"   // the order passed its expiration and was removed from the book
+)
+
+// OrderMessage reports a change to one of the user's orders. MakeAmount and
+// TakeAmount are the order's amounts after the change; a fill reduces the
+// makeAmount of a bid and the takeAmount of an ask. Side is only known once
+// an order fills.
+type OrderMessage struct {
+	EventType   string              `json:"event_type"`
+	Type        OrderEventType      `json:"type"`
+	OrderHash   string              `json:"order_hash"`
+	Owner       string              `json:"owner"`
+	AssetID     string              `json:"asset_id"`
+	Price       string              `json:"price"`
+	Side        matcher.Side        `json:"side,omitempty"`
+	SizeMatched string              `json:"size_matched,omitempty"`
+	MakeAmount  string              `json:"make_amount"`
+	TakeAmount  string              `json:"take_amount"`
+	Status      matcher.OrderStatus `json:"status,omitempty"`
+	Timestamp   int64               `json:"timestamp"`
+}
+
+// TradeStatus is how far a fill has progressed towards settlement
+type TradeStatus string
+
+const (
+	TradeMatched TradeStatus = "matched" // the fill was matched; it has no batch yet
+	TradeBatched TradeStatus = "batched" // the fill was cut into a batch; root and proof are set
+)
+
+// Roles of the user in a fill
+const (
+	RoleMaker = "maker"
+	RoleTaker = "taker"
+)
+
+// UserTradeMessage reports one of the user's fills. Once the fill is cut
+// into a batch it is sent again with the batch root and the Merkle proof of
+// its leaf; the batch's later status arrives as batch messages.
+type UserTradeMessage struct {
+	EventType string               `json:"event_type"`
+	Status    TradeStatus          `json:"status"`
+	Role      string               `json:"role"`
+	AssetID   string               `json:"asset_id,omitempty"`
+	Fill      matcher.Fill         `json:"fill"`
+	BatchID   uint64               `json:"batch_id,omitempty"`
+	Root      string               `json:"root,omitempty"`
+	Proof     *matcher.MerkleProof `json:"proof,omitempty"`
+	Timestamp int64                `json:"timestamp"`
+}
+
+// authenticate verifies a user channel request and subscribes the connection
+// to the events of the signing wallet
+func (f *Feed) authenticate(c *client, req Request) error {
+	if f.verifier == nil {
+		return fmt.Errorf("user channel is not available")
+	}
+	if req.Auth == nil {
+		return fmt.Errorf("auth is required on the user channel")
+	}
+	address, err := f.verifier.Verify(*req.Auth)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+	user := strings.ToLower(address.Hex())
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.clients[c]; !ok {
+		return nil
+	}
+	if c.user != "" && c.user != user {
+		return fmt.Errorf("connection is already authenticated as %s", c.user)
+	}
+	c.user = user
+	if f.users[user] == nil {
+		f.users[user] = make(map[*client]struct{})
+	}
+	f.users[user][c] = struct{}{}
+	log.Printf("Feed: %s authenticated as %s", c.addr, address.Hex())
+	return nil
+}
+
+// publishUserEvent sends order and trade messages for an event to the
+// connections of the addresses it concerns; the caller must hold f.mu
+func (f *Feed) publishUserEvent(e matcher.Event, now int64) {
+	if len(f.users) == 0 {
+		return
+	}
+
+	switch e.Type {
+	case matcher.EventOrderAccepted:
+		f.publishOrder(orderMessage(OrderPlacement, e.Order, now, matcher.StatusLive))
+
+	case matcher.EventOrderCancelled:
+		f.publishOrder(orderMessage(OrderCancellation, e.Order, now, ""))
+
+	case matcher.EventOrderExpired:
+		f.publishOrder(orderMessage(OrderExpiration, e.Order, now, ""))
+
+	case matcher.EventFill:
+		bidLeft, askLeft := e.Remaining()
+		bid := orderMessage(OrderUpdate, e.Bid, now, fillStatus(bidLeft))
+		bid.Side, bid.SizeMatched, bid.MakeAmount = matcher.SideBuy, e.Fill.Quantity, bidLeft
+		ask := orderMessage(OrderUpdate, e.Ask, now, fillStatus(askLeft))
+		ask.Side, ask.SizeMatched, ask.TakeAmount = matcher.SideSell, e.Fill.Quantity, askLeft
+		f.publishOrder(bid)
+		f.publishOrder(ask)
+
+		maker := e.Bid
+		if e.Fill.Side == matcher.SideBuy {
+			maker = e.Ask
+		}
+		for _, role := range []struct{ name, address string }{{RoleMaker, e.Fill.Maker}, {RoleTaker, e.Fill.Taker}} {
+			f.broadcast(f.users[strings.ToLower(role.address)], UserTradeMessage{
+				EventType: EventTrade,
+				Status:    TradeMatched,
+				Role:      role.name,
+				AssetID:   maker.TakerAsset,
+				Fill:      e.Fill,
+				Timestamp: now,
+			})
+		}
+	}
+}
+
+// publishOrder sends an order message to the connections of its owner; the caller must hold f.mu
+func (f *Feed) publishOrder(msg OrderMessage) {
+	f.broadcast(f.users[strings.ToLower(msg.Owner)], msg)
+}
+
+// orderMessage describes an order as it is after an event
+func orderMessage(typ OrderEventType, o matcher.Order, now int64, status matcher.OrderStatus) OrderMessage {
+	return OrderMessage{
+		EventType:  EventOrder,
+		Type:       typ,
+		OrderHash:  o.Hash,
+		Owner:      o.Maker,
+		AssetID:    o.TakerAsset,
+		Price:      fmt.Sprintf("%.8f", o.Price),
+		MakeAmount: o.MakeAmount,
+		TakeAmount: o.TakeAmount,
+		Status:     status,
+		Timestamp:  now,
+	}
+}
+
+// fillStatus is the status of an order with remaining left on its filling
+// side; like the matcher, it treats dust as filled
+func fillStatus(remaining string) matcher.OrderStatus {
+	if v, err := strconv.ParseFloat(remaining, 64); err == nil && v <= 0.00000001 {
+		return matcher.StatusFilled
+	}
+	return matcher.StatusPartiallyFilled
+}
+
+// publishProofs sends each fill of a newly cut batch, with its Merkle proof,
+// to the connections of its maker and taker
+func (f *Feed) publishProofs(b pipeline.Batch, fills []matcher.Fill) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	wanted := false
+	for _, fill := range fills {
+		if f.users[strings.ToLower(fill.Maker)] != nil || f.users[strings.ToLower(fill.Taker)] != nil {
+			wanted = true
+			break
+		}
+	}
+	if !wanted {
+		return
+	}
+
+	root, proofs, err := matcher.BatchProofs(fills)
+	if err != nil {
+		log.Printf("Feed: failed to build proofs for batch %d: %v", b.ID, err)
+		return
+	}
+	if root != b.Root {
+		log.Printf("Feed: batch %d root %s differs from proof root %s", b.ID, b.Root, root)
+		return
+	}
+
+	now := f.now().UnixMilli()
+	for i, fill := range fills {
+		for _, role := range []struct{ name, address string }{{RoleMaker, fill.Maker}, {RoleTaker, fill.Taker}} {
+			f.broadcast(f.users[strings.ToLower(role.address)], UserTradeMessage{
+				EventType: EventTrade,
+				Status:    TradeBatched,
+				Role:      role.name,
+				Fill:      fill,
+				BatchID:   b.ID,
+				Root:      b.Root,
+				Proof:     &proofs[i],
+				Timestamp: now,
+			})
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Event signatures followed by the indexer
+var (
+	batchSubmittedEvent = contracts.BatchSettlementABI.Events["BatchSubmitted"]
+	disputedEvent       = contracts.DisputeGameABI.Events["Disputed"]
+)
+
+// Chain is the subset of the Ethereum client used by the indexer
+type Chain interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// Config holds the contracts to follow and the polling parameters
+type Config struct {
+	BatchSettlement common.Address
+	DisputeGame     common.Address
+	StartBlock      uint64
+	PollInterval    time.Duration
+	BlockRange      uint64
+	ReorgDepth      uint64
+}
+
+// LoadConfig reads the indexer configuration from environment variables
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		PollInterval: 2000 * time.Millisecond,
+		BlockRange:   1000,
+		ReorgDepth:   64,
+	}
+
+	batchSettlement := os.Getenv("CONTRACT_ADDRESS")
+	if batchSettlement == "" {
+		batchSettlement = os.Getenv("BATCH_SETTLEMENT_ADDRESS")
+	}
+	if batchSettlement == "" {
+		batchSettlement = "0x5FbDB2315678afecb367f032d93F642f64180aa3" // Default local
+	}
+	if !common.IsHexAddress(batchSettlement) {
+		return cfg, fmt.Errorf("invalid CONTRACT_ADDRESS: %s", batchSettlement)
+	}
+	cfg.BatchSettlement = common.HexToAddress(batchSettlement)
+
+	if v := os.Getenv("DISPUTE_GAME_ADDRESS"); v != "" {
+		if !common.IsHexAddress(v) {
+			return cfg, fmt.Errorf("invalid DISPUTE_GAME_ADDRESS: %s", v)
+		}
+		cfg.DisputeGame = common.HexToAddress(v)
+	}
+
+	if v := os.Getenv("INDEXER_START_BLOCK"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid INDEXER_START_BLOCK: %s (must be non-negative integer)", v)
+		}
+		cfg.StartBlock = n
+	}
+
+	if v := os.Getenv("INDEXER_POLL_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid INDEXER_POLL_MS: %s (must be positive integer)", v)
+		}
+		cfg.PollInterval = time.Duration(n) * time.Millisecond
+	}
+
+	if v := os.Getenv("INDEXER_BLOCK_RANGE"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid INDEXER_BLOCK_RANGE: %s (must be positive integer)", v)
+		}
+		cfg.BlockRange = n
+	}
+
+	if v := os.Getenv("INDEXER_REORG_DEPTH"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid INDEXER_REORG_DEPTH: %s (must be positive integer)", v)
+		}
+		cfg.ReorgDepth = n
+	}
+
+	return cfg, nil
+}
+
+// Indexer follows BatchSettlement and DisputeGame logs and stores them locally
+type Indexer struct {
+	cfg   Config
+	chain Chain
+	store *Store
+
+	mu      sync.RWMutex
+	next    uint64                 // next block to index
+	hashes  map[uint64]common.Hash // hashes of recently indexed blocks, for reorg detection
+	unfinal []Batch                // indexed batches still within ReorgDepth of the head
+	onFinal func(Batch)
+}
+
+// New creates an indexer that starts at cfg.StartBlock
+func New(cfg Config, chain Chain, store *Store) *Indexer {
+	if cfg.BlockRange == 0 {
+		cfg.BlockRange = 1
+	}
+	if cfg.ReorgDepth == 0 {
+		cfg.ReorgDepth = 1
+	}
+
+	return &Indexer{
+		cfg:    cfg,
+		chain:  chain,
+		store:  store,
+		next:   cfg.StartBlock,
+		hashes: make(map[uint64]common.Hash),
+	}
+}
+
+// OnFinalized registers a hook called for each indexed batch once ReorgDepth
+// blocks have been built on top of the block that settled it
+func (ix *Indexer) OnFinalized(fn func(Batch)) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.onFinal = fn
+}
+
+// Run polls the chain until ctx is cancelled
+func (ix *Indexer) Run(ctx context.Context) {
+	log.Printf("Indexer started - BatchSettlement: %s, DisputeGame: %s, Start block: %d",
+		ix.cfg.BatchSettlement.Hex(), ix.cfg.DisputeGame.Hex(), ix.cfg.StartBlock)
+
+	ticker := time.NewTicker(ix.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep indexing without waiting while catching up
+		for {
+			caughtUp, err := ix.poll(ctx)
+			if err != nil {
+				log.Printf("Indexer poll error: %v", err)
+				break
+			}
+			if caughtUp {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IndexedBlock returns the last block that has been indexed
+func (ix *Indexer) IndexedBlock() uint64 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if ix.next == 0 {
+		return 0
+	}
+	return ix.next - 1
+}
+
+// poll handles any reorg and indexes the next block range, reporting whether it reached the head
+func (ix *Indexer) poll(ctx context.Context) (bool, error) {
+	head, err := ix.chain.BlockNumber(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get head block: %w", err)
+	}
+
+	if err := ix.handleReorg(ctx); err != nil {
+		return false, err
+	}
+
+	ix.mu.RLock()
+	from := ix.next
+	ix.mu.RUnlock()
+
+	if from > head {
+		return true, nil
+	}
+
+	to := from + ix.cfg.BlockRange - 1
+	if to > head {
+		to = head
+	}
+
+	logs, err := ix.chain.FilterLogs(ctx, ix.query(from, to))
+	if err != nil {
+		return false, fmt.Errorf("failed to filter logs for blocks %d-%d: %w", from, to, err)
+	}
+
+	// Record hashes for blocks close enough to the head to be reorged, plus the range end
+	hashes := make(map[uint64]common.Hash)
+	low := from
+	if head >= ix.cfg.ReorgDepth && head-ix.cfg.ReorgDepth+1 > low {
+		low = head - ix.cfg.ReorgDepth + 1
+	}
+	for b := low; b <= to; b++ {
+		header, err := ix.chain.HeaderByNumber(ctx, new(big.Int).SetUint64(b))
+		if err != nil {
+			return false, fmt.Errorf("failed to get header %d: %w", b, err)
+		}
+		hashes[b] = header.Hash()
+	}
+	if _, ok := hashes[to]; !ok {
+		header, err := ix.chain.HeaderByNumber(ctx, new(big.Int).SetUint64(to))
+		if err != nil {
+			return false, fmt.Errorf("failed to get header %d: %w", to, err)
+		}
+		hashes[to] = header.Hash()
+	}
+
+	var batches []Batch
+	var disputes []Dispute
+	for _, lg := range logs {
+		if lg.Removed {
+			continue
+		}
+
+		// Logs and headers must come from the same fork; otherwise retry on the next poll
+		if h, ok := hashes[lg.BlockNumber]; ok && h != lg.BlockHash {
+			return false, fmt.Errorf("chain reorganized while indexing block %d, retrying", lg.BlockNumber)
+		}
+
+		switch {
+		case lg.Address == ix.cfg.BatchSettlement && len(lg.Topics) > 0 && lg.Topics[0] == batchSubmittedEvent.ID:
+			b, err := decodeBatchSubmitted(lg)
+			if err != nil {
+				log.Printf("Indexer: skipping malformed BatchSubmitted log in tx %s: %v", lg.TxHash.Hex(), err)
+				continue
+			}
+			batches = append(batches, b)
+		case lg.Address == ix.cfg.DisputeGame && len(lg.Topics) > 0 && lg.Topics[0] == disputedEvent.ID:
+			d, err := decodeDisputed(lg)
+			if err != nil {
+				log.Printf("Indexer: skipping malformed Disputed log in tx %s: %v", lg.TxHash.Hex(), err)
+				continue
+			}
+			disputes = append(disputes, d)
+		}
+	}
+
+	for _, b := range batches {
+		ix.store.AddBatch(b)
+	}
+	for _, d := range disputes {
+		ix.store.AddDispute(d)
+	}
+
+	ix.mu.Lock()
+	for b, h := range hashes {
+		ix.hashes[b] = h
+	}
+	for b := range ix.hashes {
+		if to >= ix.cfg.ReorgDepth && b <= to-ix.cfg.ReorgDepth {
+			delete(ix.hashes, b)
+		}
+	}
+	ix.next = to + 1
+	ix.unfinal = append(ix.unfinal, batches...)
+	final := ix.takeFinal(head)
+	onFinal := ix.onFinal
+	ix.mu.Unlock()
+
+	if onFinal != nil {
+		for _, b := range final {
+			onFinal(b)
+		}
+	}
+
+	if len(batches) > 0 || len(disputes) > 0 {
+		log.Printf("Indexed blocks %d-%d: %d batches, %d disputes", from, to, len(batches), len(disputes))
+	}
+
+	return to == head, nil
+}
+
+// takeFinal removes and returns the batches at least ReorgDepth blocks below
+// head; the caller must hold ix.mu
+func (ix *Indexer) takeFinal(head uint64) []Batch {
+	var final []Batch
+	kept := ix.unfinal[:0]
+	for _, b := range ix.unfinal {
+		if head >= b.BlockNumber+ix.cfg.ReorgDepth {
+			final = append(final, b)
+		} else {
+			kept = append(kept, b)
+		}
+	}
+	ix.unfinal = kept
+	return final
+}
+
+// handleReorg compares the last indexed block with the chain and rewinds to the
+// most recent common ancestor if they diverged
+func (ix *Indexer) handleReorg(ctx context.Context) error {
+	ix.mu.RLock()
+	next := ix.next
+	stored, ok := ix.hashes[next-1]
+	ix.mu.RUnlock()
+
+	if next == 0 || !ok {
+		return nil
+	}
+
+	header, err := ix.chain.HeaderByNumber(ctx, new(big.Int).SetUint64(next-1))
+	if err != nil {
+		return fmt.Errorf("failed to get header %d: %w", next-1, err)
+	}
+	if header.Hash() == stored {
+		return nil
+	}
+
+	// Walk back through the recorded hashes looking for a block still on the canonical chain
+	ix.mu.RLock()
+	lowest := next - 1
+	for b := range ix.hashes {
+		if b < lowest {
+			lowest = b
+		}
+	}
+	ix.mu.RUnlock()
+
+	ancestor, found := uint64(0), false
+	for b := next - 1; b > lowest; {
+		b--
+		ix.mu.RLock()
+		h, ok := ix.hashes[b]
+		ix.mu.RUnlock()
+		if !ok {
+			continue
+		}
+
+		header, err := ix.chain.HeaderByNumber(ctx, new(big.Int).SetUint64(b))
+		if err != nil {
+			return fmt.Errorf("failed to get header %d: %w", b, err)
+		}
+		if header.Hash() == h {
+			ancestor, found = b, true
+			break
+		}
+	}
+
+	// Reorg deeper than the recorded window: re-index everything we can no longer verify
+	if !found && lowest > 0 {
+		ancestor = lowest - 1
+	}
+
+	removedBatches, removedDisputes := ix.store.RewindTo(ancestor)
+
+	ix.mu.Lock()
+	for b := range ix.hashes {
+		if b > ancestor {
+			delete(ix.hashes, b)
+		}
+	}
+	kept := ix.unfinal[:0]
+	for _, b := range ix.unfinal {
+		if b.BlockNumber <= ancestor {
+			kept = append(kept, b)
+		}
+	}
+	ix.unfinal = kept
+	ix.next = ancestor + 1
+	if ix.next < ix.cfg.StartBlock {
+		ix.next = ix.cfg.StartBlock
+	}
+	ix.mu.Unlock()
+
+	log.Printf("⚠️  Reorg detected at block %d, rewound to block %d (removed %d batches, %d disputes)",
+		next-1, ancestor, removedBatches, removedDisputes)
+
+	return nil
+}
+
+// query builds the log filter for a block range
+func (ix *Indexer) query(from, to uint64) ethereum.FilterQuery {
+	addresses := []common.Address{ix.cfg.BatchSettlement}
+	if ix.cfg.DisputeGame != (common.Address{}) {
+		addresses = append(addresses, ix.cfg.DisputeGame)
+	}
+
+	return ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: addresses,
+		Topics:    [][]common.Hash{{batchSubmittedEvent.ID, disputedEvent.ID}},
+	}
+}
+
+// decodeBatchSubmitted decodes a BatchSubmitted(root, submitter, blockNumber, batchId) log
+func decodeBatchSubmitted(lg types.Log) (Batch, error) {
+	if len(lg.Topics) != 3 {
+		return Batch{}, fmt.Errorf("expected 3 topics, got %d", len(lg.Topics))
+	}
+
+	values, err := batchSubmittedEvent.Inputs.NonIndexed().Unpack(lg.Data)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to unpack data: %w", err)
+	}
+	batchID, ok := values[1].(*big.Int)
+	if !ok {
+		return Batch{}, fmt.Errorf("unexpected batchId type %T", values[1])
+	}
+
+	return Batch{
+		BatchID:     batchID.Uint64(),
+		Root:        lg.Topics[1].Hex(),
+		Submitter:   common.BytesToAddress(lg.Topics[2].Bytes()).Hex(),
+		BlockNumber: lg.BlockNumber,
+		BlockHash:   lg.BlockHash.Hex(),
+		TxHash:      lg.TxHash.Hex(),
+		LogIndex:    lg.Index,
+	}, nil
+}
+
+// decodeDisputed decodes a Disputed(root, challenger, orderAIdx, orderBIdx, slashedOperators) log
+func decodeDisputed(lg types.Log) (Dispute, error) {
+	if len(lg.Topics) != 3 {
+		return Dispute{}, fmt.Errorf("expected 3 topics, got %d", len(lg.Topics))
+	}
+
+	values, err := disputedEvent.Inputs.NonIndexed().Unpack(lg.Data)
+	if err != nil {
+		return Dispute{}, fmt.Errorf("failed to unpack data: %w", err)
+	}
+
+	nums := make([]uint64, len(values))
+	for i, v := range values {
+		n, ok := v.(*big.Int)
+		if !ok {
+			return Dispute{}, fmt.Errorf("unexpected field %d type %T", i, v)
+		}
+		nums[i] = n.Uint64()
+	}
+
+	return Dispute{
+		Root:             lg.Topics[1].Hex(),
+		Challenger:       common.BytesToAddress(lg.Topics[2].Bytes()).Hex(),
+		OrderAIdx:        nums[0],
+		OrderBIdx:        nums[1],
+		SlashedOperators: nums[2],
+		BlockNumber:      lg.BlockNumber,
+		BlockHash:        lg.BlockHash.Hex(),
+		TxHash:           lg.TxHash.Hex(),
+		LogIndex:         lg.Index,
+	}, nil
+}
@@ -0,0 +1,227 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	testSettlement = common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	testDisputes   = common.HexToAddress("0xe7f1725E7734CE288F8367e1Bb143E90bb3F0512")
+	testSubmitter  = common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+)
+
+// fakeChain is an in-memory chain whose tail can be replaced to simulate reorgs
+type fakeChain struct {
+	headers []*types.Header
+	logs    map[uint64][]types.Log
+}
+
+func newFakeChain(n int) *fakeChain {
+	c := &fakeChain{logs: make(map[uint64][]types.Log)}
+	c.extend(n, "a")
+	return c
+}
+
+// extend appends n blocks tagged with fork so hashes differ between forks
+func (c *fakeChain) extend(n int, fork string) {
+	for i := 0; i < n; i++ {
+		var parent common.Hash
+		if len(c.headers) > 0 {
+			parent = c.headers[len(c.headers)-1].Hash()
+		}
+		c.headers = append(c.headers, &types.Header{
+			Number:     big.NewInt(int64(len(c.headers))),
+			ParentHash: parent,
+			Extra:      []byte(fork),
+			Difficulty: big.NewInt(1),
+		})
+	}
+}
+
+// reorg drops every block from number onwards and mines n replacement blocks
+func (c *fakeChain) reorg(number uint64, n int, fork string) {
+	c.headers = c.headers[:number]
+	for b := range c.logs {
+		if b >= number {
+			delete(c.logs, b)
+		}
+	}
+	c.extend(n, fork)
+}
+
+// addBatch emits a BatchSubmitted log in the given block
+func (c *fakeChain) addBatch(block uint64, root common.Hash, batchID int64) {
+	data, err := batchSubmittedEvent.Inputs.NonIndexed().Pack(big.NewInt(int64(block)), big.NewInt(batchID))
+	if err != nil {
+		panic(err)
+	}
+	c.logs[block] = append(c.logs[block], types.Log{
+		Address:     testSettlement,
+		Topics:      []common.Hash{batchSubmittedEvent.ID, root, common.BytesToHash(testSubmitter.Bytes())},
+		Data:        data,
+		BlockNumber: block,
+		BlockHash:   c.headers[block].Hash(),
+	})
+}
+
+// addDispute emits a Disputed log in the given block
+func (c *fakeChain) addDispute(block uint64, root common.Hash) {
+	data, err := disputedEvent.Inputs.NonIndexed().Pack(big.NewInt(1), big.NewInt(2), big.NewInt(3))
+	if err != nil {
+		panic(err)
+	}
+	c.logs[block] = append(c.logs[block], types.Log{
+		Address:     testDisputes,
+		Topics:      []common.Hash{disputedEvent.ID, root, common.BytesToHash(testSubmitter.Bytes())},
+		Data:        data,
+		BlockNumber: block,
+		BlockHash:   c.headers[block].Hash(),
+	})
+}
+
+func (c *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	return uint64(len(c.headers) - 1), nil
+}
+
+func (c *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number.Uint64() >= uint64(len(c.headers)) {
+		return nil, ethereum.NotFound
+	}
+	return c.headers[number.Uint64()], nil
+}
+
+func (c *fakeChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var out []types.Log
+	for b := q.FromBlock.Uint64(); b <= q.ToBlock.Uint64(); b++ {
+		out = append(out, c.logs[b]...)
+	}
+	return out, nil
+}
+
+func newTestIndexer(chain Chain) (*Indexer, *Store) {
+	store := NewStore()
+	cfg := Config{
+		BatchSettlement: testSettlement,
+		DisputeGame:     testDisputes,
+		BlockRange:      4,
+		ReorgDepth:      8,
+	}
+	return New(cfg, chain, store), store
+}
+
+func pollUntilCaughtUp(t *testing.T, ix *Indexer) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		caughtUp, err := ix.poll(context.Background())
+		if err != nil {
+			t.Fatalf("poll failed: %v", err)
+		}
+		if caughtUp {
+			return
+		}
+	}
+	t.Fatalf("indexer did not catch up")
+}
+
+func TestIndexerIndexesBatchesAndDisputes(t *testing.T) {
+	chain := newFakeChain(10)
+	chain.addBatch(2, common.HexToHash("0x01"), 1)
+	chain.addBatch(7, common.HexToHash("0x02"), 2)
+	chain.addDispute(9, common.HexToHash("0x01"))
+
+	ix, store := newTestIndexer(chain)
+	pollUntilCaughtUp(t, ix)
+
+	batches, total := store.Batches(0, 10)
+	if total != 2 || batches[0].BatchID != 2 || batches[1].BatchID != 1 {
+		t.Fatalf("unexpected batches (newest first): %+v", batches)
+	}
+	if batches[1].Submitter != testSubmitter.Hex() {
+		t.Errorf("submitter = %s, want %s", batches[1].Submitter, testSubmitter.Hex())
+	}
+
+	disputes, total := store.Disputes(0, 10)
+	if total != 1 || disputes[0].OrderAIdx != 1 || disputes[0].OrderBIdx != 2 || disputes[0].SlashedOperators != 3 {
+		t.Fatalf("unexpected disputes: %+v", disputes)
+	}
+	if ix.IndexedBlock() != 9 {
+		t.Errorf("IndexedBlock() = %d, want 9", ix.IndexedBlock())
+	}
+}
+
+func TestIndexerRewindsOnReorg(t *testing.T) {
+	chain := newFakeChain(10)
+	chain.addBatch(3, common.HexToHash("0x01"), 1)
+	chain.addBatch(8, common.HexToHash("0x02"), 2)
+
+	ix, store := newTestIndexer(chain)
+	pollUntilCaughtUp(t, ix)
+
+	// Blocks 7 onwards are replaced; batch 2 lands in block 9 on the new fork
+	chain.reorg(7, 5, "b")
+	chain.addBatch(9, common.HexToHash("0x03"), 2)
+	pollUntilCaughtUp(t, ix)
+
+	batches, total := store.Batches(0, 10)
+	if total != 2 {
+		t.Fatalf("expected 2 batches after reorg, got %+v", batches)
+	}
+	if batches[0].Root != common.HexToHash("0x03").Hex() || batches[0].BlockNumber != 9 {
+		t.Errorf("expected batch from new fork in block 9, got %+v", batches[0])
+	}
+	if batches[0].BlockHash != chain.headers[9].Hash().Hex() {
+		t.Errorf("batch block hash not from the canonical fork")
+	}
+}
+
+func TestIndexerFinalizesBatchesBelowReorgDepth(t *testing.T) {
+	chain := newFakeChain(10)
+	chain.addBatch(1, common.HexToHash("0x01"), 1)
+	chain.addBatch(5, common.HexToHash("0x02"), 2)
+	chain.addBatch(8, common.HexToHash("0x03"), 3)
+
+	ix, _ := newTestIndexer(chain)
+	var final []uint64
+	ix.OnFinalized(func(b Batch) { final = append(final, b.BatchID) })
+
+	// Head 9 with a reorg depth of 8: only the batch in block 1 is final
+	pollUntilCaughtUp(t, ix)
+	if len(final) != 1 || final[0] != 1 {
+		t.Fatalf("finalized %v at head 9, want [1]", final)
+	}
+
+	// Batch 3 is reorged out before it becomes final
+	chain.reorg(8, 6, "b")
+	pollUntilCaughtUp(t, ix)
+	if len(final) != 2 || final[1] != 2 {
+		t.Fatalf("finalized %v at head 13, want [1 2]", final)
+	}
+}
+
+func TestStorePagination(t *testing.T) {
+	store := NewStore()
+	for i := uint64(1); i <= 5; i++ {
+		store.AddBatch(Batch{BatchID: i, BlockNumber: i})
+	}
+
+	page, total := store.Batches(1, 2)
+	if total != 5 || len(page) != 2 || page[0].BatchID != 4 || page[1].BatchID != 3 {
+		t.Fatalf("unexpected page: %+v (total %d)", page, total)
+	}
+
+	page, _ = store.Batches(4, 10)
+	if len(page) != 1 || page[0].BatchID != 1 {
+		t.Fatalf("unexpected last page: %+v", page)
+	}
+
+	page, _ = store.Batches(10, 10)
+	if len(page) != 0 {
+		t.Fatalf("expected empty page past the end, got %+v", page)
+	}
+}
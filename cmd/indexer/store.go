@@ -0,0 +1,118 @@
+package indexer
+
+import (
+	"sync"
+)
+
+// Batch is an indexed BatchSubmitted event
+type Batch struct {
+	BatchID     uint64 `json:"batchId"`
+	Root        string `json:"root"`
+	Submitter   string `json:"submitter"`
+	BlockNumber uint64 `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	TxHash      string `json:"txHash"`
+	LogIndex    uint   `json:"logIndex"`
+}
+
+// Dispute is an indexed Disputed event
+type Dispute struct {
+	Root             string `json:"root"`
+	Challenger       string `json:"challenger"`
+	OrderAIdx        uint64 `json:"orderAIdx"`
+	OrderBIdx        uint64 `json:"orderBIdx"`
+	SlashedOperators uint64 `json:"slashedOperators"`
+	BlockNumber      uint64 `json:"blockNumber"`
+	BlockHash        string `json:"blockHash"`
+	TxHash           string `json:"txHash"`
+	LogIndex         uint   `json:"logIndex"`
+}
+
+// Store keeps indexed batches and disputes in chain order
+type Store struct {
+	mu       sync.RWMutex
+	batches  []Batch
+	disputes []Dispute
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{}
+}
+
+// AddBatch appends a batch; batches must be added in chain order
+func (s *Store) AddBatch(b Batch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, b)
+}
+
+// AddDispute appends a dispute; disputes must be added in chain order
+func (s *Store) AddDispute(d Dispute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disputes = append(s.disputes, d)
+}
+
+// RewindTo removes every event above the given block and returns how many were removed
+func (s *Store) RewindTo(block uint64) (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nb := len(s.batches)
+	for nb > 0 && s.batches[nb-1].BlockNumber > block {
+		nb--
+	}
+	nd := len(s.disputes)
+	for nd > 0 && s.disputes[nd-1].BlockNumber > block {
+		nd--
+	}
+
+	removedBatches := len(s.batches) - nb
+	removedDisputes := len(s.disputes) - nd
+	s.batches = s.batches[:nb]
+	s.disputes = s.disputes[:nd]
+
+	return removedBatches, removedDisputes
+}
+
+// Batches returns a page of batches, newest first, and the total count
+func (s *Store) Batches(offset, limit int) ([]Batch, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start, end := pageBounds(len(s.batches), offset, limit)
+	page := make([]Batch, 0, end-start)
+	for i := start; i < end; i++ {
+		page = append(page, s.batches[len(s.batches)-1-i])
+	}
+	return page, len(s.batches)
+}
+
+// Disputes returns a page of disputes, newest first, and the total count
+func (s *Store) Disputes(offset, limit int) ([]Dispute, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start, end := pageBounds(len(s.disputes), offset, limit)
+	page := make([]Dispute, 0, end-start)
+	for i := start; i < end; i++ {
+		page = append(page, s.disputes[len(s.disputes)-1-i])
+	}
+	return page, len(s.disputes)
+}
+
+// pageBounds clamps an offset/limit window to a collection of size n
+func pageBounds(n, offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > n {
+		offset = n
+	}
+	end := offset + limit
+	if limit < 0 || end > n {
+		end = n
+	}
+	return offset, end
+}
@@ -1,27 +1,60 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/auth"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/batcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/exposure"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/feed"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/indexer"
 	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/nonce"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/ratelimit"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/sequencer"
 	"github.com/Layr-Labs/hourglass-avs-template/cmd/submitter"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/ticker"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv"
 )
 
 // Global variables
 var (
-	orderBook    []matcher.Order
-	mu           sync.Mutex
+	book         *sequencer.Sequencer
 	volumeData   []VolumeEntry
 	volumeMu     sync.Mutex
 	totalVolume  float64
+	makerFees    = make(map[string]*feeTotals)
+	feesMu       sync.Mutex
+	submissions  *pipeline.Pipeline
+	batches      *batcher.Batcher
+	chainIndex   *indexer.Indexer
+	chainStore   *indexer.Store
+	marketFeed   *feed.Feed
+	apiKeys      *auth.Store
+	authVerifier *auth.Verifier
+	rateLimits   *ratelimit.Limiters
+	exposures    *exposure.Manager
+	nonces       *nonce.ChainSource
+	tickers      *ticker.Cache
 )
 
 // Frontend-compatible data structures
@@ -62,36 +95,167 @@ type VolumeResponse struct {
 	Timestamp    int64         `json:"timestamp"`
 }
 
-// validateOrder validates an incoming order
+// PlacedFill is a fill produced by placing an order, with the batch it was assigned to
+type PlacedFill struct {
+	matcher.Fill
+	BatchID uint64 `json:"batchId,omitempty"`
+}
+
+// PlaceOrderResponse is the response to POST /orders
+type PlaceOrderResponse struct {
+	Success   bool                `json:"success"`
+	OrderHash string              `json:"orderHash"`
+	Seq       uint64              `json:"seq"`
+	Status    matcher.OrderStatus `json:"status"`
+	Filled    string              `json:"filled"`
+	Remaining string              `json:"remaining"`
+	Fills     []PlacedFill        `json:"fills"`
+	BatchIDs  []uint64            `json:"batchIds"`
+}
+
+// feeTotals accumulates the fees charged to one address, in 1e-8 units
+type feeTotals struct {
+	collateral int64
+	tokens     int64
+	makerFills int
+	takerFills int
+}
+
+// MakerFees reports the fees charged to an address. Sellers pay fees in
+// collateral and buyers in outcome tokens.
+type MakerFees struct {
+	Maker          string `json:"maker"`
+	CollateralFees string `json:"collateralFees"`
+	TokenFees      string `json:"tokenFees"`
+	MakerFills     int    `json:"makerFills"`
+	TakerFills     int    `json:"takerFills"`
+}
+
+type FeesResponse struct {
+	Makers    []MakerFees `json:"makers"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+// QuoteResponse is the response to GET /quote
+type QuoteResponse struct {
+	Market        string       `json:"market"`
+	Side          matcher.Side `json:"side"`
+	Amount        string       `json:"amount,omitempty"`
+	RequestedSize string       `json:"requestedSize,omitempty"`
+	matcher.Quote
+	Timestamp int64 `json:"timestamp"`
+}
+
+// maxTickerMarkets is the most markets one batch ticker request may name
+const maxTickerMarkets = 100
+
+// MidpointResponse is the response to GET /midpoint and an entry of GET /midpoints
+type MidpointResponse struct {
+	Market    string `json:"market"`
+	Midpoint  string `json:"midpoint"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// SpreadResponse is the response to GET /spread and an entry of GET /spreads
+type SpreadResponse struct {
+	Market    string `json:"market"`
+	Spread    string `json:"spread"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// PriceResponse is the response to GET /price and an entry of GET /prices
+type PriceResponse struct {
+	Market    string       `json:"market"`
+	Side      matcher.Side `json:"side"`
+	Price     string       `json:"price"`
+	Timestamp int64        `json:"timestamp"`
+}
+
+// LastTradePriceResponse is the response to GET /last-trade-price and an
+// entry of GET /last-trades-prices
+type LastTradePriceResponse struct {
+	Market    string       `json:"market"`
+	Price     string       `json:"price"`
+	Size      string       `json:"size"`
+	Side      matcher.Side `json:"side"`
+	TradedAt  int64        `json:"tradedAt,omitempty"`
+	Timestamp int64        `json:"timestamp"`
+}
+
+type BatchesResponse struct {
+	Batches      []indexer.Batch `json:"batches"`
+	Total        int             `json:"total"`
+	Offset       int             `json:"offset"`
+	Limit        int             `json:"limit"`
+	IndexedBlock uint64          `json:"indexedBlock"`
+}
+
+type DisputesResponse struct {
+	Disputes     []indexer.Dispute `json:"disputes"`
+	Total        int               `json:"total"`
+	Offset       int               `json:"offset"`
+	Limit        int               `json:"limit"`
+	IndexedBlock uint64            `json:"indexedBlock"`
+}
+
+// validateOrder validates an incoming order, naming the field at fault
 func validateOrder(order matcher.Order) error {
+	missing := func(field string) error {
+		return newAPIError(http.StatusBadRequest, CodeMissingField, field, "%s cannot be empty", field)
+	}
+
 	if order.Maker == "" {
-		return fmt.Errorf("maker address cannot be empty")
+		return missing("maker")
+	}
+	if !common.IsHexAddress(order.Maker) {
+		return newAPIError(http.StatusBadRequest, CodeInvalidField, "maker", "maker must be a hex address")
+	}
+	switch order.Type {
+	case "", matcher.OrderLimit, matcher.OrderMarket:
+	default:
+		return newAPIError(http.StatusBadRequest, CodeInvalidField, "orderType", "orderType must be limit or market")
 	}
-	if order.Price <= 0 {
-		return fmt.Errorf("price must be positive")
+	market := order.IsMarket()
+	if order.Side == "" {
+		return missing("side")
+	}
+	if order.Side != matcher.SideBuy && order.Side != matcher.SideSell {
+		return newAPIError(http.StatusBadRequest, CodeInvalidField, "side", "side must be buy or sell")
+	}
+
+	// A market order's price is an optional worst price
+	if !(order.Price > 0 || market && order.Price == 0) || math.IsInf(order.Price, 0) {
+		return newAPIError(http.StatusBadRequest, CodeInvalidPrice, "price", "price must be positive")
 	}
 	if order.Timestamp <= 0 {
-		return fmt.Errorf("timestamp must be positive")
+		return newAPIError(http.StatusBadRequest, CodeInvalidField, "timestamp", "timestamp must be positive")
 	}
 	if order.Signature == "" {
-		return fmt.Errorf("signature cannot be empty")
+		return missing("signature")
+	}
+	if _, err := hexutil.Decode(order.Signature); err != nil {
+		return newAPIError(http.StatusBadRequest, CodeInvalidSignature, "signature", "signature must be 0x-prefixed hex")
 	}
 	if order.TakerAsset == "" {
-		return fmt.Errorf("takerAsset cannot be empty")
+		return missing("takerAsset")
 	}
 	if order.MakeAmount == "" {
-		return fmt.Errorf("makeAmount cannot be empty")
+		return missing("makeAmount")
 	}
-	if order.TakeAmount == "" {
-		return fmt.Errorf("takeAmount cannot be empty")
+	if order.TakeAmount == "" && !market {
+		return missing("takeAmount")
 	}
 
 	// Validate amounts are positive numbers
-	if makeAmt, err := strconv.ParseFloat(order.MakeAmount, 64); err != nil || makeAmt <= 0 {
-		return fmt.Errorf("makeAmount must be a positive number")
+	if makeAmt, err := strconv.ParseFloat(order.MakeAmount, 64); err != nil || !(makeAmt > 0) || math.IsInf(makeAmt, 0) {
+		return newAPIError(http.StatusBadRequest, CodeInvalidAmount, "makeAmount", "makeAmount must be a positive number")
+	}
+	// The book decides what a market order receives, so its takeAmount is ignored
+	if takeAmt, err := strconv.ParseFloat(order.TakeAmount, 64); !market && (err != nil || !(takeAmt > 0) || math.IsInf(takeAmt, 0)) {
+		return newAPIError(http.StatusBadRequest, CodeInvalidAmount, "takeAmount", "takeAmount must be a positive number")
 	}
-	if takeAmt, err := strconv.ParseFloat(order.TakeAmount, 64); err != nil || takeAmt <= 0 {
-		return fmt.Errorf("takeAmount must be a positive number")
+	if order.Expiration < 0 {
+		return newAPIError(http.StatusBadRequest, CodeInvalidField, "expiration", "expiration must be a unix timestamp, or 0 to never expire")
 	}
 
 	return nil
@@ -116,38 +280,90 @@ func convertToFrontendOrder(order matcher.Order, side string) (FrontendOrder, er
 	}, nil
 }
 
-// classifyOrderSide determines if an order is a bid or ask based on price relative to market
-func classifyOrderSide(order matcher.Order, allOrders []matcher.Order) string {
-	if len(allOrders) == 0 {
-		return "bid" // Default to bid if no comparison available
+// classifyOrderSide reports whether an order is a bid or an ask from its declared side
+func classifyOrderSide(order matcher.Order) string {
+	if order.Side == matcher.SideBuy {
+		return "bid"
 	}
+	return "ask"
+}
 
-	// Calculate median price to determine bid/ask classification
-	prices := make([]float64, len(allOrders))
-	for i, o := range allOrders {
-		prices[i] = o.Price
+// recordTrades tracks volume for fills matched against an incoming order
+func recordTrades(taker matcher.Order, fills []matcher.Fill, at time.Time) {
+	tickers.RecordTrades(taker, fills, at)
+	for _, fill := range fills {
+		quantity, err := strconv.ParseFloat(fill.Quantity, 64)
+		if err != nil {
+			continue
+		}
+		// Trades execute at the resting maker's price, not the taker's limit
+		price, err := strconv.ParseFloat(fill.Price, 64)
+		if err != nil {
+			log.Printf("Skipping volume for fill %s/%s with invalid price %q", fill.MakerHash, fill.TakerHash, fill.Price)
+			continue
+		}
+		trackVolume(price, quantity, at)
+		trackFees(fill)
 	}
+}
 
-	// Simple classification: above median = bid, below median = ask
-	var sum float64
-	for _, p := range prices {
-		sum += p
+// trackFees adds the maker and taker fees of a fill to the totals of their addresses
+func trackFees(fill matcher.Fill) {
+	makerFee, err := parseFeeUnits(fill.MakerFee)
+	if err != nil {
+		log.Printf("Skipping fees for fill %s/%s with invalid maker fee %q", fill.MakerHash, fill.TakerHash, fill.MakerFee)
+		return
+	}
+	takerFee, err := parseFeeUnits(fill.TakerFee)
+	if err != nil {
+		log.Printf("Skipping fees for fill %s/%s with invalid taker fee %q", fill.MakerHash, fill.TakerHash, fill.TakerFee)
+		return
 	}
-	avgPrice := sum / float64(len(prices))
 
-	if order.Price >= avgPrice {
-		return "bid"
+	feesMu.Lock()
+	defer feesMu.Unlock()
+
+	totals := func(addr string) *feeTotals {
+		addr = strings.ToLower(addr)
+		if makerFees[addr] == nil {
+			makerFees[addr] = &feeTotals{}
+		}
+		return makerFees[addr]
+	}
+
+	// The buyer pays in outcome tokens and the seller in collateral
+	maker, taker := totals(fill.Maker), totals(fill.Taker)
+	maker.makerFills++
+	taker.takerFills++
+	if fill.Side == matcher.SideBuy {
+		maker.collateral += makerFee
+		taker.tokens += takerFee
+	} else {
+		maker.tokens += makerFee
+		taker.collateral += takerFee
 	}
-	return "ask"
+}
+
+// parseFeeUnits parses an 8-decimal fee into 1e-8 units
+func parseFeeUnits(s string) (int64, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) != 8 {
+		return 0, fmt.Errorf("fee %q does not have 8 decimals", s)
+	}
+	return strconv.ParseInt(whole+frac, 10, 64)
+}
+
+// formatFeeUnits formats 1e-8 units with 8 decimals
+func formatFeeUnits(units int64) string {
+	return fmt.Sprintf("%d.%08d", units/100_000_000, units%100_000_000)
 }
 
 // trackVolume adds volume data for a completed trade
-func trackVolume(price, quantity float64) {
+func trackVolume(price, quantity float64, at time.Time) {
 	volumeMu.Lock()
 	defer volumeMu.Unlock()
 
-	now := time.Now()
-	timeStr := now.Format("15:04")
+	timeStr := at.Format("15:04")
 	value := price * quantity
 
 	// Add to total volume
@@ -188,91 +404,386 @@ func generateMockVolumeData() []VolumeEntry {
 	return data
 }
 
-// enableCORS adds CORS headers to allow frontend access
-func enableCORS(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+// enableCORS adds CORS headers that let pages from CORS_ALLOWED_ORIGINS call
+// the API; other origins get none and the browser blocks the response
+func enableCORS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" || authVerifier == nil || !authVerifier.AllowsOrigin(origin) {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{"Content-Type",
+		auth.HeaderAddress, auth.HeaderSignature, auth.HeaderTimestamp, auth.HeaderNonce, auth.HeaderAPIKey, auth.HeaderPassphrase}, ", "))
+	w.Header().Set("Access-Control-Expose-Headers", "X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
 }
 
-// handleOrders handles POST /orders endpoint
+// handleOrders handles POST /orders and DELETE /orders endpoints
 func handleOrders(w http.ResponseWriter, r *http.Request) {
-	enableCORS(w)
+	enableCORS(w, r)
 	
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	if r.Method == http.MethodDelete {
+		handleCancelOrder(w, r)
+		return
+	}
+
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	limit := &rateCheck{action: ratelimit.ActionPlace}
+	if err := limit.allow(w, ratelimit.ScopeIP, clientIP(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+	creds, body, err := authenticate(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := limit.allow(w, ratelimit.ScopeAPIKey, creds.APIKey); err != nil {
+		writeError(w, err)
 		return
 	}
 
 	var o matcher.Order
-	if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
-		http.Error(w, `{"error":"Invalid order"}`, http.StatusBadRequest)
+	if err := json.Unmarshal(body, &o); err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidJSON, "", "invalid order: %v", err))
 		return
 	}
 
 	// Validate order fields
 	if err := validateOrder(o); err != nil {
-		http.Error(w, `{"error":"Invalid order"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Add order to orderbook
-	mu.Lock()
-	orderBook = append(orderBook, o)
-	orderBookCopy := make([]matcher.Order, len(orderBook))
-	copy(orderBookCopy, orderBook)
-	mu.Unlock()
-
-	log.Printf("Order added to orderbook. Total orders: %d", len(orderBookCopy))
-
-	// Trigger multi-fill matching with batch size limit of 100
-	mu.Lock()
-	root, fillsBytes, updatedBook, err := matcher.MatchAndBatch(orderBook, 100)
-	orderBook = updatedBook
-
-	// Track volume for completed fills
-	if err == nil && len(fillsBytes) > 0 {
-		// Parse fills to track volume
-		var fills []matcher.Fill
-		if jsonErr := json.Unmarshal(fillsBytes, &fills); jsonErr == nil {
-			for _, fill := range fills {
-				if quantity, parseErr := strconv.ParseFloat(fill.Quantity, 64); parseErr == nil {
-					// Use average price for volume tracking
-					avgPrice := o.Price
-					trackVolume(avgPrice, quantity)
-				}
+		writeError(w, err)
+		return
+	}
+
+	// An API key only places orders for the wallet that derived it
+	if !strings.EqualFold(o.Maker, creds.Address) {
+		writeError(w, newAPIError(http.StatusForbidden, CodeForbidden, "maker", "maker %s does not own the API key", o.Maker))
+		return
+	}
+	if err := limit.allow(w, ratelimit.ScopeMaker, o.Maker); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Reject new orders while the submission queue is saturated
+	if submissions.Full() {
+		log.Printf("Submission queue full (%d batches), rejecting order", submissions.Len())
+		writeError(w, newAPIError(http.StatusServiceUnavailable, CodeQueueFull, "", "submission queue full, retry later"))
+		return
+	}
+
+	// Catch up with a nonce the maker raised since the last poll, so orders
+	// signed under an invalidated nonce are not accepted in the meantime
+	if nonces.Enabled() {
+		if n, err := nonces.Nonce(r.Context(), common.HexToAddress(o.Maker)); err != nil {
+			log.Printf("Warning: Could not read nonce of %s: %v", o.Maker, err)
+		} else if _, err := book.SetNonce(o.Maker, n); err != nil {
+			log.Printf("Error raising nonce of %s: %v", o.Maker, err)
+		}
+	}
+
+	// Reserve what the order commits so the maker cannot promise the same
+	// collateral or outcome tokens twice. A resubmitted order is refused here,
+	// so the release below only ever frees the reservation made just now.
+	reserved := matcher.OrderHash(o)
+	if err := exposures.Reserve(r.Context(), o); err != nil {
+		if errors.Is(err, exposure.ErrUnavailable) {
+			log.Printf("Error checking balances: %v", err)
+		}
+		writeError(w, err)
+		return
+	}
+
+	// The sequencer logs the order to the WAL, matches it and hands any fills
+	// to the batcher, which cuts batches by count, size or age
+	placed, err := book.PlaceOrder(o)
+	if err != nil {
+		exposures.Release(reserved)
+		if apiErr := toAPIError(err); apiErr.Status == http.StatusInternalServerError {
+			log.Printf("Error placing order: %v", err)
+		}
+		writeError(w, err)
+		return
+	}
+
+	response := PlaceOrderResponse{
+		Success:   true,
+		OrderHash: placed.Order.Hash,
+		Seq:       placed.Order.Seq,
+		Status:    placed.Status,
+		Filled:    placed.Filled,
+		Remaining: placed.Remaining,
+		Fills:     make([]PlacedFill, len(placed.Fills)),
+		BatchIDs:  []uint64{},
+	}
+	for i, fill := range placed.Fills {
+		response.Fills[i].Fill = fill
+		if i < len(placed.BatchIDs) {
+			response.Fills[i].BatchID = placed.BatchIDs[i]
+			if n := len(response.BatchIDs); n == 0 || response.BatchIDs[n-1] != placed.BatchIDs[i] {
+				response.BatchIDs = append(response.BatchIDs, placed.BatchIDs[i])
 			}
 		}
 	}
-	mu.Unlock()
 
-	if err == nil && len(fillsBytes) > 0 {
-		aggSig, err := matcher.AggregateBLS(root)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleCancelOrder handles DELETE /orders endpoint
+func handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	limit := &rateCheck{action: ratelimit.ActionCancel}
+	if err := limit.allow(w, ratelimit.ScopeIP, clientIP(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+	creds, body, err := authenticate(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := limit.allow(w, ratelimit.ScopeAPIKey, creds.APIKey); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := limit.allow(w, ratelimit.ScopeMaker, creds.Address); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req struct {
+		OrderHash string `json:"orderHash"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidJSON, "", "invalid cancel request: %v", err))
+		return
+	}
+	if req.OrderHash == "" {
+		writeError(w, newAPIError(http.StatusBadRequest, CodeMissingField, "orderHash", "orderHash cannot be empty"))
+		return
+	}
+
+	// Orders of other wallets are reported as not found
+	if o, ok := book.Order(req.OrderHash); !ok || !strings.EqualFold(o.Maker, creds.Address) {
+		writeError(w, sequencer.ErrOrderNotFound)
+		return
+	}
+
+	if err := book.CancelOrder(req.OrderHash); err != nil {
+		if !errors.Is(err, sequencer.ErrOrderNotFound) {
+			log.Printf("Error cancelling order: %v", err)
+		}
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"success":true}`))
+}
+
+// maxRequestBody limits the body of an authenticated request
+const maxRequestBody = 1 << 20
+
+// authenticate reads the body of a trading request and checks its L2 headers,
+// returning the credentials it was signed with and the body
+func authenticate(w http.ResponseWriter, r *http.Request) (auth.Credentials, []byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		return auth.Credentials{}, nil, newAPIError(http.StatusBadRequest, CodeInvalidJSON, "", "failed to read request body: %v", err)
+	}
+	creds, err := authVerifier.VerifyRequest(apiKeys, r.Header, r.Method, r.URL.Path, body)
+	if err != nil {
+		return auth.Credentials{}, nil, err
+	}
+	return creds, body, nil
+}
+
+// rateCheck counts one request against the rate limits of its action and
+// reports the tightest limit in the response headers
+type rateCheck struct {
+	action ratelimit.Action
+	result ratelimit.Result
+}
+
+// allow counts the request against the bucket of key in scope and returns an
+// error if the bucket is empty
+func (c *rateCheck) allow(w http.ResponseWriter, scope ratelimit.Scope, key string) error {
+	res := rateLimits.Allow(c.action, scope, key)
+	c.result = c.result.Tighter(res)
+	if c.result.Limit > 0 {
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(c.result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(c.result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(c.result.Reset.Seconds())), 10))
+	}
+	if res.Allowed {
+		return nil
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(res.RetryAfter.Seconds())), 10))
+	return newAPIError(http.StatusTooManyRequests, CodeRateLimited, "", "%s rate limit per %s exceeded, retry in %v", c.action, scope, res.RetryAfter)
+}
+
+// restingMakers returns the makers with orders on the book
+func restingMakers() []common.Address {
+	seen := make(map[common.Address]bool)
+	var makers []common.Address
+	for _, o := range book.Orders() {
+		maker := common.HexToAddress(o.Maker)
+		if !seen[maker] {
+			seen[maker] = true
+			makers = append(makers, maker)
+		}
+	}
+	return makers
+}
+
+// clientIP returns the address of the client that sent r
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// walletAuth checks the L1 headers of a request and returns the wallet that
+// signed them and the nonce it signed
+func walletAuth(r *http.Request) (common.Address, uint64, error) {
+	a, err := auth.WalletAuthFromHeader(r.Header)
+	if err != nil {
+		return common.Address{}, 0, err
+	}
+	address, err := authVerifier.Verify(a)
+	if err != nil {
+		return common.Address{}, 0, err
+	}
+	return address, a.Nonce, nil
+}
+
+// APIKeyResponse carries the credentials of an API key
+type APIKeyResponse struct {
+	APIKey     string `json:"apiKey"`
+	Secret     string `json:"secret"`
+	Passphrase string `json:"passphrase"`
+}
+
+// handleAPIKey handles POST /auth/api-key, which creates credentials for a
+// wallet signature, and DELETE /auth/api-key, which revokes the key signing
+// the request
+func handleAPIKey(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPost:
+		address, nonce, err := walletAuth(r)
 		if err != nil {
-			log.Printf("BLS aggregate error: %v", err)
-		} else {
-			if txHash, err2 := submitter.SubmitBatch(root, fillsBytes, aggSig); err2 == nil {
-				log.Printf("Batch submitted: %s", txHash)
-			} else {
-				log.Printf("Error submitting batch: %v", err2)
+			writeError(w, err)
+			return
+		}
+		creds, err := apiKeys.Create(address, nonce)
+		if err != nil {
+			if toAPIError(err).Status == http.StatusInternalServerError {
+				log.Printf("Error creating API key: %v", err)
+			}
+			writeError(w, err)
+			return
+		}
+		log.Printf("API key %s created for %s (nonce %d)", creds.APIKey, creds.Address, creds.Nonce)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIKeyResponse{APIKey: creds.APIKey, Secret: creds.Secret, Passphrase: creds.Passphrase})
+
+	case http.MethodDelete:
+		creds, _, err := authenticate(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := apiKeys.Revoke(creds.APIKey); err != nil {
+			if toAPIError(err).Status == http.StatusInternalServerError {
+				log.Printf("Error revoking API key: %v", err)
 			}
+			writeError(w, err)
+			return
 		}
-	} else if err != nil {
-		log.Printf("Error in MatchAndBatch: %v", err)
+		log.Printf("API key %s revoked by %s", creds.APIKey, creds.Address)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true}`))
+
+	default:
+		writeError(w, errMethodNotAllowed)
+	}
+}
+
+// handleDeriveAPIKey handles GET /auth/derive-api-key, which returns the
+// credentials a wallet created with the signed nonce
+func handleDeriveAPIKey(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	address, nonce, err := walletAuth(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	creds, err := apiKeys.Derive(address, nonce)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"success":true}`))
+	json.NewEncoder(w).Encode(APIKeyResponse{APIKey: creds.APIKey, Secret: creds.Secret, Passphrase: creds.Passphrase})
+}
+
+// handleAPIKeys handles GET /auth/api-keys, which lists the API keys of the
+// wallet owning the key signing the request
+func handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	creds, _, err := authenticate(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiKeys": apiKeys.Keys(common.HexToAddress(creds.Address)),
+	})
 }
 
 // handleOrderBook handles GET /book endpoint
 func handleOrderBook(w http.ResponseWriter, r *http.Request) {
-	enableCORS(w)
+	enableCORS(w, r)
 	
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -280,20 +791,17 @@ func handleOrderBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, errMethodNotAllowed)
 		return
 	}
 
-	mu.Lock()
-	orderBookCopy := make([]matcher.Order, len(orderBook))
-	copy(orderBookCopy, orderBook)
-	mu.Unlock()
+	orderBookCopy := book.Orders()
 
 	// Convert to frontend format and classify as bids/asks
 	var bids, asks []FrontendOrder
 
 	for _, order := range orderBookCopy {
-		side := classifyOrderSide(order, orderBookCopy)
+		side := classifyOrderSide(order)
 		frontendOrder, err := convertToFrontendOrder(order, side)
 		if err != nil {
 			log.Printf("Error converting order: %v", err)
@@ -336,7 +844,7 @@ func handleOrderBook(w http.ResponseWriter, r *http.Request) {
 
 // handleDepth handles GET /depth endpoint
 func handleDepth(w http.ResponseWriter, r *http.Request) {
-	enableCORS(w)
+	enableCORS(w, r)
 	
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -344,14 +852,11 @@ func handleDepth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, errMethodNotAllowed)
 		return
 	}
 
-	mu.Lock()
-	orderBookCopy := make([]matcher.Order, len(orderBook))
-	copy(orderBookCopy, orderBook)
-	mu.Unlock()
+	orderBookCopy := book.Orders()
 
 	// Group orders by price and compute cumulative depth
 	priceMap := make(map[float64]struct {
@@ -360,7 +865,7 @@ func handleDepth(w http.ResponseWriter, r *http.Request) {
 	})
 
 	for _, order := range orderBookCopy {
-		side := classifyOrderSide(order, orderBookCopy)
+		side := classifyOrderSide(order)
 		amount, err := strconv.ParseFloat(order.MakeAmount, 64)
 		if err != nil {
 			continue
@@ -418,7 +923,7 @@ func handleDepth(w http.ResponseWriter, r *http.Request) {
 
 // handleVolume handles GET /volume endpoint
 func handleVolume(w http.ResponseWriter, r *http.Request) {
-	enableCORS(w)
+	enableCORS(w, r)
 	
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -426,7 +931,7 @@ func handleVolume(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, errMethodNotAllowed)
 		return
 	}
 
@@ -454,9 +959,326 @@ func handleVolume(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleFees handles GET /fees endpoint, optionally filtered by ?maker=
+func handleFees(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	filter := strings.ToLower(r.URL.Query().Get("maker"))
+
+	feesMu.Lock()
+	makers := make([]MakerFees, 0, len(makerFees))
+	for addr, t := range makerFees {
+		if filter != "" && addr != filter {
+			continue
+		}
+		makers = append(makers, MakerFees{
+			Maker:          addr,
+			CollateralFees: formatFeeUnits(t.collateral),
+			TokenFees:      formatFeeUnits(t.tokens),
+			MakerFills:     t.makerFills,
+			TakerFills:     t.takerFills,
+		})
+	}
+	feesMu.Unlock()
+
+	if filter != "" && len(makers) == 0 {
+		makers = append(makers, MakerFees{Maker: filter, CollateralFees: formatFeeUnits(0), TokenFees: formatFeeUnits(0)})
+	}
+	sort.Slice(makers, func(i, j int) bool { return makers[i].Maker < makers[j].Maker })
+
+	response := FeesResponse{
+		Makers:    makers,
+		Timestamp: time.Now().Unix(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleQuote handles GET /quote?market=&side=&amount=&price=, estimating
+// what a market order would fill against the current book
+func handleQuote(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	o := matcher.Order{
+		TakerAsset: q.Get("market"),
+		MakeAmount: q.Get("amount"),
+		Side:       matcher.Side(q.Get("side")),
+		Type:       matcher.OrderMarket,
+	}
+	if o.TakerAsset == "" {
+		writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "market", "market is required"))
+		return
+	}
+	if o.Side != matcher.SideBuy && o.Side != matcher.SideSell {
+		writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "side", "side must be buy or sell"))
+		return
+	}
+	size := q.Get("size")
+	if (o.MakeAmount == "") == (size == "") {
+		writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "size", "exactly one of size or amount is required"))
+		return
+	}
+	if o.MakeAmount != "" {
+		if amount, err := strconv.ParseFloat(o.MakeAmount, 64); err != nil || !(amount > 0) || math.IsInf(amount, 0) {
+			writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "amount", "amount must be a positive number"))
+			return
+		}
+	}
+	if v := q.Get("price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(price > 0 && price < 1) {
+			writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "price", "price must be between 0 and 1 exclusive"))
+			return
+		}
+		o.Price = price
+	}
+
+	response := QuoteResponse{
+		Market:        o.TakerAsset,
+		Side:          o.Side,
+		Amount:        o.MakeAmount,
+		RequestedSize: size,
+		Timestamp:     time.Now().Unix(),
+	}
+	if size == "" {
+		response.Quote = matcher.QuoteMarket(book.Orders(), o, book.Markets())
+	} else {
+		quote, err := matcher.QuoteSize(book.Orders(), o.TakerAsset, o.Side, size, o.Price)
+		if err != nil {
+			writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "size", "size must be a positive number"))
+			return
+		}
+		response.Quote = quote
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// tickerHandler returns a handler of GET requests for a ticker value of the
+// market named by the market parameter, or with batch set, of each of the
+// comma-separated markets parameter, answered from the ticker cache. entry
+// may read further query parameters and fail with an API error.
+func tickerHandler(batch bool, entry func(q url.Values, market string, now int64) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enableCORS(w, r)
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			writeError(w, errMethodNotAllowed)
+			return
+		}
+
+		now := time.Now().Unix()
+		q := r.URL.Query()
+		if !batch {
+			market := q.Get("market")
+			if market == "" {
+				writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "market", "market is required"))
+				return
+			}
+			response, err := entry(q, market, now)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		var markets []string
+		for _, m := range strings.Split(q.Get("markets"), ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				markets = append(markets, m)
+			}
+		}
+		if len(markets) == 0 || len(markets) > maxTickerMarkets {
+			writeError(w, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "markets",
+				"markets must list between 1 and %d comma-separated markets", maxTickerMarkets))
+			return
+		}
+		entries := make([]interface{}, len(markets))
+		for i, m := range markets {
+			var err error
+			if entries[i], err = entry(q, m, now); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+func midpointOf(_ url.Values, market string, now int64) (interface{}, error) {
+	return MidpointResponse{Market: market, Midpoint: tickers.Top(market).Midpoint, Timestamp: now}, nil
+}
+
+func spreadOf(_ url.Values, market string, now int64) (interface{}, error) {
+	return SpreadResponse{Market: market, Spread: tickers.Top(market).Spread, Timestamp: now}, nil
+}
+
+// priceOf answers with the best bid for side buy and the best ask for side sell
+func priceOf(q url.Values, market string, now int64) (interface{}, error) {
+	side := matcher.Side(strings.ToLower(q.Get("side")))
+	top := tickers.Top(market)
+	switch side {
+	case matcher.SideBuy:
+		return PriceResponse{Market: market, Side: side, Price: top.BestBid, Timestamp: now}, nil
+	case matcher.SideSell:
+		return PriceResponse{Market: market, Side: side, Price: top.BestAsk, Timestamp: now}, nil
+	}
+	return nil, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "side", "side must be buy or sell")
+}
+
+func lastTradePriceOf(_ url.Values, market string, now int64) (interface{}, error) {
+	response := LastTradePriceResponse{Market: market, Timestamp: now}
+	if trade, ok := tickers.LastTrade(market); ok {
+		response.Price = trade.Price
+		response.Size = trade.Size
+		response.Side = trade.Side
+		response.TradedAt = trade.Timestamp
+	}
+	return response, nil
+}
+
+// handleMarkets handles GET /markets endpoint
+func handleMarkets(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book.Markets())
+}
+
+// parsePagination reads the offset and limit query parameters
+func parsePagination(r *http.Request) (int, int, error) {
+	offset, limit := 0, 50
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "offset", "offset must be a non-negative integer")
+		}
+		offset = n
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			return 0, 0, newAPIError(http.StatusBadRequest, CodeInvalidQuery, "limit", "limit must be between 1 and 500")
+		}
+		limit = n
+	}
+
+	return offset, limit, nil
+}
+
+// handleBatches handles GET /batches endpoint
+func handleBatches(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	offset, limit, err := parsePagination(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	page, total := chainStore.Batches(offset, limit)
+	response := BatchesResponse{
+		Batches:      page,
+		Total:        total,
+		Offset:       offset,
+		Limit:        limit,
+		IndexedBlock: chainIndex.IndexedBlock(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleDisputes handles GET /disputes endpoint
+func handleDisputes(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	offset, limit, err := parsePagination(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	page, total := chainStore.Disputes(offset, limit)
+	response := DisputesResponse{
+		Disputes:     page,
+		Total:        total,
+		Offset:       offset,
+		Limit:        limit,
+		IndexedBlock: chainIndex.IndexedBlock(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleHealth handles GET /health endpoint
 func handleHealth(w http.ResponseWriter, r *http.Request) {
-	enableCORS(w)
+	enableCORS(w, r)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
@@ -485,18 +1307,199 @@ func main() {
 		}
 	}
 
-	// Initialize the global orderbook and volume tracking
-	orderBook = make([]matcher.Order, 0)
+	// Connect the batch submitter to the chain
+	submitter.Init()
+
+	// Initialize volume tracking
 	volumeData = make([]VolumeEntry, 0)
 	totalVolume = 0
-	log.Println("Orderbook and volume tracking initialized")
+	log.Println("Volume tracking initialized")
+
+	// Wallets sign in with EIP-712 signatures and trade with the API keys they
+	// derive. The market data feed streams book changes, trades and batch
+	// status over /ws, and each wallet's own orders and fills once it signs in.
+	authCfg, err := auth.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid auth configuration: %v", err)
+	}
+	feedCfg, err := feed.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid feed configuration: %v", err)
+	}
+	authVerifier = auth.NewVerifier(authCfg)
+	apiKeys, err = auth.OpenStore(authCfg.StorePath)
+	if err != nil {
+		log.Fatalf("Failed to open credential store: %v", err)
+	}
+	marketFeed = feed.New(feedCfg, authVerifier)
+
+	// Limit how fast each API key, maker and IP can place and cancel orders
+	rateCfg, err := ratelimit.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid rate limit configuration: %v", err)
+	}
+	rateLimits = ratelimit.New(rateCfg)
+	log.Printf("Rate limits initialized - Place per key/maker/IP: %v/%v/%v per s, Cancel per key/maker/IP: %v/%v/%v per s",
+		rateCfg.Place.APIKey.Rate, rateCfg.Place.Maker.Rate, rateCfg.Place.IP.Rate,
+		rateCfg.Cancel.APIKey.Rate, rateCfg.Cancel.Maker.Rate, rateCfg.Cancel.IP.Rate)
+
+	// Start the asynchronous signing and submission workers
+	pipelineCfg, err := pipeline.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid pipeline configuration: %v", err)
+	}
+	submissions = pipeline.New(pipelineCfg, matcher.AggregateBLS, submitter.SubmitBatch)
+	submissions.OnStage(marketFeed.BatchStage)
+	submissions.RetryIf(submitter.IsRetryable)
+	submissions.OnPark(func(b pipeline.Batch, aggSig []byte, attempts int, err error) {
+		submitter.RecordFailedBatch(b.Root, b.Fills, aggSig, attempts, err)
+	})
+	submissions.Start()
+
+	// Start the batcher with IDs continuing from the on-chain batch counter
+	batcherCfg, err := batcher.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid batcher configuration: %v", err)
+	}
+	// Recovery requeues the fills of every batch above the on-chain counter,
+	// so a guessed counter would cut settled fills into batches again
+	submitted, err := submitter.TotalBatchesSubmitted()
+	if err != nil {
+		log.Fatalf("Failed to read totalBatchesSubmitted: %v", err)
+	}
+
+	// Orders are checked against the maker's on-chain balances and allowances
+	rpcURL := os.Getenv("RPC_URL")
+	if rpcURL == "" {
+		rpcURL = "http://localhost:8545"
+	}
+	chainClient, err := ethclient.Dial(rpcURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to %s: %v", rpcURL, err)
+	}
+	exposureCfg, err := exposure.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid exposure configuration: %v", err)
+	}
+	exposures = exposure.New(exposureCfg, chainClient)
+	if exposures.Enabled() {
+		log.Printf("Balance checks enabled - Collateral: %s, Conditional tokens: %s, Exchange: %s, Cache TTL: %v",
+			exposureCfg.Collateral.Hex(), exposureCfg.ConditionalTokens.Hex(), exposureCfg.Exchange.Hex(), exposureCfg.CacheTTL)
+	} else {
+		log.Println("Balance checks disabled (COLLATERAL_ADDRESS not set)")
+	}
+
+	// Rebuild the order book from the WAL; fills of batches that never
+	// settled are cut again under fresh IDs
+	sequencerCfg, err := sequencer.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid WAL configuration: %v", err)
+	}
+	tickers = ticker.New()
+	book, err = sequencer.Open(sequencerCfg, recordTrades)
+	if err != nil {
+		log.Fatalf("Failed to open sequencer: %v", err)
+	}
+	unsettled, err := book.Recover(submitted)
+	if err != nil {
+		log.Fatalf("Failed to recover from WAL: %v", err)
+	}
+
+	// Publish the recovered book, then every change to it and every fill.
+	// Recovered orders keep their reservations until they fill or are cancelled.
+	marketFeed.Update(book.Orders())
+	tickers.Update(book.Orders())
+	exposures.Track(book.Orders())
+	go exposures.Run(context.Background())
+	book.OnBook(func(_ uint64, orders []matcher.Order, markets []string) {
+		marketFeed.UpdateMarkets(orders, markets)
+		tickers.Update(orders)
+	})
+
+	// Raising a maker's nonce on the exchange cancels their resting orders
+	// signed under a lower nonce
+	nonceCfg, err := nonce.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid nonce configuration: %v", err)
+	}
+	nonces = nonce.NewChainSource(nonceCfg, chainClient, restingMakers)
+	if !nonces.Enabled() {
+		log.Println("Nonce tracking disabled (EXCHANGE_ADDRESS not set)")
+	}
+	go nonces.Run(context.Background())
+	go nonce.Listen(context.Background(), nonces, func(inc nonce.Increment) {
+		if _, err := book.SetNonce(inc.Maker.Hex(), inc.Nonce); err != nil {
+			log.Printf("Error raising nonce of %s: %v", inc.Maker.Hex(), err)
+		}
+	})
+	book.SetObserver(matcher.ObserverFunc(func(e matcher.Event) {
+		matcher.LogObserver.Observe(e)
+		exposures.Observe(e)
+		marketFeed.Observe(e)
+	}))
+	log.Printf("Market feed initialized - Send buffer: %d, Write timeout: %v, Ping interval: %v, Auth chain ID: %d, Auth max age: %v",
+		feedCfg.SendBuffer, feedCfg.WriteTimeout, feedCfg.PingInterval, authCfg.ChainID, authCfg.MaxAge)
+
+	batches = batcher.New(batcherCfg, submitted+1, submissions)
+	batches.OnCut(func(b pipeline.Batch, fills []matcher.Fill) error {
+		if err := book.RecordBatch(b, fills); err != nil {
+			return err
+		}
+		marketFeed.BatchCut(b, fills)
+		return nil
+	})
+	book.SetSink(batches)
+	log.Printf("Batcher initialized - Next batch ID: %d, MaxFills: %d, MaxDelay: %v, MaxBytes: %d",
+		batches.NextID(), batcherCfg.MaxFills, batcherCfg.MaxDelay, batcherCfg.MaxBytes)
+
+	if len(unsettled) > 0 {
+		log.Printf("Requeueing %d unsettled fills from the WAL", len(unsettled))
+		if _, err := batches.Add(unsettled); err != nil {
+			log.Fatalf("Failed to requeue unsettled fills: %v", err)
+		}
+	}
+
+	// Periodically snapshot the book so restarts replay only the WAL tail
+	go book.RunSnapshots(context.Background(), submitter.TotalBatchesSubmitted)
+
+	// Sweep orders past their expiration off the book
+	go book.RunSweeper(context.Background())
+	log.Printf("Snapshots enabled - Dir: %s, Interval: %v, Retain: %d",
+		sequencerCfg.SnapshotDir, sequencerCfg.SnapshotInterval, sequencerCfg.SnapshotRetain)
+
+	// Follow BatchSettlement and DisputeGame events
+	indexerCfg, err := indexer.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid indexer configuration: %v", err)
+	}
+	chainStore = indexer.NewStore()
+	chainIndex = indexer.New(indexerCfg, chainClient, chainStore)
+	chainIndex.OnFinalized(marketFeed.BatchFinalized)
+	go chainIndex.Run(context.Background())
 
 	// Setup HTTP routes
 	http.HandleFunc("/orders", handleOrders)
+	http.HandleFunc("/auth/api-key", handleAPIKey)
+	http.HandleFunc("/auth/derive-api-key", handleDeriveAPIKey)
+	http.HandleFunc("/auth/api-keys", handleAPIKeys)
 	http.HandleFunc("/book", handleOrderBook)
 	http.HandleFunc("/depth", handleDepth) 
 	http.HandleFunc("/volume", handleVolume)
+	http.HandleFunc("/fees", handleFees)
+	http.HandleFunc("/markets", handleMarkets)
+	http.HandleFunc("/quote", handleQuote)
+	http.HandleFunc("/midpoint", tickerHandler(false, midpointOf))
+	http.HandleFunc("/midpoints", tickerHandler(true, midpointOf))
+	http.HandleFunc("/spread", tickerHandler(false, spreadOf))
+	http.HandleFunc("/spreads", tickerHandler(true, spreadOf))
+	http.HandleFunc("/price", tickerHandler(false, priceOf))
+	http.HandleFunc("/prices", tickerHandler(true, priceOf))
+	http.HandleFunc("/last-trade-price", tickerHandler(false, lastTradePriceOf))
+	http.HandleFunc("/last-trades-prices", tickerHandler(true, lastTradePriceOf))
+	http.HandleFunc("/batches", handleBatches)
+	http.HandleFunc("/disputes", handleDisputes)
 	http.HandleFunc("/health", handleHealth)
+	http.Handle("/ws", marketFeed)
 
 	// Start the HTTP server on port 8081
 	log.Println("Starting HTTP server on port 8081...")
@@ -1,39 +1,283 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
-	performerV1 "github.com/Layr-Labs/protocol-apis/gen/protos/eigenlayer/hourglass/v1/performer"
-	"go.uber.org/zap"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/auth"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/exposure"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/nonce"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/ratelimit"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/sequencer"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/ticker"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/wal"
+	"github.com/ethereum/go-ethereum/common"
 )
 
-func Test_TaskRequestPayload(t *testing.T) {
-	// ------------------------------------------------------------------------
-	// Write your test cases here
-	// ------------------------------------------------------------------------
+var (
+	alice = common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	bob   = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+)
+
+// setupServer points the handlers at a fresh book, key store and pipeline,
+// with balance and nonce checks disabled, and returns an API key of alice
+// and one of bob
+func setupServer(t *testing.T) (auth.Credentials, auth.Credentials) {
+	t.Helper()
+
+	var err error
+	authVerifier = auth.NewVerifier(auth.Config{ChainID: 31337, MaxAge: time.Minute})
+	if apiKeys, err = auth.OpenStore(""); err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	rateLimits = ratelimit.New(ratelimit.Config{})
+	exposures = exposure.New(exposure.Config{}, nil)
+	nonces = nonce.NewChainSource(nonce.Config{}, nil, nil)
+	submissions = pipeline.New(pipeline.Config{QueueSize: 1, Workers: 1}, nil, nil)
+	tickers = ticker.New()
 
-	logger, err := zap.NewDevelopment()
+	book, err = sequencer.Open(sequencer.Config{
+		WAL:              wal.Options{Dir: t.TempDir(), Sync: wal.SyncNever},
+		MaxFillsPerMatch: 100,
+		Markets:          matcher.Markets{Default: matcher.MarketParams{TickSize: 0.01}},
+	}, recordTrades)
 	if err != nil {
-		t.Errorf("Failed to create logger: %v", err)
+		t.Fatalf("Open failed: %v", err)
 	}
+	t.Cleanup(func() { book.Close() })
+	if _, err := book.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	book.OnBook(func(_ uint64, orders []matcher.Order, _ []string) {
+		tickers.Update(orders)
+	})
 
-	taskWorker := NewTaskWorker(logger)
+	aliceKey, err := apiKeys.Create(alice, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	bobKey, err := apiKeys.Create(bob, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return aliceKey, bobKey
+}
 
-	taskRequest := &performerV1.TaskRequest{
-		TaskId:   []byte("test-task-id"),
-		Payload:  []byte("test-data"),
-		Metadata: []byte("test-metadata"),
+func testOrder(maker common.Address, side matcher.Side, price float64, amount string) matcher.Order {
+	return matcher.Order{
+		Maker:      maker.Hex(),
+		TakerAsset: "0x2a",
+		MakeAmount: amount,
+		TakeAmount: amount,
+		Price:      price,
+		Side:       side,
+		Timestamp:  time.Now().Unix(),
+		Signature:  "0x5e",
 	}
+}
 
-	err = taskWorker.ValidateTask(taskRequest)
+// placeOrder posts v to /orders, signed with creds unless they are empty
+func placeOrder(t *testing.T, creds auth.Credentials, v interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(v)
 	if err != nil {
-		t.Errorf("ValidateTask failed: %v", err)
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(body))
+	if creds.APIKey != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		if err := auth.SetRequestHeaders(r.Header, creds, timestamp, http.MethodPost, "/orders", body); err != nil {
+			t.Fatalf("SetRequestHeaders failed: %v", err)
+		}
 	}
+	w := httptest.NewRecorder()
+	handleOrders(w, r)
+	return w
+}
 
-	resp, err := taskWorker.HandleTask(taskRequest)
-	if err != nil {
-		t.Errorf("HandleTask failed: %v", err)
+// get sends a GET request to handler
+func get(handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// decode unmarshals the body of a response with the given status into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body.String())
 	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+}
+
+// expectError checks the status, code and field of an error response
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, code, field string) {
+	t.Helper()
+	var got APIError
+	decode(t, w, status, &got)
+	if got.Code != code || got.Field != field {
+		t.Fatalf("error %+v, want code %s and field %q", got, code, field)
+	}
+}
+
+func TestPlaceOrderErrors(t *testing.T) {
+	aliceKey, bobKey := setupServer(t)
+
+	noSide := testOrder(alice, "", 0.5, "1")
+	offTick := testOrder(alice, matcher.SideBuy, 0.505, "1")
+
+	tests := []struct {
+		name   string
+		creds  auth.Credentials
+		body   interface{}
+		status int
+		code   string
+		field  string
+	}{
+		{"unsigned", auth.Credentials{}, testOrder(alice, matcher.SideBuy, 0.5, "1"), http.StatusUnauthorized, CodeUnauthorized, ""},
+		{"invalid JSON", aliceKey, "not an order", http.StatusBadRequest, CodeInvalidJSON, ""},
+		{"missing side", aliceKey, noSide, http.StatusBadRequest, CodeMissingField, "side"},
+		{"off tick", aliceKey, offTick, http.StatusBadRequest, CodeInvalidTick, "price"},
+		{"maker of another key", bobKey, testOrder(alice, matcher.SideBuy, 0.5, "1"), http.StatusForbidden, CodeForbidden, "maker"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, placeOrder(t, tt.creds, tt.body), tt.status, tt.code, tt.field)
+		})
+	}
+
+	// A saturated submission queue rejects orders until it drains
+	if err := submissions.TryPublish(pipeline.Batch{ID: 1}); err != nil {
+		t.Fatalf("TryPublish failed: %v", err)
+	}
+	expectError(t, placeOrder(t, aliceKey, testOrder(alice, matcher.SideBuy, 0.5, "1")), http.StatusServiceUnavailable, CodeQueueFull, "")
+}
+
+func TestPlaceOrderRateLimited(t *testing.T) {
+	aliceKey, _ := setupServer(t)
+	rateLimits = ratelimit.New(ratelimit.Config{Place: ratelimit.Limits{APIKey: ratelimit.Limit{Rate: 0.001, Burst: 1}}})
+
+	var placed PlaceOrderResponse
+	decode(t, placeOrder(t, aliceKey, testOrder(alice, matcher.SideBuy, 0.5, "1")), http.StatusOK, &placed)
 
-	t.Logf("Response: %v", resp)
+	w := placeOrder(t, aliceKey, testOrder(alice, matcher.SideBuy, 0.4, "1"))
+	expectError(t, w, http.StatusTooManyRequests, CodeRateLimited, "")
+	if w.Header().Get("Retry-After") == "" {
+		t.Errorf("rate limited response has no Retry-After header")
+	}
+}
+
+func TestPlaceOrderResponse(t *testing.T) {
+	aliceKey, bobKey := setupServer(t)
+
+	// An ask rests with its takeAmount, the size asks are filled by
+	ask := testOrder(alice, matcher.SideSell, 0.5, "4")
+	ask.TakeAmount = "6"
+	var rested PlaceOrderResponse
+	decode(t, placeOrder(t, aliceKey, ask), http.StatusOK, &rested)
+	if !rested.Success || rested.Status != matcher.StatusLive || rested.Filled != "0.00000000" || rested.Remaining != "6.00000000" {
+		t.Fatalf("unexpected response for a resting ask: %+v", rested)
+	}
+
+	// A bid for 10 takes the 6 on offer and rests with the other 4
+	var crossed PlaceOrderResponse
+	decode(t, placeOrder(t, bobKey, testOrder(bob, matcher.SideBuy, 0.5, "10")), http.StatusOK, &crossed)
+	if crossed.Status != matcher.StatusPartiallyFilled || crossed.Filled != "6.00000000" || crossed.Remaining != "4.00000000" {
+		t.Fatalf("unexpected response for a crossing bid: %+v", crossed)
+	}
+	if len(crossed.Fills) != 1 || crossed.Fills[0].MakerHash != rested.OrderHash || crossed.Fills[0].Quantity != "6.00000000" {
+		t.Fatalf("unexpected fills %+v", crossed.Fills)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	aliceKey, _ := setupServer(t)
+	var placed PlaceOrderResponse
+	decode(t, placeOrder(t, aliceKey, testOrder(alice, matcher.SideSell, 0.5, "4")), http.StatusOK, &placed)
+
+	for _, tt := range []struct {
+		target string
+		field  string
+	}{
+		{"/quote?side=buy&size=1", "market"},
+		{"/quote?market=0x2a&size=1", "side"},
+		{"/quote?market=0x2a&side=buy&size=1&amount=1", "size"},
+		{"/quote?market=0x2a&side=buy&amount=-1", "amount"},
+		{"/quote?market=0x2a&side=buy&size=1&price=1.5", "price"},
+	} {
+		expectError(t, get(handleQuote, tt.target), http.StatusBadRequest, CodeInvalidQuery, tt.field)
+	}
+
+	var quote QuoteResponse
+	decode(t, get(handleQuote, "/quote?market=0x2a&side=buy&size=3"), http.StatusOK, &quote)
+	if quote.Size != "3.00000000" || quote.AveragePrice != "0.50000000" || !quote.Fillable {
+		t.Fatalf("unexpected quote %+v", quote)
+	}
+}
+
+func TestTickerErrors(t *testing.T) {
+	setupServer(t)
+
+	expectError(t, get(tickerHandler(false, midpointOf), "/midpoint"), http.StatusBadRequest, CodeInvalidQuery, "market")
+	expectError(t, get(tickerHandler(true, midpointOf), "/midpoints?markets=,"), http.StatusBadRequest, CodeInvalidQuery, "markets")
+
+	w := httptest.NewRecorder()
+	tickerHandler(false, midpointOf)(w, httptest.NewRequest(http.MethodPost, "/midpoint?market=0x2a", nil))
+	expectError(t, w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+}
+
+func TestTickerEndpoints(t *testing.T) {
+	aliceKey, bobKey := setupServer(t)
+	for _, o := range []matcher.Order{
+		testOrder(alice, matcher.SideBuy, 0.4, "5"),
+		testOrder(alice, matcher.SideSell, 0.6, "5"),
+	} {
+		var placed PlaceOrderResponse
+		decode(t, placeOrder(t, aliceKey, o), http.StatusOK, &placed)
+	}
+
+	// The price of a buy is the best bid and that of a sell the best ask
+	var price PriceResponse
+	decode(t, get(tickerHandler(false, priceOf), "/price?market=0x2a&side=buy"), http.StatusOK, &price)
+	if price.Side != matcher.SideBuy || price.Price != "0.40000000" {
+		t.Fatalf("buy price %+v, want the best bid", price)
+	}
+	decode(t, get(tickerHandler(false, priceOf), "/price?market=0x2a&side=SELL"), http.StatusOK, &price)
+	if price.Side != matcher.SideSell || price.Price != "0.60000000" {
+		t.Fatalf("sell price %+v, want the best ask", price)
+	}
+	expectError(t, get(tickerHandler(false, priceOf), "/price?market=0x2a"), http.StatusBadRequest, CodeInvalidQuery, "side")
+	expectError(t, get(tickerHandler(true, priceOf), "/prices?markets=0x2a,0x2b"), http.StatusBadRequest, CodeInvalidQuery, "side")
+
+	var prices []PriceResponse
+	decode(t, get(tickerHandler(true, priceOf), "/prices?markets=0x2a,0x2b&side=sell"), http.StatusOK, &prices)
+	if len(prices) != 2 || prices[0].Price != "0.60000000" || prices[1].Market != "0x2b" || prices[1].Price != "" {
+		t.Fatalf("unexpected prices %+v", prices)
+	}
+
+	var midpoints []MidpointResponse
+	decode(t, get(tickerHandler(true, midpointOf), "/midpoints?markets=0x2a"), http.StatusOK, &midpoints)
+	if len(midpoints) != 1 || midpoints[0].Midpoint != "0.50000000" {
+		t.Fatalf("unexpected midpoints %+v", midpoints)
+	}
+
+	// The last trade carries the sequencer time of the taker order
+	start := time.Now().UnixMilli()
+	var placed PlaceOrderResponse
+	decode(t, placeOrder(t, bobKey, testOrder(bob, matcher.SideBuy, 0.6, "2")), http.StatusOK, &placed)
+	var last LastTradePriceResponse
+	decode(t, get(tickerHandler(false, lastTradePriceOf), "/last-trade-price?market=0x2a"), http.StatusOK, &last)
+	if last.Price != "0.60000000" || last.Size != "2.00000000" || last.Side != matcher.SideBuy || last.TradedAt < start || last.TradedAt > time.Now().UnixMilli() {
+		t.Fatalf("unexpected last trade %+v", last)
+	}
 }
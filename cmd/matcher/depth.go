@@ -0,0 +1,96 @@
+package matcher
+
+import "math/big"
+
+// Level is the total size resting at one price on one side of a market
+type Level struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+// SplitBook separates a book into bids and asks by their declared side, the
+// same way matching does, each in price-time priority
+func SplitBook(book []Order) (bids []Order, asks []Order) {
+	return splitBidsAsks(book)
+}
+
+// Depth aggregates the orders of the market for asset into price levels.
+// Sizes are the amounts matching fills: makeAmount for bids and takeAmount
+// for asks. Bids are sorted by descending price and asks by ascending price.
+func Depth(book []Order, asset string) (bids []Level, asks []Level) {
+	b, a := SplitBook(book)
+	bids = levels(b, asset, func(o Order) string { return o.MakeAmount })
+	asks = levels(a, asset, func(o Order) string { return o.TakeAmount })
+	return bids, asks
+}
+
+// levels sums the sizes of the orders of asset at each price. Orders come
+// sorted best price first, and so do the levels.
+func levels(orders []Order, asset string, size func(Order) string) []Level {
+	var prices []string
+	sizes := make(map[string]*big.Int)
+	for _, o := range orders {
+		if o.TakerAsset != asset {
+			continue
+		}
+		s, err := parseFixed(size(o))
+		if err != nil || s.Sign() <= 0 {
+			continue
+		}
+		price := formatAmount(o.Price)
+		if sizes[price] == nil {
+			sizes[price] = new(big.Int)
+			prices = append(prices, price)
+		}
+		sizes[price].Add(sizes[price], s)
+	}
+
+	result := make([]Level, len(prices))
+	for i, p := range prices {
+		result[i] = Level{Price: p, Size: formatFixed(sizes[p])}
+	}
+	return result
+}
+
+// Top is the top of one market's book. Prices are fixed-point with 8
+// decimals and empty when the side they depend on has no orders.
+type Top struct {
+	BestBid  string `json:"bestBid"`
+	BestAsk  string `json:"bestAsk"`
+	Midpoint string `json:"midpoint"` // mean of the best bid and ask
+	Spread   string `json:"spread"`   // best ask less best bid
+}
+
+// TopOfBook returns the top of book of every market with orders in book,
+// keyed by asset, from the same price levels as Depth
+func TopOfBook(book []Order) map[string]Top {
+	assets := make(map[string]bool)
+	for _, o := range book {
+		assets[o.TakerAsset] = true
+	}
+
+	// Split once; levels come best price first
+	b, a := SplitBook(book)
+	tops := make(map[string]Top, len(assets))
+	for asset := range assets {
+		bids := levels(b, asset, func(o Order) string { return o.MakeAmount })
+		asks := levels(a, asset, func(o Order) string { return o.TakeAmount })
+		var top Top
+		if len(bids) > 0 {
+			top.BestBid = bids[0].Price
+		}
+		if len(asks) > 0 {
+			top.BestAsk = asks[0].Price
+		}
+		if mid, ok := midpoint(bids, asks); ok {
+			top.Midpoint = formatFixed(mid)
+			bid, _ := parseFixed(top.BestBid)
+			ask, _ := parseFixed(top.BestAsk)
+			top.Spread = formatSigned(ask.Sub(ask, bid))
+		}
+		if top != (Top{}) {
+			tops[asset] = top
+		}
+	}
+	return tops
+}
@@ -0,0 +1,58 @@
+package matcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDepth(t *testing.T) {
+	other := testOrder("0xcccccccccc", SideBuy, 0.55, "7", 5, 5)
+	other.TakerAsset = "0xother"
+	book := []Order{
+		testOrder("0xaaaaaaaaaa", SideBuy, 0.4, "10", 1, 1),
+		testOrder("0xbbbbbbbbbb", SideBuy, 0.4, "2.5", 2, 2),
+		testOrder("0xaaaaaaaaaa", SideBuy, 0.3, "4", 3, 3),
+		testOrder("0xbbbbbbbbbb", SideSell, 0.6, "1", 4, 4),
+		testOrder("0xaaaaaaaaaa", SideSell, 0.5, "3", 6, 6),
+		other,
+	}
+
+	// Levels are sorted best price first on each side
+	bids, asks := Depth(book, "0xasset")
+	wantBids := []Level{{"0.40000000", "12.50000000"}, {"0.30000000", "4.00000000"}}
+	wantAsks := []Level{{"0.50000000", "3.00000000"}, {"0.60000000", "1.00000000"}}
+	if !reflect.DeepEqual(bids, wantBids) {
+		t.Errorf("bids = %+v, want %+v", bids, wantBids)
+	}
+	if !reflect.DeepEqual(asks, wantAsks) {
+		t.Errorf("asks = %+v, want %+v", asks, wantAsks)
+	}
+
+	bids, asks = Depth(book, "0xother")
+	if !reflect.DeepEqual(bids, []Level{{"0.55000000", "7.00000000"}}) || len(asks) != 0 {
+		t.Errorf("other market: bids %+v, asks %+v", bids, asks)
+	}
+
+	if bids, asks := Depth(book, "0xnone"); len(bids) != 0 || len(asks) != 0 {
+		t.Errorf("unknown market: bids %+v, asks %+v", bids, asks)
+	}
+}
+
+func TestTopOfBook(t *testing.T) {
+	other := testOrder("0xcccccccccc", SideBuy, 0.9, "7", 5, 5)
+	other.TakerAsset = "0xother"
+	book := append(marketBook(), other)
+
+	tops := TopOfBook(book)
+	want := Top{BestBid: "0.45000000", BestAsk: "0.50000000", Midpoint: "0.47500000", Spread: "0.05000000"}
+	if tops["0xasset"] != want {
+		t.Errorf("top of 0xasset = %+v, want %+v", tops["0xasset"], want)
+	}
+	// The other market only has a bid, so it has no midpoint or spread
+	if top := tops["0xother"]; top != (Top{BestBid: "0.90000000"}) {
+		t.Errorf("top of 0xother = %+v", top)
+	}
+	if len(TopOfBook(nil)) != 0 {
+		t.Errorf("empty book has a top")
+	}
+}
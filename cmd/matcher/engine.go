@@ -0,0 +1,208 @@
+package matcher
+
+import (
+	"log"
+	"math/big"
+)
+
+// EventType identifies what happened while matching
+type EventType string
+
+const (
+	EventOrderAccepted EventType = "order_accepted" // an order was added to the book
+	EventFill          EventType = "fill"           // a bid and an ask crossed
+	EventOrderFilled   EventType = "order_filled"   // an order was fully filled and left the book
+	EventInvalidAmount EventType = "invalid_amount" // an order was skipped because its amount does not parse
+
+	// EventOrderCancelled is not produced by matching; the sequencer reports
+	// orders it removes from the book, such as cancels, with it
+	EventOrderCancelled EventType = "order_cancelled"
+
+	// EventOrderExpired is reported for orders removed by Expire
+	EventOrderExpired EventType = "order_expired"
+)
+
+// Event is an outcome of matching. Order is the order the event concerns;
+// fill events carry the fill and the bid and ask as they were before it.
+type Event struct {
+	Type  EventType
+	Order Order
+	Fill  Fill
+	Bid   Order
+	Ask   Order
+	Err   string
+}
+
+// Observer receives matching events, typically for logging or metrics
+type Observer interface {
+	Observe(Event)
+}
+
+// ObserverFunc adapts a function to the Observer interface
+type ObserverFunc func(Event)
+
+// Observe calls f(e)
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// NopObserver discards every event
+var NopObserver Observer = ObserverFunc(func(Event) {})
+
+// LogObserver logs every event
+var LogObserver Observer = ObserverFunc(func(e Event) {
+	switch e.Type {
+	case EventOrderAccepted:
+		log.Printf("Order %d accepted @ %.8f", e.Order.Seq, e.Order.Price)
+	case EventFill:
+		log.Printf("Matching bid %d @ %.8f with ask %d @ %.8f, fill quantity: %s",
+			e.Bid.Seq, e.Bid.Price, e.Ask.Seq, e.Ask.Price, e.Fill.Quantity)
+	case EventOrderFilled:
+		log.Printf("Order %d fully filled", e.Order.Seq)
+	case EventInvalidAmount:
+		log.Printf("Skipping order %d with invalid amount: %s", e.Order.Seq, e.Err)
+	case EventOrderCancelled:
+		log.Printf("Order %d cancelled @ %.8f", e.Order.Seq, e.Order.Price)
+	case EventOrderExpired:
+		log.Printf("Order %d expired @ %.8f", e.Order.Seq, e.Order.Price)
+	}
+})
+
+// Remaining returns what is left of the bid's makeAmount and the ask's
+// takeAmount after the fill of a fill event, computed as matching does
+func (e Event) Remaining() (bid string, ask string) {
+	b, errBid := parseAmount(e.Bid.MakeAmount)
+	a, errAsk := parseAmount(e.Ask.TakeAmount)
+	if e.Type != EventFill || errBid != nil || errAsk != nil {
+		return formatAmount(0), formatAmount(0)
+	}
+	q := min(b, a)
+	return formatAmount(b - q), formatAmount(a - q)
+}
+
+// Execute adds order to book and matches it, charging fees at the rates of
+// markets, and returns the new book and the events produced. It is a pure
+// function: it does not log, read the clock or modify book, so the same book,
+// order and markets always give the same result.
+func Execute(book []Order, order Order, maxFills int, markets Markets) ([]Order, []Event) {
+	next := make([]Order, len(book), len(book)+1)
+	copy(next, book)
+	next = append(next, order)
+
+	events := []Event{{Type: EventOrderAccepted, Order: order}}
+	_, remaining, matchEvents := match(next, maxFills, markets)
+	return remaining, append(events, matchEvents...)
+}
+
+// Expire removes the orders of book that are expired at now, in unix
+// milliseconds, and returns the rest of the book and an event per removed
+// order. Like Execute it is pure and does not modify book; matching a book
+// that went through Expire at the same time never fills an expired order.
+func Expire(book []Order, now int64) ([]Order, []Event) {
+	var events []Event
+	kept := make([]Order, 0, len(book))
+	for _, o := range book {
+		if o.Expired(now) {
+			events = append(events, Event{Type: EventOrderExpired, Order: o})
+			continue
+		}
+		kept = append(kept, o)
+	}
+	if len(events) == 0 {
+		return book, nil
+	}
+	return kept, events
+}
+
+// OrderStatus is the state of an order after it was matched
+type OrderStatus string
+
+const (
+	StatusLive            OrderStatus = "live"             // resting on the book without fills
+	StatusPartiallyFilled OrderStatus = "partially_filled" // resting on the book after some fills
+	StatusFilled          OrderStatus = "filled"           // fully filled and off the book
+	StatusDropped         OrderStatus = "dropped"          // off the book without fills, for an invalid amount
+	StatusCancelled       OrderStatus = "cancelled"        // a market order whose unfilled remainder was cancelled
+)
+
+// OrderResult summarizes what matching did to one order
+type OrderResult struct {
+	Status    OrderStatus
+	Filled    string // total quantity filled, 8 decimals
+	Remaining string // quantity left resting, 8 decimals
+}
+
+// Summarize reports what happened to order, identified by its Hash, given the
+// events and resulting book of an Execute call. Remaining is the amount the
+// matcher fills on the order's side: makeAmount for bids and takeAmount for
+// asks, whether or not the order filled. A market order never rests: its
+// Remaining is the part of its makeAmount that was cancelled.
+func Summarize(order Order, events []Event, book []Order) OrderResult {
+	filled := new(big.Int)
+	fills := 0
+	cancelled := ""
+	var result OrderResult
+	for _, e := range events {
+		if e.Type == EventOrderCancelled && e.Order.Hash == order.Hash {
+			cancelled = remainingAmount(e.Order)
+			continue
+		}
+		if e.Type != EventFill || (e.Bid.Hash != order.Hash && e.Ask.Hash != order.Hash) {
+			continue
+		}
+		if q, err := parseFixed(e.Fill.Quantity); err == nil {
+			filled.Add(filled, q)
+		}
+		fills++
+	}
+	result.Filled = formatFixed(filled)
+	result.Remaining = formatFixed(new(big.Int))
+
+	for _, o := range book {
+		if o.Hash != order.Hash {
+			continue
+		}
+		if r, err := parseFixed(remainingAmount(o)); err == nil {
+			result.Remaining = formatFixed(r)
+		}
+		result.Status = StatusLive
+		if fills > 0 {
+			result.Status = StatusPartiallyFilled
+		}
+		return result
+	}
+
+	if cancelled != "" {
+		if r, err := parseFixed(cancelled); err == nil {
+			result.Remaining = formatFixed(r)
+		}
+		result.Status = StatusCancelled
+		return result
+	}
+
+	result.Status = StatusFilled
+	if fills == 0 {
+		result.Status = StatusDropped
+	}
+	return result
+}
+
+// remainingAmount returns the amount of o the matcher fills: takeAmount for a
+// limit ask and makeAmount for bids and market orders
+func remainingAmount(o Order) string {
+	if o.Side == SideSell && !o.IsMarket() {
+		return o.TakeAmount
+	}
+	return o.MakeAmount
+}
+
+// Fills returns the fills carried by events, in order
+func Fills(events []Event) []Fill {
+	var fills []Fill
+	for _, e := range events {
+		if e.Type == EventFill {
+			fills = append(fills, e.Fill)
+		}
+	}
+	return fills
+}
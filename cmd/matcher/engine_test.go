@@ -0,0 +1,196 @@
+package matcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testOrder(maker string, side Side, price float64, amount string, ts int64, seq uint64) Order {
+	o := Order{
+		Seq:        seq,
+		Maker:      maker,
+		TakerAsset: "0xasset",
+		MakeAmount: amount,
+		TakeAmount: amount,
+		Price:      price,
+		Timestamp:  ts,
+		Side:       side,
+		Signature:  "0xsig",
+	}
+	o.Hash = OrderHash(o)
+	return o
+}
+
+func TestExecuteBreaksTiesBySequence(t *testing.T) {
+	// Three bids share price and timestamp; only seq tells them apart
+	a := testOrder("0xaaaaaaaaaa", SideBuy, 0.6, "5", 10, 1)
+	b := testOrder("0xbbbbbbbbbb", SideBuy, 0.6, "5", 10, 2)
+	c := testOrder("0xcccccccccc", SideBuy, 0.6, "5", 10, 3)
+	taker := testOrder("0xdddddddddd", SideSell, 0.4, "5", 11, 4)
+
+	_, want := Execute([]Order{a, b, c}, taker, 100, Markets{})
+	for _, book := range [][]Order{{c, b, a}, {b, a, c}, {c, a, b}} {
+		_, got := Execute(book, taker, 100, Markets{})
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("events depend on book order:\n got %+v\nwant %+v", got, want)
+		}
+	}
+
+	fills := Fills(want)
+	if len(fills) != 1 || fills[0].MakerHash != a.Hash || fills[0].TakerHash != taker.Hash {
+		t.Fatalf("expected the ask to fill bid a, got %+v", fills)
+	}
+}
+
+func TestExecuteFillsAtMakerPrice(t *testing.T) {
+	ask := testOrder("0xaaaaaaaaaa", SideSell, 0.4, "5", 1, 1)
+	bid := testOrder("0xbbbbbbbbbb", SideBuy, 0.6, "5", 2, 2)
+
+	// A bid lifting a resting ask buys at the ask's price
+	_, events := Execute([]Order{ask}, bid, 100, Markets{})
+	fills := Fills(events)
+	if len(fills) != 1 {
+		t.Fatalf("expected 1 fill, got %+v", fills)
+	}
+	want := Fill{MakerHash: OrderHash(ask), TakerHash: OrderHash(bid), Maker: ask.Maker, Taker: bid.Maker, Quantity: "5.00000000", Price: "0.40000000", Side: SideBuy, MakerFee: "0.00000000", TakerFee: "0.00000000"}
+	if fills[0] != want {
+		t.Fatalf("got fill %+v, want %+v", fills[0], want)
+	}
+
+	// An ask hitting a resting bid sells at the bid's price
+	bid.Seq, ask.Seq = 1, 2
+	bid.Hash, ask.Hash = OrderHash(bid), OrderHash(ask)
+	_, events = Execute([]Order{bid}, ask, 100, Markets{})
+	fills = Fills(events)
+	want = Fill{MakerHash: OrderHash(bid), TakerHash: OrderHash(ask), Maker: bid.Maker, Taker: ask.Maker, Quantity: "5.00000000", Price: "0.60000000", Side: SideSell, MakerFee: "0.00000000", TakerFee: "0.00000000"}
+	if len(fills) != 1 || fills[0] != want {
+		t.Fatalf("got fills %+v, want %+v", fills, want)
+	}
+}
+
+func TestExecuteIsPure(t *testing.T) {
+	book := []Order{testOrder("0xaaaaaaaaaa", SideBuy, 0.6, "10", 1, 1)}
+	snapshot := append([]Order(nil), book...)
+
+	next, events := Execute(book, testOrder("0xbbbbbbbbbb", SideSell, 0.5, "4", 2, 2), 100, Markets{})
+	if !reflect.DeepEqual(book, snapshot) {
+		t.Fatalf("Execute modified its input book")
+	}
+	if len(next) != 1 || next[0].MakeAmount != "6.00000000" {
+		t.Fatalf("unexpected book after partial fill: %+v", next)
+	}
+
+	var types []EventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	want := []EventType{EventOrderAccepted, EventFill, EventOrderFilled}
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("events %v, want %v", types, want)
+	}
+}
+
+func TestExpireRemovesExpiredOrders(t *testing.T) {
+	live := testOrder("0xaaaaaaaaaa", SideBuy, 0.5, "10", 1, 1)
+	lasting := testOrder("0xbbbbbbbbbb", SideSell, 0.6, "10", 2, 2)
+	lasting.Expiration = 1_700_000_000
+	lasting.Hash = OrderHash(lasting)
+	book := []Order{live, lasting}
+
+	// An order is valid through the second of its expiration
+	if next, events := Expire(book, 1_700_000_000_999); len(next) != 2 || events != nil {
+		t.Fatalf("expired before its time: book %+v, events %+v", next, events)
+	}
+
+	next, events := Expire(book, 1_700_000_001_000)
+	if len(next) != 1 || next[0].Hash != live.Hash {
+		t.Fatalf("unexpected book after expiry: %+v", next)
+	}
+	if len(events) != 1 || events[0].Type != EventOrderExpired || events[0].Order.Hash != lasting.Hash {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if len(book) != 2 || book[1].Hash != lasting.Hash {
+		t.Fatalf("Expire modified its input book")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ask := testOrder("0xaaaaaaaaaa", SideSell, 0.4, "3", 1, 1)
+	bid := testOrder("0xbbbbbbbbbb", SideBuy, 0.6, "5", 2, 2)
+
+	book, events := Execute([]Order{ask}, bid, 100, Markets{})
+	got := Summarize(bid, events, book)
+	if got.Status != StatusPartiallyFilled || got.Filled != "3.00000000" || got.Remaining != "2.00000000" {
+		t.Fatalf("unexpected summary for the taker: %+v", got)
+	}
+	if got := Summarize(ask, events, book); got.Status != StatusFilled || got.Remaining != "0.00000000" {
+		t.Fatalf("unexpected summary for the maker: %+v", got)
+	}
+
+	rest := testOrder("0xcccccccccc", SideBuy, 0.1, "4", 3, 3)
+	book, events = Execute(nil, rest, 100, Markets{})
+	if got := Summarize(rest, events, book); got.Status != StatusLive || got.Filled != "0.00000000" || got.Remaining != "4.00000000" {
+		t.Fatalf("unexpected summary for a resting order: %+v", got)
+	}
+
+	// An ask is filled by its takeAmount, with or without fills
+	unfilled := testOrder("0xdddddddddd", SideSell, 0.9, "4", 4, 4)
+	unfilled.TakeAmount = "6"
+	unfilled.Hash = OrderHash(unfilled)
+	book, events = Execute(nil, unfilled, 100, Markets{})
+	if got := Summarize(unfilled, events, book); got.Status != StatusLive || got.Remaining != "6.00000000" {
+		t.Fatalf("unexpected summary for a resting ask: %+v", got)
+	}
+	cancelled := []Event{{Type: EventOrderCancelled, Order: unfilled}}
+	if got := Summarize(unfilled, cancelled, nil); got.Status != StatusCancelled || got.Remaining != "6.00000000" {
+		t.Fatalf("unexpected summary for a cancelled ask: %+v", got)
+	}
+}
+
+func TestExecuteRespectsSidesAndMarkets(t *testing.T) {
+	// A buy below a resting sell does not fill, however the book splits
+	ask := testOrder("0xaaaaaaaaaa", SideSell, 0.6, "5", 1, 1)
+	bid := testOrder("0xbbbbbbbbbb", SideBuy, 0.4, "5", 2, 2)
+	book, events := Execute([]Order{ask}, bid, 100, Markets{})
+	if fills := Fills(events); len(fills) != 0 || len(book) != 2 {
+		t.Fatalf("buy @ 0.4 traded with sell @ 0.6: fills %+v, book %+v", fills, book)
+	}
+
+	// Crossing orders in different markets do not trade
+	other := testOrder("0xcccccccccc", SideBuy, 0.7, "5", 3, 3)
+	other.TakerAsset = "0xother"
+	other.Hash = OrderHash(other)
+	book, events = Execute(book, other, 100, Markets{})
+	if fills := Fills(events); len(fills) != 0 || len(book) != 3 {
+		t.Fatalf("orders of different markets traded: fills %+v, book %+v", fills, book)
+	}
+
+	// Two sells on the same side never trade with each other either
+	book, events = Execute([]Order{ask}, testOrder("0xdddddddddd", SideSell, 0.3, "5", 4, 4), 100, Markets{})
+	if fills := Fills(events); len(fills) != 0 || len(book) != 2 {
+		t.Fatalf("two sells traded: fills %+v", fills)
+	}
+}
+
+func TestFillsOfOneOrderCarryItsHash(t *testing.T) {
+	bid := testOrder("0xaaaaaaaaaa", SideBuy, 0.6, "10", 1, 1)
+	book, events := Execute(nil, bid, 100, Markets{})
+	book, first := Execute(book, testOrder("0xbbbbbbbbbb", SideSell, 0.5, "4", 2, 2), 100, Markets{})
+	_, second := Execute(book, testOrder("0xcccccccccc", SideSell, 0.5, "3", 3, 3), 100, Markets{})
+
+	fills := append(Fills(first), Fills(second)...)
+	if len(events) != 1 || len(fills) != 2 {
+		t.Fatalf("expected two fills of the bid, got %+v", fills)
+	}
+	for _, f := range fills {
+		if f.MakerHash != bid.Hash {
+			t.Fatalf("fill %+v does not name the bid %s", f, bid.Hash)
+		}
+	}
+
+	// A market order partly filling the same bid names it the same way
+	_, events = ExecuteMarket(book, marketOrder(SideSell, "1", 0), 100, Markets{})
+	if fills := Fills(events); len(fills) != 1 || fills[0].MakerHash != bid.Hash {
+		t.Fatalf("market fill %+v does not name the bid %s", fills, bid.Hash)
+	}
+}
@@ -0,0 +1,63 @@
+package matcher
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// fixedScale is the fixed-point scale of amounts and prices, which carry 8 decimals
+var fixedScale = big.NewInt(100_000_000)
+
+// bpsScale converts basis points to a fraction
+var bpsScale = big.NewInt(10_000)
+
+// parseFixed parses a decimal string into an integer count of 1e-8 units,
+// rounding down any further digits
+func parseFixed(s string) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(fixedScale))
+	return new(big.Int).Quo(r.Num(), r.Denom()), nil
+}
+
+// formatFixed formats a count of 1e-8 units with 8 decimals, like formatAmount
+func formatFixed(v *big.Int) string {
+	q, r := new(big.Int).QuoRem(v, fixedScale, new(big.Int))
+	return fmt.Sprintf("%s.%08d", q, r.Int64())
+}
+
+// fillFee computes the fee one side of a fill pays at rateBps, using the
+// Polymarket outcome-price formula. With p the price and s the size:
+//
+//	seller: rate × min(p, 1−p) × s      paid in collateral
+//	buyer:  rate × min(p, 1−p) × s / p  paid in outcome tokens
+//
+// The fee is computed on 8-decimal fixed-point integers and rounded down, so
+// every node derives the same amount. quantity and price are as formatted by
+// formatAmount.
+func fillFee(quantity, price string, rateBps uint64, buyer bool) string {
+	zero := formatFixed(new(big.Int))
+	size, errSize := parseFixed(quantity)
+	p, errPrice := parseFixed(price)
+	if rateBps == 0 || errSize != nil || errPrice != nil {
+		return zero
+	}
+	if p.Sign() <= 0 || p.Cmp(fixedScale) >= 0 {
+		return zero // outcome prices lie strictly between 0 and 1
+	}
+
+	spread := new(big.Int).Sub(fixedScale, p)
+	if p.Cmp(spread) < 0 {
+		spread.Set(p)
+	}
+
+	fee := new(big.Int).Mul(size, new(big.Int).SetUint64(rateBps))
+	fee.Mul(fee, spread)
+	divisor := new(big.Int).Mul(bpsScale, fixedScale)
+	if buyer {
+		divisor.Mul(bpsScale, p)
+	}
+	return formatFixed(fee.Quo(fee, divisor))
+}
@@ -0,0 +1,55 @@
+package matcher
+
+import "testing"
+
+func TestFillFee(t *testing.T) {
+	tests := []struct {
+		quantity, price string
+		rateBps         uint64
+		buyer           bool
+		want            string
+	}{
+		{"100.00000000", "0.40000000", 100, false, "0.40000000"},
+		{"100.00000000", "0.40000000", 100, true, "1.00000000"},
+		{"100.00000000", "0.70000000", 100, false, "0.30000000"},
+		{"100.00000000", "0.70000000", 100, true, "0.42857142"}, // rounded down
+		{"100.00000000", "0.50000000", 0, true, "0.00000000"},
+		{"0.00000001", "0.50000000", 200, false, "0.00000000"},
+		{"100.00000000", "1.00000000", 100, false, "0.00000000"},
+	}
+	for _, tt := range tests {
+		if got := fillFee(tt.quantity, tt.price, tt.rateBps, tt.buyer); got != tt.want {
+			t.Errorf("fillFee(%s, %s, %d, buyer=%v) = %s, want %s", tt.quantity, tt.price, tt.rateBps, tt.buyer, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteChargesMakerAndTakerFees(t *testing.T) {
+	markets := Markets{
+		Default: MarketParams{TakerFeeBps: 50},
+		Assets:  map[string]MarketParams{"0xasset": {MakerFeeBps: 10, TakerFeeBps: 100}},
+	}
+	ask := testOrder("0xaaaaaaaaaa", SideSell, 0.4, "100", 1, 1)
+	bid := testOrder("0xbbbbbbbbbb", SideBuy, 0.6, "100", 2, 2)
+
+	// The taker bid buys at 0.4 and pays in outcome tokens; the maker sells and pays in collateral
+	_, events := Execute([]Order{ask}, bid, 100, markets)
+	fills := Fills(events)
+	if len(fills) != 1 {
+		t.Fatalf("expected 1 fill, got %+v", fills)
+	}
+	if fills[0].Maker != ask.Maker || fills[0].Taker != bid.Maker {
+		t.Fatalf("fill roles %s/%s, want maker %s and taker %s", fills[0].Maker, fills[0].Taker, ask.Maker, bid.Maker)
+	}
+	if fills[0].MakerFee != "0.04000000" || fills[0].TakerFee != "1.00000000" {
+		t.Fatalf("got maker fee %s and taker fee %s", fills[0].MakerFee, fills[0].TakerFee)
+	}
+
+	// Fees are part of the leaf
+	_, free := Execute([]Order{ask}, bid, 100, Markets{})
+	a, _ := fills[0].CalculateHash()
+	b, _ := Fills(free)[0].CalculateHash()
+	if string(a) == string(b) {
+		t.Fatalf("fill leaf does not commit to fees")
+	}
+}
@@ -0,0 +1,158 @@
+package matcher
+
+import "math"
+
+// OrderType says how an order trades
+type OrderType string
+
+const (
+	OrderLimit  OrderType = "limit"  // rests at its price until filled or cancelled; the default
+	OrderMarket OrderType = "market" // takes liquidity up to an optional worst price and never rests
+)
+
+// IsMarket reports whether the order is a market order
+func (o Order) IsMarket() bool {
+	return o.Type == OrderMarket
+}
+
+// ExecuteMarket matches a market order against the other side of its market
+// and returns the new book and the events produced. A buy spends up to
+// MakeAmount of collateral and a sell sells up to MakeAmount shares; a
+// non-zero Price is the worst price the order accepts. Resting orders are
+// taken best price first, in time priority within a price, and fill at their
+// own price. Whatever is left once the book, the worst price or maxFills runs
+// out is cancelled instead of resting. Like Execute it is pure.
+func ExecuteMarket(book []Order, order Order, maxFills int, markets Markets) ([]Order, []Event) {
+	next := withHashes(book)
+	if order.Hash == "" {
+		order.Hash = OrderHash(order)
+	}
+	events := []Event{{Type: EventOrderAccepted, Order: order}}
+
+	left, err := parseAmount(order.MakeAmount)
+	if err != nil {
+		return next, append(events, Event{Type: EventInvalidAmount, Order: order, Err: err.Error()})
+	}
+
+	buy := order.Side == SideBuy
+	params := markets.Params(order.TakerAsset)
+	removed := make(map[int]bool)
+	fills := 0
+	spent := false // nothing is left that buys a whole unit
+
+	for _, i := range opposite(next, order) {
+		if fills >= maxFills {
+			break
+		}
+		maker := &next[i]
+		if order.Price > 0 && ((buy && maker.Price > order.Price) || (!buy && maker.Price < order.Price)) {
+			break
+		}
+
+		// A buy takes the ask's takeAmount and a sell the bid's makeAmount, as matching does
+		available := maker.MakeAmount
+		if buy {
+			available = maker.TakeAmount
+		}
+		size, err := parseAmount(available)
+		if err != nil {
+			events = append(events, Event{Type: EventInvalidAmount, Order: *maker, Err: err.Error()})
+			removed[i] = true
+			continue
+		}
+
+		// The shares the order still wants at this price, rounded down so a buy never overspends
+		want := left
+		if buy {
+			want = math.Floor(left/maker.Price*1e8) / 1e8
+		}
+		if want <= dustAmount {
+			spent = true
+			break
+		}
+		fillQty := min(size, want)
+
+		fill := Fill{
+			MakerHash: maker.Hash,
+			TakerHash: order.Hash,
+			Maker:     maker.Maker,
+			Taker:     order.Maker,
+			Quantity:  formatAmount(fillQty),
+			Price:     formatAmount(maker.Price),
+			Side:      SideSell,
+		}
+		if buy {
+			fill.Side = SideBuy
+		}
+		fill.MakerFee = fillFee(fill.Quantity, fill.Price, params.MakerFeeBps, fill.Side == SideSell)
+		fill.TakerFee = fillFee(fill.Quantity, fill.Price, params.TakerFeeBps, fill.Side == SideBuy)
+
+		// The taker carries the shares it still wants, so Remaining works as for limit orders
+		taker := order
+		event := Event{Type: EventFill, Fill: fill, Bid: *maker, Ask: taker}
+		if buy {
+			taker.MakeAmount = formatAmount(want)
+			event.Bid, event.Ask = taker, *maker
+		} else {
+			event.Ask.TakeAmount = formatAmount(want)
+		}
+		events = append(events, event)
+		fills++
+
+		size -= fillQty
+		if buy {
+			maker.TakeAmount = formatAmount(size)
+			left -= fillQty * maker.Price
+		} else {
+			maker.MakeAmount = formatAmount(size)
+			left -= fillQty
+		}
+		if size <= dustAmount {
+			events = append(events, Event{Type: EventOrderFilled, Order: *maker})
+			removed[i] = true
+		}
+	}
+
+	rest := order
+	if left > dustAmount && !spent {
+		rest.MakeAmount = formatAmount(left)
+		events = append(events, Event{Type: EventOrderCancelled, Order: rest})
+	} else {
+		rest.MakeAmount = formatAmount(0)
+		events = append(events, Event{Type: EventOrderFilled, Order: rest})
+	}
+
+	if len(removed) == 0 {
+		return next, events
+	}
+	kept := next[:0]
+	for i, o := range next {
+		if !removed[i] {
+			kept = append(kept, o)
+		}
+	}
+	return kept, events
+}
+
+// opposite returns the positions in book of the orders a market order takes
+// from: the asks of its market for a buy and the bids for a sell, best price
+// first and in time priority within a price
+func opposite(book []Order, order Order) []int {
+	bids, asks := splitBidsAsks(book)
+	side := bids
+	if order.Side == SideBuy {
+		side = asks
+	}
+
+	positions := make(map[string]int, len(book))
+	for i, o := range book {
+		positions[o.Hash] = i
+	}
+	var result []int
+	for _, o := range side {
+		if o.TakerAsset == order.TakerAsset {
+			result = append(result, positions[o.Hash])
+		}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package matcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+// marketBook rests bids at 0.45 and 0.4 and asks at 0.5 and 0.55, one share
+// each except two at 0.5
+func marketBook() []Order {
+	return []Order{
+		testOrder("0xaaaaaaaaaa", SideBuy, 0.45, "1", 1, 1),
+		testOrder("0xbbbbbbbbbb", SideBuy, 0.4, "1", 2, 2),
+		testOrder("0xcccccccccc", SideSell, 0.5, "2", 3, 3),
+		testOrder("0xdddddddddd", SideSell, 0.55, "1", 4, 4),
+	}
+}
+
+func marketOrder(side Side, amount string, worst float64) Order {
+	o := Order{Maker: "0xeeeeeeeeee", TakerAsset: "0xasset", MakeAmount: amount, Price: worst, Side: side, Type: OrderMarket, Timestamp: 5, Signature: "0xsig"}
+	o.Hash = OrderHash(o)
+	return o
+}
+
+func TestExecuteMarketBuyWalksAsks(t *testing.T) {
+	book := marketBook()
+	order := marketOrder(SideBuy, "1.55", 0)
+
+	next, events := ExecuteMarket(book, order, 100, Markets{})
+	fills := Fills(events)
+	if len(fills) != 2 || fills[0].Price != "0.50000000" || fills[0].Quantity != "2.00000000" ||
+		fills[1].Price != "0.55000000" || fills[1].Quantity != "1.00000000" || fills[1].Side != SideBuy {
+		t.Fatalf("unexpected fills %+v", fills)
+	}
+	if len(next) != 2 || next[0].Price != 0.45 || next[1].Price != 0.4 {
+		t.Fatalf("asks left on the book: %+v", next)
+	}
+	if last := events[len(events)-1]; last.Type != EventOrderFilled || last.Order.Hash != order.Hash {
+		t.Fatalf("last event %+v, want the order filled", last)
+	}
+	if res := Summarize(order, events, next); res.Status != StatusFilled || res.Filled != "3.00000000" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestExecuteMarketCancelsRemainder(t *testing.T) {
+	// The worst price stops the buy after the 0.5 asks
+	buy := marketOrder(SideBuy, "1.55", 0.5)
+	next, events := ExecuteMarket(marketBook(), buy, 100, Markets{})
+	if len(Fills(events)) != 1 || len(next) != 3 {
+		t.Fatalf("worst price ignored: fills %+v, book %+v", Fills(events), next)
+	}
+	res := Summarize(buy, events, next)
+	if res.Status != StatusCancelled || res.Filled != "2.00000000" || res.Remaining != "0.55000000" {
+		t.Fatalf("unexpected buy result %+v", res)
+	}
+	for _, o := range next {
+		if o.Hash == buy.Hash {
+			t.Fatalf("market order rested on the book")
+		}
+	}
+
+	// A sell hits the bids best first and cancels what the book cannot take
+	sell := marketOrder(SideSell, "3", 0)
+	next, events = ExecuteMarket(marketBook(), sell, 100, Markets{})
+	var prices []string
+	for _, f := range Fills(events) {
+		prices = append(prices, f.Price)
+	}
+	if !reflect.DeepEqual(prices, []string{"0.45000000", "0.40000000"}) {
+		t.Fatalf("sell filled at %v", prices)
+	}
+	if res := Summarize(sell, events, next); res.Status != StatusCancelled || res.Remaining != "1.00000000" {
+		t.Fatalf("unexpected sell result %+v", res)
+	}
+}
+
+func TestCheckMarketOrder(t *testing.T) {
+	markets := Markets{Default: MarketParams{TickSize: 0.01, MinSize: 5}}
+	if err := markets.CheckOrder(marketOrder(SideBuy, "1", 0)); err != nil {
+		t.Fatalf("market buy below min size rejected: %v", err)
+	}
+	for _, tt := range []struct {
+		name  string
+		order Order
+		field string
+	}{
+		{"no side", marketOrder("", "10", 0), "side"},
+		{"off tick worst price", marketOrder(SideBuy, "10", 0.555), "price"},
+		{"sell below min size", marketOrder(SideSell, "1", 0), "makeAmount"},
+		{"unknown type", Order{Type: "stop"}, "orderType"},
+	} {
+		rule, ok := markets.CheckOrder(tt.order).(*RuleError)
+		if !ok || rule.Field != tt.field {
+			t.Errorf("%s: got %v, want a %s rule error", tt.name, rule, tt.field)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MarketParams are the trading parameters of a market. A zero TickSize or
+// MinSize leaves prices or sizes unrestricted.
+type MarketParams struct {
+	TickSize    float64 `json:"tickSize"`
+	MinSize     float64 `json:"minSize"`
+	MakerFeeBps uint64  `json:"makerFeeBps"`
+	TakerFeeBps uint64  `json:"takerFeeBps"`
+}
+
+// Markets holds the parameters of every market. An order belongs to the
+// market of the asset it trades, its TakerAsset; markets without an entry
+// use Default.
+type Markets struct {
+	Default MarketParams            `json:"default"`
+	Assets  map[string]MarketParams `json:"assets,omitempty"`
+}
+
+// Params returns the parameters of the market for asset
+func (m Markets) Params(asset string) MarketParams {
+	if p, ok := m.Assets[asset]; ok {
+		return p
+	}
+	return m.Default
+}
+
+// WithTickSize returns a copy of m in which the market for asset has the given tick size
+func (m Markets) WithTickSize(asset string, tick float64) Markets {
+	assets := make(map[string]MarketParams, len(m.Assets)+1)
+	for k, v := range m.Assets {
+		assets[k] = v
+	}
+	p := m.Params(asset)
+	p.TickSize = tick
+	assets[asset] = p
+	m.Assets = assets
+	return m
+}
+
+// Errors returned for orders that break the rules of their market
+var (
+	ErrPriceOutOfRange = errors.New("price out of range")
+	ErrInvalidTick     = errors.New("price not on tick")
+	ErrBelowMinSize    = errors.New("size below market minimum")
+	ErrFeeRateTooLow   = errors.New("feeRateBps below market fee rate")
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrInvalidSide     = errors.New("invalid side")
+	ErrInvalidType     = errors.New("invalid order type")
+)
+
+// RuleError reports which field of an order broke a market rule. It wraps
+// one of the errors above, so errors.Is still identifies the rule.
+type RuleError struct {
+	Field string
+	Err   error
+	Msg   string
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, e.Msg)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// priceUnits converts a price to 1e-8 units. ok is false if the price has
+// more precision than fills are formatted with.
+func priceUnits(price float64) (units int64, ok bool) {
+	scaled := price * 1e8
+	rounded := math.Round(scaled)
+	return int64(rounded), math.Abs(scaled-rounded) < 1e-4
+}
+
+// ValidTickSize checks that tick can serve as a tick size: strictly between 0
+// and 1, with at most 8 decimals
+func ValidTickSize(tick float64) error {
+	if !(tick > 0 && tick < 1) {
+		return fmt.Errorf("tick size %v must be between 0 and 1 exclusive", tick)
+	}
+	if _, ok := priceUnits(tick); !ok {
+		return fmt.Errorf("tick size %v has more than 8 decimals", tick)
+	}
+	return nil
+}
+
+// OnTick reports whether price is a whole multiple of tick. A zero tick accepts any price.
+func OnTick(price, tick float64) bool {
+	if tick == 0 {
+		return true
+	}
+	p, ok := priceUnits(price)
+	t, tickOK := priceUnits(tick)
+	return ok && tickOK && t > 0 && p%t == 0
+}
+
+// CheckOrder verifies an order against the rules of its market. Orders must
+// declare a side, binary outcomes trade strictly between 0 and 1, prices must
+// sit on the tick, both
+// amounts must reach the minimum size and the signed fee rate must cover the
+// market's.
+func (m Markets) CheckOrder(o Order) error {
+	switch o.Type {
+	case "", OrderLimit:
+	case OrderMarket:
+		return m.checkMarketOrder(o)
+	default:
+		return &RuleError{Field: "orderType", Err: ErrInvalidType, Msg: fmt.Sprintf("order type %q must be limit or market", o.Type)}
+	}
+
+	p := m.Params(o.TakerAsset)
+
+	// The declared side decides which orders it can cross
+	if o.Side != SideBuy && o.Side != SideSell {
+		return &RuleError{Field: "side", Err: ErrInvalidSide, Msg: "orders must be buy or sell"}
+	}
+
+	if !(o.Price > 0 && o.Price < 1) {
+		return &RuleError{Field: "price", Err: ErrPriceOutOfRange, Msg: fmt.Sprintf("price %v must be between 0 and 1 exclusive", o.Price)}
+	}
+	if !OnTick(o.Price, p.TickSize) {
+		return &RuleError{Field: "price", Err: ErrInvalidTick, Msg: fmt.Sprintf("price %v is not a multiple of tick size %v", o.Price, p.TickSize)}
+	}
+
+	for _, amount := range []struct{ field, value string }{{"makeAmount", o.MakeAmount}, {"takeAmount", o.TakeAmount}} {
+		v, err := parseAmount(amount.value)
+		if err != nil {
+			return &RuleError{Field: amount.field, Err: ErrInvalidAmount, Msg: err.Error()}
+		}
+		if v < p.MinSize {
+			return &RuleError{Field: amount.field, Err: ErrBelowMinSize, Msg: fmt.Sprintf("%s %s is below minimum size %v", amount.field, amount.value, p.MinSize)}
+		}
+	}
+
+	return m.CheckFeeRate(o)
+}
+
+// checkMarketOrder verifies a market order. It needs a side, its price is an
+// optional worst price, and only makeAmount counts: the collateral to spend
+// when buying, which has no minimum, and the shares to sell when selling.
+func (m Markets) checkMarketOrder(o Order) error {
+	p := m.Params(o.TakerAsset)
+
+	if o.Side != SideBuy && o.Side != SideSell {
+		return &RuleError{Field: "side", Err: ErrInvalidSide, Msg: "market orders must be buy or sell"}
+	}
+	if o.Price != 0 {
+		if !(o.Price > 0 && o.Price < 1) {
+			return &RuleError{Field: "price", Err: ErrPriceOutOfRange, Msg: fmt.Sprintf("worst price %v must be between 0 and 1 exclusive", o.Price)}
+		}
+		if !OnTick(o.Price, p.TickSize) {
+			return &RuleError{Field: "price", Err: ErrInvalidTick, Msg: fmt.Sprintf("worst price %v is not a multiple of tick size %v", o.Price, p.TickSize)}
+		}
+	}
+
+	v, err := parseAmount(o.MakeAmount)
+	if err != nil {
+		return &RuleError{Field: "makeAmount", Err: ErrInvalidAmount, Msg: err.Error()}
+	}
+	if o.Side == SideSell && v < p.MinSize {
+		return &RuleError{Field: "makeAmount", Err: ErrBelowMinSize, Msg: fmt.Sprintf("makeAmount %s is below minimum size %v", o.MakeAmount, p.MinSize)}
+	}
+
+	return m.CheckFeeRate(o)
+}
+
+// CheckFeeRate verifies that an order's signed feeRateBps covers the fee
+// rate of its market in either role, since a resting order can be a maker
+// and an incoming order a taker
+func (m Markets) CheckFeeRate(o Order) error {
+	p := m.Params(o.TakerAsset)
+	rate := p.MakerFeeBps
+	if p.TakerFeeBps > rate {
+		rate = p.TakerFeeBps
+	}
+	if o.FeeRateBps < rate {
+		return &RuleError{Field: "feeRateBps", Err: ErrFeeRateTooLow, Msg: fmt.Sprintf("order signs %d bps, market charges up to %d bps", o.FeeRateBps, rate)}
+	}
+	return nil
+}
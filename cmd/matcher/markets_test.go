@@ -0,0 +1,90 @@
+package matcher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckOrder(t *testing.T) {
+	markets := Markets{
+		Default: MarketParams{TickSize: 0.01},
+		Assets:  map[string]MarketParams{"0xfine": {TickSize: 0.001, MinSize: 5}},
+	}
+
+	tests := []struct {
+		asset  string
+		price  float64
+		amount string
+		want   error
+	}{
+		{"0xasset", 0.57, "1", nil},
+		{"0xasset", 0.575, "1", ErrInvalidTick},
+		{"0xasset", 0.5000001, "1", ErrInvalidTick},
+		{"0xasset", 1, "1", ErrPriceOutOfRange},
+		{"0xasset", 0, "1", ErrPriceOutOfRange},
+		{"0xfine", 0.575, "5", nil},
+		{"0xfine", 0.5755, "5", ErrInvalidTick},
+		{"0xfine", 0.575, "4.99", ErrBelowMinSize},
+	}
+	for _, tt := range tests {
+		o := testOrder("0xaaaaaaaaaa", SideBuy, tt.price, tt.amount, 1, 1)
+		o.TakerAsset = tt.asset
+		if err := markets.CheckOrder(o); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("CheckOrder(%s @ %v x %s) = %v, want %v", tt.asset, tt.price, tt.amount, err, tt.want)
+		}
+	}
+}
+
+func TestWithTickSizeCopiesMarkets(t *testing.T) {
+	markets := Markets{Default: MarketParams{TickSize: 0.01, MinSize: 1}}
+	finer := markets.WithTickSize("0xasset", 0.001)
+
+	if got := finer.Params("0xasset"); got.TickSize != 0.001 || got.MinSize != 1 {
+		t.Fatalf("unexpected params after tick change: %+v", got)
+	}
+	if markets.Params("0xasset").TickSize != 0.01 {
+		t.Fatalf("WithTickSize modified the original markets")
+	}
+	if err := ValidTickSize(0.0001); err != nil {
+		t.Fatalf("valid tick size rejected: %v", err)
+	}
+	if err := ValidTickSize(1); err == nil {
+		t.Fatalf("tick size 1 accepted")
+	}
+}
+
+func TestCheckFeeRate(t *testing.T) {
+	markets := Markets{Assets: map[string]MarketParams{"0xasset": {MakerFeeBps: 10, TakerFeeBps: 100}}}
+	o := testOrder("0xaaaaaaaaaa", SideBuy, 0.4, "100", 1, 1)
+
+	if err := markets.CheckFeeRate(o); !errors.Is(err, ErrFeeRateTooLow) {
+		t.Fatalf("expected ErrFeeRateTooLow, got %v", err)
+	}
+	o.FeeRateBps = 100
+	if err := markets.CheckFeeRate(o); err != nil {
+		t.Fatalf("order signing the taker rate rejected: %v", err)
+	}
+	o.TakerAsset = "0xother"
+	o.FeeRateBps = 0
+	if err := markets.CheckFeeRate(o); err != nil {
+		t.Fatalf("order in a fee-free market rejected: %v", err)
+	}
+}
+
+func TestRuleErrorNamesField(t *testing.T) {
+	markets := Markets{Default: MarketParams{MinSize: 5}}
+	o := testOrder("0xaaaaaaaaaa", SideBuy, 0.5, "10", 1, 1)
+	o.TakeAmount = "1"
+
+	var rule *RuleError
+	err := markets.CheckOrder(o)
+	if !errors.As(err, &rule) || rule.Field != "takeAmount" || !errors.Is(err, ErrBelowMinSize) {
+		t.Fatalf("expected a takeAmount minimum size error, got %v", err)
+	}
+
+	// Limit orders must declare which side of the book they are on
+	o.TakeAmount, o.Side = "10", ""
+	if err := markets.CheckOrder(o); !errors.As(err, &rule) || rule.Field != "side" || !errors.Is(err, ErrInvalidSide) {
+		t.Fatalf("expected a side error, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"sort"
 	"strconv"
@@ -77,26 +78,51 @@ func init() {
 
 // Order represents a polymarket CLOB order with EIP-712 signature
 type Order struct {
-	Maker      string  `json:"maker"`
-	TakerAsset string  `json:"takerAsset"`
-	MakeAmount string  `json:"makeAmount"`
-	TakeAmount string  `json:"takeAmount"`
-	Price      float64 `json:"price"`
-	Timestamp  int64   `json:"timestamp"`
-	Signature  string  `json:"signature"`
+	Hash       string    `json:"hash,omitempty"`
+	Seq        uint64    `json:"seq,omitempty"`
+	Maker      string    `json:"maker"`
+	TakerAsset string    `json:"takerAsset"`
+	MakeAmount string    `json:"makeAmount"`
+	TakeAmount string    `json:"takeAmount"`
+	Price      float64   `json:"price"`
+	Timestamp  int64     `json:"timestamp"`
+	FeeRateBps uint64    `json:"feeRateBps,omitempty"`
+	Nonce      uint64    `json:"nonce,omitempty"`      // maker nonce; the order is void once the maker's on-chain nonce exceeds it
+	Expiration int64     `json:"expiration,omitempty"` // unix seconds after which the order is void; 0 never expires
+	Side       Side      `json:"side,omitempty"`       // required direction; decides which orders it crosses and whether the maker commits collateral or outcome tokens
+	Type       OrderType `json:"orderType,omitempty"`  // limit if empty; market orders spend makeAmount without resting
+	Signature  string    `json:"signature"`
 }
 
-// Fill represents a matched order fill for the Merkle tree
+// Side is the direction of an order; a fill records the side of its taker
+type Side string
+
+const (
+	SideBuy  Side = "buy"  // the taker was a bid and lifted a resting ask
+	SideSell Side = "sell" // the taker was an ask and hit a resting bid
+)
+
+// Fill represents a matched order fill for the Merkle tree. The maker is the
+// order that was resting on the book and the taker is the aggressor; the fill
+// executes at the maker's limit price. Fees are charged to the buyer in
+// outcome tokens and to the seller in collateral.
 type Fill struct {
 	MakerHash string `json:"makerHash"`
 	TakerHash string `json:"takerHash"`
+	Maker     string `json:"maker"`
+	Taker     string `json:"taker"`
 	Quantity  string `json:"quantity"`
+	Price     string `json:"price"`
+	Side      Side   `json:"side"`
+	MakerFee  string `json:"makerFee"`
+	TakerFee  string `json:"takerFee"`
 }
 
 // CalculateHash implements merkletree.Content interface
 func (f Fill) CalculateHash() ([]byte, error) {
 	h := sha256.New()
-	data := fmt.Sprintf("%s:%s:%s", f.MakerHash, f.TakerHash, f.Quantity)
+	data := fmt.Sprintf("%s:%s:%s:%s:%s:%s:%s:%s:%s", f.MakerHash, f.TakerHash, f.Maker, f.Taker,
+		f.Quantity, f.Price, f.Side, f.MakerFee, f.TakerFee)
 	h.Write([]byte(data))
 	return h.Sum(nil), nil
 }
@@ -107,17 +133,22 @@ func (f Fill) Equals(other merkletree.Content) (bool, error) {
 	if !ok {
 		return false, nil
 	}
-	return f.MakerHash == otherFill.MakerHash &&
-		f.TakerHash == otherFill.TakerHash &&
-		f.Quantity == otherFill.Quantity, nil
+	return f == otherFill, nil
 }
 
-// orderHash creates a hash for an order
-func orderHash(order Order) string {
+// Expired reports whether the order is past its expiration at now, in unix
+// milliseconds. As on the exchange, an order is still valid during the second
+// of its expiration.
+func (o Order) Expired(now int64) bool {
+	return o.Expiration != 0 && o.Expiration < now/1000
+}
+
+// OrderHash creates a hash for an order
+func OrderHash(order Order) string {
 	h := sha256.New()
-	data := fmt.Sprintf("%s:%s:%s:%s:%.8f:%d:%s",
+	data := fmt.Sprintf("%s:%s:%s:%s:%.8f:%d:%d:%d:%d:%s",
 		order.Maker, order.TakerAsset, order.MakeAmount, order.TakeAmount,
-		order.Price, order.Timestamp, order.Signature)
+		order.Price, order.Timestamp, order.FeeRateBps, order.Nonce, order.Expiration, order.Signature)
 	h.Write([]byte(data))
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
@@ -130,14 +161,21 @@ func min(a, b float64) float64 {
 	return b
 }
 
+// dustAmount is the smallest amount the matcher treats as non-zero; amounts
+// are formatted with 8 decimals, so anything at or below it rounds away
+const dustAmount = 0.00000001
+
 // parseAmount safely parses a string amount to float64
 func parseAmount(amountStr string) (float64, error) {
 	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil {
 		return 0, fmt.Errorf("invalid amount format: %w", err)
 	}
-	if amount <= 0 {
-		return 0, fmt.Errorf("amount must be positive, got: %f", amount)
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("amount must be finite, got: %s", amountStr)
+	}
+	if amount <= dustAmount {
+		return 0, fmt.Errorf("amount must be greater than %.8f, got: %s", dustAmount, amountStr)
 	}
 	return amount, nil
 }
@@ -147,45 +185,71 @@ func formatAmount(amount float64) string {
 	return fmt.Sprintf("%.8f", amount)
 }
 
-// sortOrders sorts orders by price-time priority (descending price, ascending timestamp)
-func sortOrders(orders []Order) {
-	sort.Slice(orders, func(i, j int) bool {
+// restedFirst reports whether a was on the book before b. The sequencer
+// assigns increasing sequence numbers, so the lower one rested first; orders
+// without one fall back to timestamp and hash.
+func restedFirst(a, b Order) bool {
+	if a.Seq != 0 && b.Seq != 0 && a.Seq != b.Seq {
+		return a.Seq < b.Seq
+	}
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp < b.Timestamp
+	}
+	return a.Hash < b.Hash
+}
+
+// sortOrders sorts orders of one side by price-time priority: best price
+// first, which is the highest for bids and the lowest for asks, then earliest
+// timestamp. Ties are broken by sequence number and then hash, so the result
+// does not depend on the order of the input.
+func sortOrders(orders []Order, side Side) {
+	sort.SliceStable(orders, func(i, j int) bool {
 		if orders[i].Price != orders[j].Price {
-			return orders[i].Price > orders[j].Price // Descending price (higher prices first)
+			if side == SideSell {
+				return orders[i].Price < orders[j].Price // Ascending ask price (cheapest first)
+			}
+			return orders[i].Price > orders[j].Price // Descending bid price (highest first)
 		}
-		return orders[i].Timestamp < orders[j].Timestamp // Ascending timestamp (earlier first)
+		if orders[i].Timestamp != orders[j].Timestamp {
+			return orders[i].Timestamp < orders[j].Timestamp // Ascending timestamp (earlier first)
+		}
+		if orders[i].Seq != orders[j].Seq {
+			return orders[i].Seq < orders[j].Seq // Ascending sequence number (accepted first)
+		}
+		return orders[i].Hash < orders[j].Hash
 	})
 }
 
-// splitBidsAsks separates orders into bids (buyers) and asks (sellers)
-// For simplicity, we assume higher priced orders are bids and lower are asks
-// In a real implementation, this would be determined by order type field
+// splitBidsAsks separates orders into bids (buyers) and asks (sellers) by
+// their declared side, each in price-time priority. Orders without a valid
+// side are on neither side and never match.
 func splitBidsAsks(orders []Order) (bids []Order, asks []Order) {
-	if len(orders) == 0 {
-		return bids, asks
-	}
-
-	// Sort by price first to determine bid/ask classification
-	sorted := make([]Order, len(orders))
-	copy(sorted, orders)
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].Price > sorted[j].Price
-	})
-
-	// Split at median price - top half are bids, bottom half are asks
-	midpoint := len(sorted) / 2
-	if len(sorted)%2 == 1 {
-		midpoint++
+	for _, o := range orders {
+		switch o.Side {
+		case SideBuy:
+			bids = append(bids, o)
+		case SideSell:
+			asks = append(asks, o)
+		}
 	}
+	sortOrders(bids, SideBuy)
+	sortOrders(asks, SideSell)
+	return bids, asks
+}
 
-	for i := 0; i < midpoint && i < len(sorted); i++ {
-		bids = append(bids, sorted[i])
-	}
-	for i := midpoint; i < len(sorted); i++ {
-		asks = append(asks, sorted[i])
+// byMarket groups orders by the asset they trade and returns the assets in
+// sorted order, so markets are matched in the same order on every replay
+func byMarket(orders []Order) ([]string, map[string][]Order) {
+	groups := make(map[string][]Order)
+	var assets []string
+	for _, o := range orders {
+		if _, ok := groups[o.TakerAsset]; !ok {
+			assets = append(assets, o.TakerAsset)
+		}
+		groups[o.TakerAsset] = append(groups[o.TakerAsset], o)
 	}
-
-	return bids, asks
+	sort.Strings(assets)
+	return assets, groups
 }
 
 // computeMerkleRoot builds a Merkle tree over fills and returns the root
@@ -213,58 +277,130 @@ func computeMerkleRoot(fills []Fill) (string, error) {
 
 // MatchAndBatch implements enhanced multi-fill matching logic with order book pruning
 func MatchAndBatch(orders []Order, maxBatch int) (string, []byte, []Order, error) {
-	// Check if we have enough orders to match
-	if len(orders) < 2 {
-		log.Printf("Not enough orders to match. Current orderbook size: %d", len(orders))
+	fills, remainingOrders := Match(orders, maxBatch)
+
+	// If no fills were created, return original orders
+	if len(fills) == 0 {
 		return "", nil, orders, nil
 	}
 
-	log.Printf("Starting multi-fill matching process with %d orders, maxBatch: %d", len(orders), maxBatch)
+	root, fillsBytes, err := BuildBatch(fills)
+	if err != nil {
+		return "", nil, remainingOrders, err
+	}
+
+	return root, fillsBytes, remainingOrders, nil
+}
+
+// BuildBatch computes the Merkle root over fills and serializes them for submission
+func BuildBatch(fills []Fill) (string, []byte, error) {
+	root, err := computeMerkleRoot(fills)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to compute merkle root: %w", err)
+	}
+
+	fillsBytes, err := json.Marshal(fills)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal fills: %w", err)
+	}
+
+	return root, fillsBytes, nil
+}
+
+// Match runs price-time priority matching over the order book, producing up to
+// maxBatch fills and the remaining orders with updated amounts
+func Match(orders []Order, maxBatch int) ([]Fill, []Order) {
+	fills, remaining, _ := match(orders, maxBatch, Markets{})
+	return fills, remaining
+}
+
+// match is Match that also charges fees at the rates of markets and reports
+// what happened as events. It neither logs nor reads the clock, and does not
+// modify orders.
+func match(orders []Order, maxBatch int, markets Markets) ([]Fill, []Order, []Event) {
+	var events []Event
+
+	// Check if we have enough orders to match
+	if len(orders) < 2 {
+		return nil, orders, events
+	}
 
-	// Initialize variables for the matching loop
 	fills := []Fill{}
 	remainingOrders := make([]Order, 0, len(orders))
 
-	// 1. Sort orders by descending Price, ascending Timestamp
-	sortedOrders := make([]Order, len(orders))
-	copy(sortedOrders, orders)
-	sortOrders(sortedOrders)
+	// Only orders of the same market trade with each other
+	assets, groups := byMarket(orders)
+	for _, asset := range assets {
+		// 1. Split into bids and asks, each in price-time priority
+		bids, asks := splitBidsAsks(groups[asset])
+		for _, o := range groups[asset] {
+			if o.Side != SideBuy && o.Side != SideSell {
+				remainingOrders = append(remainingOrders, o)
+			}
+		}
+
+		marketFills, marketEvents, rest := matchMarket(bids, asks, maxBatch-len(fills), markets.Params(asset))
+		fills = append(fills, marketFills...)
+		events = append(events, marketEvents...)
+		remainingOrders = append(remainingOrders, rest...)
+	}
 
-	// 2. Split into bids and asks
-	bids, asks := splitBidsAsks(sortedOrders)
-	
-	log.Printf("Split orders: %d bids, %d asks", len(bids), len(asks))
+	// If no fills were created, return original orders
+	if len(fills) == 0 {
+		return nil, orders, events
+	}
+
+	return fills, remainingOrders, events
+}
+
+// withHashes returns a copy of orders in which every order carries its hash
+func withHashes(orders []Order) []Order {
+	result := make([]Order, len(orders))
+	for i, o := range orders {
+		if o.Hash == "" {
+			o.Hash = OrderHash(o)
+		}
+		result[i] = o
+	}
+	return result
+}
+
+// matchMarket crosses the bids and asks of one market, both in price-time
+// priority, producing up to maxFills fills charged at the fee rates of
+// params. It returns the fills, the events and the orders left resting.
+func matchMarket(bids, asks []Order, maxFills int, params MarketParams) ([]Fill, []Event, []Order) {
+	var fills []Fill
+	var events []Event
+	var remainingOrders []Order
 
-	// Create working copies to modify during matching
-	workingBids := make([]Order, len(bids))
-	workingAsks := make([]Order, len(asks))
-	copy(workingBids, bids)
-	copy(workingAsks, asks)
+	// Create working copies to modify during matching, identified by the
+	// hash of their original amounts
+	workingBids := withHashes(bids)
+	workingAsks := withHashes(asks)
 
 	i, j := 0, 0
 
 	// Multi-fill matching loop
-	for len(fills) < maxBatch && i < len(workingBids) && j < len(workingAsks) {
+	for len(fills) < maxFills && i < len(workingBids) && j < len(workingAsks) {
 		bid := &workingBids[i]
 		ask := &workingAsks[j]
 
 		// Check if orders can cross (bid price >= ask price)
 		if bid.Price < ask.Price {
-			log.Printf("No more crossable orders: bid price %.8f < ask price %.8f", bid.Price, ask.Price)
 			break
 		}
 
 		// Parse amounts for calculation
 		bidMakeAmount, err := parseAmount(bid.MakeAmount)
 		if err != nil {
-			log.Printf("Error parsing bid makeAmount: %v", err)
+			events = append(events, Event{Type: EventInvalidAmount, Order: *bid, Err: err.Error()})
 			i++
 			continue
 		}
 
 		askTakeAmount, err := parseAmount(ask.TakeAmount)
 		if err != nil {
-			log.Printf("Error parsing ask takeAmount: %v", err)
+			events = append(events, Event{Type: EventInvalidAmount, Order: *ask, Err: err.Error()})
 			j++
 			continue
 		}
@@ -272,16 +408,25 @@ func MatchAndBatch(orders []Order, maxBatch int) (string, []byte, []Order, error
 		// 2. Compute fillQty = min(bid.makeAmount, ask.takeAmount)
 		fillQty := min(bidMakeAmount, askTakeAmount)
 
-		log.Printf("Matching bid %.8f @ %.8f with ask %.8f @ %.8f, fill quantity: %.8f",
-			bidMakeAmount, bid.Price, askTakeAmount, ask.Price, fillQty)
-
-		// Create fill record
+		// Create fill record at the resting maker's price. The hashes are
+		// the orders' own, which stay the same while their amounts shrink.
+		maker, taker, side := *ask, *bid, SideBuy
+		if restedFirst(*bid, *ask) {
+			maker, taker, side = *bid, *ask, SideSell
+		}
 		fill := Fill{
-			MakerHash: orderHash(*bid),
-			TakerHash: orderHash(*ask),
+			MakerHash: maker.Hash,
+			TakerHash: taker.Hash,
+			Maker:     maker.Maker,
+			Taker:     taker.Maker,
 			Quantity:  formatAmount(fillQty),
+			Price:     formatAmount(maker.Price),
+			Side:      side,
 		}
+		fill.MakerFee = fillFee(fill.Quantity, fill.Price, params.MakerFeeBps, side == SideSell)
+		fill.TakerFee = fillFee(fill.Quantity, fill.Price, params.TakerFeeBps, side == SideBuy)
 		fills = append(fills, fill)
+		events = append(events, Event{Type: EventFill, Fill: fill, Bid: *bid, Ask: *ask})
 
 		// 3. Reduce bid.MakeAmount and ask.TakeAmount by fillQty
 		bidMakeAmount -= fillQty
@@ -291,72 +436,29 @@ func MatchAndBatch(orders []Order, maxBatch int) (string, []byte, []Order, error
 		ask.TakeAmount = formatAmount(askTakeAmount)
 
 		// 4. Advance or keep pointers based on leftover
-		if bidMakeAmount <= 0.00000001 { // Use epsilon for floating point comparison
-			log.Printf("Bid fully filled, advancing to next bid")
+		if bidMakeAmount <= dustAmount { // Use epsilon for floating point comparison
+			events = append(events, Event{Type: EventOrderFilled, Order: *bid})
 			i++
 		}
-		if askTakeAmount <= 0.00000001 { // Use epsilon for floating point comparison
-			log.Printf("Ask fully filled, advancing to next ask")
+		if askTakeAmount <= dustAmount { // Use epsilon for floating point comparison
+			events = append(events, Event{Type: EventOrderFilled, Order: *ask})
 			j++
 		}
 	}
 
-	log.Printf("Matching complete: %d fills created", len(fills))
-
 	// 5. Build remaining orders list - append unmatched bids and asks
-	// Add unmatched bids
 	for idx := i; idx < len(workingBids); idx++ {
-		if amount, err := parseAmount(workingBids[idx].MakeAmount); err == nil && amount > 0.00000001 {
+		if amount, err := parseAmount(workingBids[idx].MakeAmount); err == nil && amount > dustAmount {
 			remainingOrders = append(remainingOrders, workingBids[idx])
 		}
 	}
-
-	// Add unmatched asks  
 	for idx := j; idx < len(workingAsks); idx++ {
-		if amount, err := parseAmount(workingAsks[idx].TakeAmount); err == nil && amount > 0.00000001 {
+		if amount, err := parseAmount(workingAsks[idx].TakeAmount); err == nil && amount > dustAmount {
 			remainingOrders = append(remainingOrders, workingAsks[idx])
 		}
 	}
 
-	// Add partially filled orders if they have remaining amounts
-	if i > 0 && i <= len(workingBids) {
-		if bid := workingBids[i-1]; i-1 < len(workingBids) {
-			if amount, err := parseAmount(bid.MakeAmount); err == nil && amount > 0.00000001 {
-				remainingOrders = append(remainingOrders, bid)
-			}
-		}
-	}
-	if j > 0 && j <= len(workingAsks) {
-		if ask := workingAsks[j-1]; j-1 < len(workingAsks) {
-			if amount, err := parseAmount(ask.TakeAmount); err == nil && amount > 0.00000001 {
-				remainingOrders = append(remainingOrders, ask)
-			}
-		}
-	}
-
-	log.Printf("Remaining orders after matching: %d (started with %d)", len(remainingOrders), len(orders))
-
-	// If no fills were created, return original orders
-	if len(fills) == 0 {
-		log.Printf("No matches found, returning original orders")
-		return "", nil, orders, nil
-	}
-
-	// Compute Merkle root for fills
-	root, err := computeMerkleRoot(fills)
-	if err != nil {
-		return "", nil, remainingOrders, fmt.Errorf("failed to compute merkle root: %w", err)
-	}
-
-	log.Printf("Merkle root computed: %s", root)
-
-	// Serialize fills to bytes
-	fillsBytes, err := json.Marshal(fills)
-	if err != nil {
-		return "", nil, remainingOrders, fmt.Errorf("failed to marshal fills: %w", err)
-	}
-
-	return root, fillsBytes, remainingOrders, nil
+	return fills, events, remainingOrders
 }
 
 // AggregateBLS creates a real BLS aggregate signature for the batch root
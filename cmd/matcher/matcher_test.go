@@ -0,0 +1,227 @@
+package matcher
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// quantityTolerance absorbs the rounding of amounts to 8 decimals on every fill
+const quantityTolerance = 1e-6
+
+// decodeOrders turns fuzz input into an order book. Every 4 bytes describe one
+// order: price in cents, amount, timestamp and a byte that picks the amount's
+// precision or an amount the matcher has to reject; its top bits pick the
+// side and one of two markets. Sequence numbers are unique, like the ones the
+// sequencer assigns.
+func decodeOrders(data []byte) []Order {
+	var orders []Order
+	for k := 0; k+4 <= len(data) && len(orders) < 64; k += 4 {
+		price := float64(data[k]%99+1) / 100
+		amount := fmt.Sprintf("%d", data[k+1]%50+1)
+		switch {
+		case data[k+3]%3 == 0:
+			amount = fmt.Sprintf("%d.%03d", data[k+1]%50, int(data[k+3])*7%1000+1)
+		case data[k+3]%16 == 1:
+			amount = []string{"Inf", "NaN", "1e-9", "-1", "x"}[int(data[k+1])%5]
+		}
+		side := SideBuy
+		if data[k+3]&0x40 != 0 {
+			side = SideSell
+		}
+		o := testOrder(fmt.Sprintf("0x%02x%02x", k, data[k+2]), side, price, amount, int64(data[k+2]%8), uint64(len(orders)+1))
+		if data[k+3]&0x80 != 0 {
+			o.TakerAsset = "0xother"
+			o.Hash = OrderHash(o)
+		}
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+func amountOf(t *testing.T, s string) float64 {
+	t.Helper()
+	v, err := parseAmount(s)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// checkMatchInvariants runs match and checks the properties every result must have
+func checkMatchInvariants(t *testing.T, orders []Order, maxBatch int) {
+	t.Helper()
+
+	fills, remaining, events := match(orders, maxBatch, Markets{})
+
+	// maxBatch is respected
+	if len(fills) > maxBatch {
+		t.Fatalf("%d fills exceed maxBatch %d", len(fills), maxBatch)
+	}
+
+	// No order is left on the book twice, and nothing appears that was not there
+	bySeq := make(map[uint64]Order, len(orders))
+	for _, o := range orders {
+		bySeq[o.Seq] = o
+	}
+	left := make(map[uint64]Order, len(remaining))
+	for _, o := range remaining {
+		if _, dup := left[o.Seq]; dup {
+			t.Fatalf("order %d appears twice in the remaining book", o.Seq)
+		}
+		if _, ok := bySeq[o.Seq]; !ok {
+			t.Fatalf("remaining book holds unknown order %d", o.Seq)
+		}
+		left[o.Seq] = o
+	}
+
+	// Quantity is conserved: what an order lost equals what it filled
+	isBid := make(map[uint64]bool, len(orders))
+	for _, o := range orders {
+		isBid[o.Seq] = o.Side == SideBuy
+	}
+
+	filled := make(map[uint64]float64)
+	bidSeqs := make(map[string][]uint64)
+	askSeqs := make(map[string][]uint64)
+	for _, e := range events {
+		if e.Type != EventFill {
+			continue
+		}
+		q := amountOf(t, e.Fill.Quantity)
+		if q <= 0 {
+			t.Fatalf("fill with non-positive quantity %q", e.Fill.Quantity)
+		}
+		if e.Bid.Price < e.Ask.Price {
+			t.Fatalf("fill between bid @ %.2f and ask @ %.2f does not cross", e.Bid.Price, e.Ask.Price)
+		}
+		if e.Bid.Side != SideBuy || e.Ask.Side != SideSell || e.Bid.TakerAsset != e.Ask.TakerAsset {
+			t.Fatalf("fill between %s %s order %d and %s %s order %d", e.Bid.Side, e.Bid.TakerAsset, e.Bid.Seq,
+				e.Ask.Side, e.Ask.TakerAsset, e.Ask.Seq)
+		}
+		maker, side := e.Ask, SideBuy
+		if restedFirst(e.Bid, e.Ask) {
+			maker, side = e.Bid, SideSell
+		}
+		if e.Fill.Side != side || e.Fill.Price != formatAmount(maker.Price) {
+			t.Fatalf("fill %+v does not execute at resting order %d's price %.2f", e.Fill, maker.Seq, maker.Price)
+		}
+		// Fills name orders by the hash they were placed with, however often they fill
+		if e.Fill.MakerHash != bySeq[maker.Seq].Hash || (e.Fill.TakerHash != bySeq[e.Bid.Seq].Hash && e.Fill.TakerHash != bySeq[e.Ask.Seq].Hash) {
+			t.Fatalf("fill %+v does not carry the hashes of orders %d and %d", e.Fill, e.Bid.Seq, e.Ask.Seq)
+		}
+		filled[e.Bid.Seq] += q
+		filled[e.Ask.Seq] += q
+		bidSeqs[e.Bid.TakerAsset] = append(bidSeqs[e.Bid.TakerAsset], e.Bid.Seq)
+		askSeqs[e.Ask.TakerAsset] = append(askSeqs[e.Ask.TakerAsset], e.Ask.Seq)
+	}
+
+	if len(fills) > 0 {
+		for _, o := range orders {
+			original := amountOf(t, o.TakeAmount)
+			after := 0.0
+			if r, ok := left[o.Seq]; ok {
+				after = amountOf(t, r.TakeAmount)
+			}
+			if isBid[o.Seq] {
+				original = amountOf(t, o.MakeAmount)
+				if r, ok := left[o.Seq]; ok {
+					after = amountOf(t, r.MakeAmount)
+				} else {
+					after = 0
+				}
+			}
+			if original == 0 {
+				continue // skipped for an invalid amount
+			}
+			if math.Abs(original-after-filled[o.Seq]) > quantityTolerance {
+				t.Fatalf("order %d: started with %.8f, %.8f left, but filled %.8f",
+					o.Seq, original, after, filled[o.Seq])
+			}
+		}
+	}
+
+	// Price-time priority: each side of a market is consumed strictly in
+	// sorted order, so an order only fills once every better order is done
+	assets, groups := byMarket(orders)
+	for _, asset := range assets {
+		bids, asks := splitBidsAsks(groups[asset])
+		checkPriority(t, "bid", bidSeqs[asset], bids)
+		checkPriority(t, "ask", askSeqs[asset], asks)
+	}
+
+	// No market is left crossed unless matching stopped at maxBatch
+	if len(fills) < maxBatch {
+		bestBid, bestAsk := make(map[string]float64), make(map[string]float64)
+		for _, o := range remaining {
+			if amountOf(t, o.MakeAmount) == 0 || amountOf(t, o.TakeAmount) == 0 {
+				continue // never matched, so it takes no side
+			}
+			if isBid[o.Seq] {
+				bestBid[o.TakerAsset] = math.Max(bestBid[o.TakerAsset], o.Price)
+			} else if ask, ok := bestAsk[o.TakerAsset]; !ok || o.Price < ask {
+				bestAsk[o.TakerAsset] = o.Price
+			}
+		}
+		for asset, bid := range bestBid {
+			if ask, ok := bestAsk[asset]; ok && bid >= ask {
+				t.Fatalf("market %s left crossed: best bid %.2f >= best ask %.2f", asset, bid, ask)
+			}
+		}
+	}
+}
+
+// checkPriority verifies fills on one side visit orders in priority order without revisiting
+func checkPriority(t *testing.T, side string, seqs []uint64, ordered []Order) {
+	t.Helper()
+	rank := make(map[uint64]int, len(ordered))
+	for i, o := range ordered {
+		rank[o.Seq] = i
+	}
+	for k := 1; k < len(seqs); k++ {
+		if rank[seqs[k]] < rank[seqs[k-1]] {
+			t.Fatalf("%s %d filled after lower-priority %s %d", side, seqs[k], side, seqs[k-1])
+		}
+	}
+}
+
+func FuzzMatch(f *testing.F) {
+	f.Add([]byte{60, 10, 1, 2, 50, 4, 2, 2}, uint8(100))
+	f.Add([]byte{60, 10, 1, 2, 55, 3, 2, 2, 50, 4, 3, 2, 40, 2, 4, 2}, uint8(2))
+	f.Add([]byte{50, 5, 0, 2, 50, 5, 0, 2, 50, 5, 0, 2, 50, 5, 0, 2}, uint8(1))
+
+	f.Fuzz(func(t *testing.T, data []byte, maxBatch uint8) {
+		checkMatchInvariants(t, decodeOrders(data), int(maxBatch%128)+1)
+	})
+}
+
+func TestMatchInvariantsRandomBooks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		data := make([]byte, 4*(rng.Intn(24)+2))
+		rng.Read(data)
+		checkMatchInvariants(t, decodeOrders(data), rng.Intn(20)+1)
+	}
+}
+
+func TestMatchIsIndependentOfBookOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for n := 0; n < 100; n++ {
+		data := make([]byte, 4*(rng.Intn(16)+2))
+		rng.Read(data)
+		orders := decodeOrders(data)
+
+		wantFills, wantBook := Match(orders, 100)
+		shuffled := append([]Order(nil), orders...)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		gotFills, gotBook := Match(shuffled, 100)
+
+		if fmt.Sprint(gotFills) != fmt.Sprint(wantFills) {
+			t.Fatalf("fills depend on book order")
+		}
+		if len(wantFills) > 0 && fmt.Sprint(gotBook) != fmt.Sprint(wantBook) {
+			t.Fatalf("remaining book depends on book order")
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package matcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/cbergoon/merkletree"
+)
+
+// MerkleProof proves that a fill is a leaf of a batch's Merkle tree.
+// Siblings are the hashes next to the path from the leaf to the root, leaf
+// level first; Right[i] is true when Siblings[i] is the right-hand node.
+type MerkleProof struct {
+	Leaf     string   `json:"leaf"`
+	Siblings []string `json:"siblings"`
+	Right    []bool   `json:"right"`
+}
+
+// BatchProofs builds the Merkle tree over fills, as BuildBatch does, and
+// returns its root and the proof of every fill, in order
+func BatchProofs(fills []Fill) (string, []MerkleProof, error) {
+	if len(fills) == 0 {
+		return "", nil, fmt.Errorf("cannot compute merkle proofs for empty fills")
+	}
+
+	contents := make([]merkletree.Content, len(fills))
+	for i, fill := range fills {
+		contents[i] = fill
+	}
+	tree, err := merkletree.NewTree(contents)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create merkle tree: %w", err)
+	}
+
+	proofs := make([]MerkleProof, len(fills))
+	for i, fill := range fills {
+		leaf, err := fill.CalculateHash()
+		if err != nil {
+			return "", nil, err
+		}
+		path, index, err := tree.GetMerklePath(fill)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get merkle path of fill %d: %w", i, err)
+		}
+
+		proof := MerkleProof{Leaf: hex.EncodeToString(leaf), Siblings: make([]string, len(path)), Right: make([]bool, len(path))}
+		for j, sibling := range path {
+			proof.Siblings[j] = hex.EncodeToString(sibling)
+			proof.Right[j] = index[j] == 1
+		}
+		proofs[i] = proof
+	}
+	return fmt.Sprintf("%x", tree.MerkleRoot()), proofs, nil
+}
+
+// Verify reports whether the proof leads from fill to root
+func (p MerkleProof) Verify(fill Fill, root string) bool {
+	hash, err := fill.CalculateHash()
+	if err != nil || hex.EncodeToString(hash) != p.Leaf || len(p.Right) != len(p.Siblings) {
+		return false
+	}
+
+	for i, s := range p.Siblings {
+		sibling, err := hex.DecodeString(s)
+		if err != nil {
+			return false
+		}
+		h := sha256.New()
+		if p.Right[i] {
+			h.Write(hash)
+			h.Write(sibling)
+		} else {
+			h.Write(sibling)
+			h.Write(hash)
+		}
+		hash = h.Sum(nil)
+	}
+
+	want, err := hex.DecodeString(root)
+	return err == nil && bytes.Equal(hash, want)
+}
@@ -0,0 +1,54 @@
+package matcher
+
+import "testing"
+
+func TestBatchProofs(t *testing.T) {
+	for n := 1; n <= 5; n++ {
+		fills := make([]Fill, n)
+		for i := range fills {
+			fills[i] = Fill{
+				MakerHash: "maker", TakerHash: "taker",
+				Quantity: formatAmount(float64(i + 1)), Price: "0.50000000", Side: SideBuy,
+				MakerFee: "0.00000000", TakerFee: "0.00000000",
+			}
+		}
+		root, _, err := BuildBatch(fills)
+		if err != nil {
+			t.Fatalf("BuildBatch failed: %v", err)
+		}
+
+		proofRoot, proofs, err := BatchProofs(fills)
+		if err != nil {
+			t.Fatalf("BatchProofs failed: %v", err)
+		}
+		if proofRoot != root {
+			t.Fatalf("%d fills: proof root %s, batch root %s", n, proofRoot, root)
+		}
+		for i, p := range proofs {
+			if !p.Verify(fills[i], root) {
+				t.Errorf("%d fills: proof of fill %d does not verify", n, i)
+			}
+			other := fills[(i+1)%n]
+			if n > 1 && p.Verify(other, root) {
+				t.Errorf("%d fills: proof of fill %d verifies another fill", n, i)
+			}
+		}
+	}
+}
+
+func TestEventRemaining(t *testing.T) {
+	ask := testOrder("0xaaaaaaaaaa", SideSell, 0.4, "10", 1, 1)
+	bid := testOrder("0xbbbbbbbbbb", SideBuy, 0.5, "4", 2, 2)
+	_, events := Execute([]Order{ask}, bid, 10, Markets{})
+
+	for _, e := range events {
+		if e.Type != EventFill {
+			continue
+		}
+		if b, a := e.Remaining(); b != "0.00000000" || a != "6.00000000" {
+			t.Fatalf("Remaining() = %s, %s; want 0 and 6", b, a)
+		}
+		return
+	}
+	t.Fatalf("no fill in %+v", events)
+}
@@ -0,0 +1,147 @@
+package matcher
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Quote is the estimated outcome of taking liquidity from the current book.
+// Prices and amounts are fixed-point with 8 decimals.
+type Quote struct {
+	Size         string `json:"size"`         // shares that would fill
+	Cost         string `json:"cost"`         // collateral that would change hands
+	AveragePrice string `json:"averagePrice"` // volume-weighted average price; empty if nothing fills
+	WorstPrice   string `json:"worstPrice"`   // price of the last level reached; empty if nothing fills
+	Midpoint     string `json:"midpoint"`     // mean of the best bid and ask; empty unless both sides rest
+	Slippage     string `json:"slippage"`     // how much worse than the midpoint the average is, as a fraction of it
+	Fillable     bool   `json:"fillable"`     // the whole amount fills within the worst price
+}
+
+// QuoteMarket estimates what a market order would fill against book without
+// changing it. Fees are left out.
+func QuoteMarket(book []Order, order Order, markets Markets) Quote {
+	_, events := ExecuteMarket(book, order, len(book)+1, markets)
+
+	// The order's own outcome is always the last event
+	t := newTally(order.Side)
+	for _, e := range events {
+		if e.Type != EventFill {
+			continue
+		}
+		qty, errQty := parseFixed(e.Fill.Quantity)
+		price, errPrice := parseFixed(e.Fill.Price)
+		if errQty != nil || errPrice != nil {
+			continue
+		}
+		t.add(qty, price)
+	}
+	return t.quote(book, order.TakerAsset, events[len(events)-1].Type == EventOrderFilled)
+}
+
+// QuoteSize estimates the price of buying or selling size shares in the
+// market of asset from the price levels of book, up to a worst price if
+// worst is non-zero
+func QuoteSize(book []Order, asset string, side Side, size string, worst float64) (Quote, error) {
+	want, err := parseFixed(size)
+	if err != nil || want.Sign() <= 0 {
+		return Quote{}, fmt.Errorf("%w: size %q must be positive", ErrInvalidAmount, size)
+	}
+	limit, _ := priceUnits(worst)
+
+	bids, asks := Depth(book, asset)
+	levels := bids
+	if side == SideBuy {
+		levels = asks
+	}
+
+	t := newTally(side)
+	left := new(big.Int).Set(want)
+	for _, l := range levels {
+		if left.Sign() <= 0 {
+			break
+		}
+		price, errPrice := parseFixed(l.Price)
+		available, errSize := parseFixed(l.Size)
+		if errPrice != nil || errSize != nil {
+			continue
+		}
+		if limit > 0 && ((side == SideBuy && price.Int64() > limit) || (side == SideSell && price.Int64() < limit)) {
+			break
+		}
+		if available.Cmp(left) > 0 {
+			available.Set(left)
+		}
+		t.add(available, price)
+		left.Sub(left, available)
+	}
+	return t.quote(book, asset, left.Sign() <= 0), nil
+}
+
+// tally accumulates the fills of a quote in 1e-8 units
+type tally struct {
+	side  Side
+	size  *big.Int
+	cost  *big.Int
+	worst *big.Int
+}
+
+func newTally(side Side) *tally {
+	return &tally{side: side, size: new(big.Int), cost: new(big.Int)}
+}
+
+// add counts qty shares filled at price
+func (t *tally) add(qty, price *big.Int) {
+	t.size.Add(t.size, qty)
+	t.cost.Add(t.cost, new(big.Int).Quo(new(big.Int).Mul(qty, price), fixedScale))
+	t.worst = price
+}
+
+// quote summarizes the tally against the midpoint of asset's market in book
+func (t *tally) quote(book []Order, asset string, fillable bool) Quote {
+	q := Quote{Size: formatFixed(t.size), Cost: formatFixed(t.cost), Fillable: fillable}
+
+	var avg *big.Int
+	if t.size.Sign() > 0 {
+		avg = new(big.Int).Quo(new(big.Int).Mul(t.cost, fixedScale), t.size)
+		q.AveragePrice = formatFixed(avg)
+		q.WorstPrice = formatFixed(t.worst)
+	}
+
+	mid, ok := midpoint(Depth(book, asset))
+	if !ok {
+		return q
+	}
+	q.Midpoint = formatFixed(mid)
+	if avg != nil && mid.Sign() > 0 {
+		// Paying above the midpoint when buying and receiving below it when selling is slippage
+		diff := new(big.Int).Sub(avg, mid)
+		if t.side == SideSell {
+			diff.Neg(diff)
+		}
+		q.Slippage = formatSigned(new(big.Int).Quo(diff.Mul(diff, fixedScale), mid))
+	}
+	return q
+}
+
+// midpoint returns the mean of the best bid and ask, in 1e-8 units. ok is
+// false unless both sides have a level.
+func midpoint(bids, asks []Level) (*big.Int, bool) {
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil, false
+	}
+	bid, errBid := parseFixed(bids[0].Price)
+	ask, errAsk := parseFixed(asks[0].Price)
+	if errBid != nil || errAsk != nil {
+		return nil, false
+	}
+	mid := new(big.Int).Add(bid, ask)
+	return mid.Quo(mid, big.NewInt(2)), true
+}
+
+// formatSigned is formatFixed for values that may be negative
+func formatSigned(v *big.Int) string {
+	if v.Sign() < 0 {
+		return "-" + formatFixed(new(big.Int).Neg(v))
+	}
+	return formatFixed(v)
+}
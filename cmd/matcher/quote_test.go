@@ -0,0 +1,59 @@
+package matcher
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQuoteMarket(t *testing.T) {
+	book := marketBook()
+	snapshot := append([]Order(nil), book...)
+
+	q := QuoteMarket(book, marketOrder(SideBuy, "1.55", 0), Markets{})
+	want := Quote{Size: "3.00000000", Cost: "1.55000000", AveragePrice: "0.51666666", WorstPrice: "0.55000000",
+		Midpoint: "0.47500000", Slippage: "0.08771928", Fillable: true}
+	if q != want {
+		t.Fatalf("got quote %+v, want %+v", q, want)
+	}
+	if !reflect.DeepEqual(book, snapshot) {
+		t.Fatalf("QuoteMarket modified the book")
+	}
+
+	if q := QuoteMarket(book, marketOrder(SideSell, "5", 0.45), Markets{}); q.Fillable || q.Size != "1.00000000" {
+		t.Fatalf("quote beyond the worst price: %+v", q)
+	}
+}
+
+func TestQuoteSize(t *testing.T) {
+	book := marketBook()
+
+	q, err := QuoteSize(book, "0xasset", SideBuy, "2.5", 0)
+	if err != nil {
+		t.Fatalf("QuoteSize failed: %v", err)
+	}
+	want := Quote{Size: "2.50000000", Cost: "1.27500000", AveragePrice: "0.51000000", WorstPrice: "0.55000000",
+		Midpoint: "0.47500000", Slippage: "0.07368421", Fillable: true}
+	if q != want {
+		t.Fatalf("got quote %+v, want %+v", q, want)
+	}
+
+	// Selling stops at the worst price and reports what it reached
+	q, err = QuoteSize(book, "0xasset", SideSell, "3", 0.45)
+	if err != nil {
+		t.Fatalf("QuoteSize failed: %v", err)
+	}
+	if q.Fillable || q.Size != "1.00000000" || q.WorstPrice != "0.45000000" || q.Slippage != "0.05263157" {
+		t.Fatalf("unexpected sell quote %+v", q)
+	}
+
+	// An empty market quotes nothing
+	q, err = QuoteSize(book, "0xother", SideBuy, "1", 0)
+	if err != nil || q.Fillable || q.Size != "0.00000000" || q.AveragePrice != "" || q.Midpoint != "" {
+		t.Fatalf("unexpected quote of an empty market %+v, %v", q, err)
+	}
+
+	if _, err := QuoteSize(book, "0xasset", SideBuy, "0", 0); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("zero size = %v, want ErrInvalidAmount", err)
+	}
+}
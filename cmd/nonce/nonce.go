@@ -0,0 +1,208 @@
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Increment is a raise of a maker's on-chain nonce. Orders with a lower
+// nonce can no longer be settled.
+type Increment struct {
+	Maker common.Address
+	Nonce uint64
+}
+
+// Source delivers nonce increments
+type Source interface {
+	Increments() <-chan Increment
+}
+
+// Listen applies the increments of src until ctx is cancelled or the source
+// closes its channel
+func Listen(ctx context.Context, src Source, apply func(Increment)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case inc, ok := <-src.Increments():
+			if !ok {
+				return
+			}
+			apply(inc)
+		}
+	}
+}
+
+// LocalSource is a Source fed in-process, for tests and local development
+// without an exchange contract
+type LocalSource struct {
+	ch chan Increment
+}
+
+// NewLocalSource creates a local source that buffers up to buffer increments
+func NewLocalSource(buffer int) *LocalSource {
+	return &LocalSource{ch: make(chan Increment, buffer)}
+}
+
+// Increment emits an increment, blocking while the buffer is full
+func (s *LocalSource) Increment(maker common.Address, nonce uint64) {
+	s.ch <- Increment{Maker: maker, Nonce: nonce}
+}
+
+// Close ends the source; Listen returns once the buffered increments are applied
+func (s *LocalSource) Close() {
+	close(s.ch)
+}
+
+// Increments implements Source
+func (s *LocalSource) Increments() <-chan Increment {
+	return s.ch
+}
+
+// Config holds the exchange whose nonces are followed and the poll interval
+type Config struct {
+	Exchange     common.Address // zero disables the chain source
+	PollInterval time.Duration
+}
+
+// LoadConfig reads the nonce source configuration from environment variables
+func LoadConfig() (Config, error) {
+	cfg := Config{PollInterval: 2000 * time.Millisecond}
+
+	if v := os.Getenv("EXCHANGE_ADDRESS"); v != "" {
+		if !common.IsHexAddress(v) {
+			return cfg, fmt.Errorf("invalid EXCHANGE_ADDRESS: %s", v)
+		}
+		cfg.Exchange = common.HexToAddress(v)
+	}
+
+	if v := os.Getenv("NONCE_POLL_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid NONCE_POLL_MS: %s (must be positive integer)", v)
+		}
+		cfg.PollInterval = time.Duration(n) * time.Millisecond
+	}
+
+	return cfg, nil
+}
+
+// Chain is the subset of the Ethereum client used by the chain source
+type Chain interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// ChainSource follows the exchange's nonces of the makers with resting
+// orders. The exchange emits no event when a maker calls incrementNonce, so
+// it reads nonces(maker) with eth_call every PollInterval.
+type ChainSource struct {
+	cfg    Config
+	chain  Chain
+	makers func() []common.Address
+	ch     chan Increment
+
+	mu    sync.Mutex
+	known map[common.Address]uint64
+}
+
+// NewChainSource creates a source that watches the makers returned by makers
+func NewChainSource(cfg Config, chain Chain, makers func() []common.Address) *ChainSource {
+	return &ChainSource{
+		cfg:    cfg,
+		chain:  chain,
+		makers: makers,
+		ch:     make(chan Increment, 64),
+		known:  make(map[common.Address]uint64),
+	}
+}
+
+// Enabled reports whether an exchange is configured
+func (s *ChainSource) Enabled() bool {
+	return s.cfg.Exchange != (common.Address{})
+}
+
+// Increments implements Source
+func (s *ChainSource) Increments() <-chan Increment {
+	return s.ch
+}
+
+// Run polls the nonces of the watched makers until ctx is cancelled
+func (s *ChainSource) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+	log.Printf("Nonce source started - Exchange: %s, Poll interval: %v", s.cfg.Exchange.Hex(), s.cfg.PollInterval)
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.poll(ctx); err != nil {
+			log.Printf("Nonce poll error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the nonce of each watched maker and emits those that rose
+func (s *ChainSource) poll(ctx context.Context) error {
+	for _, maker := range s.makers() {
+		n, err := s.Nonce(ctx, maker)
+		if err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		raised := n > s.known[maker]
+		if raised {
+			s.known[maker] = n
+		}
+		s.mu.Unlock()
+		if !raised {
+			continue
+		}
+
+		select {
+		case s.ch <- Increment{Maker: maker, Nonce: n}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Nonce reads the current nonce of maker from the exchange
+func (s *ChainSource) Nonce(ctx context.Context, maker common.Address) (uint64, error) {
+	data, err := contracts.NonceManagerABI.Pack("nonces", maker)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack nonces: %w", err)
+	}
+	res, err := s.chain.CallContract(ctx, ethereum.CallMsg{To: &s.cfg.Exchange, Data: data}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read nonce of %s: %w", maker.Hex(), err)
+	}
+	out, err := contracts.NonceManagerABI.Unpack("nonces", res)
+	if err != nil || len(out) == 0 {
+		return 0, fmt.Errorf("failed to decode nonce of %s: %v", maker.Hex(), err)
+	}
+	n, ok := out[0].(*big.Int)
+	if !ok || !n.IsUint64() {
+		return 0, fmt.Errorf("nonce of %s out of range: %v", maker.Hex(), out[0])
+	}
+	return n.Uint64(), nil
+}
@@ -0,0 +1,96 @@
+package nonce
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	exchange = common.HexToAddress("0x00000000000000000000000000000000000000e0")
+	alice    = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	bob      = common.HexToAddress("0x00000000000000000000000000000000000000b0")
+)
+
+// fakeChain serves nonces(maker) from a map
+type fakeChain struct {
+	nonces map[common.Address]uint64
+}
+
+func (c *fakeChain) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	method, err := contracts.NonceManagerABI.MethodById(msg.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	n := c.nonces[args[0].(common.Address)]
+	return method.Outputs.Pack(new(big.Int).SetUint64(n))
+}
+
+func TestListenAppliesLocalIncrements(t *testing.T) {
+	src := NewLocalSource(4)
+	src.Increment(alice, 1)
+	src.Increment(bob, 3)
+	src.Increment(alice, 2)
+	src.Close()
+
+	var got []Increment
+	Listen(context.Background(), src, func(inc Increment) { got = append(got, inc) })
+
+	want := []Increment{{alice, 1}, {bob, 3}, {alice, 2}}
+	if len(got) != len(want) {
+		t.Fatalf("applied %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("increment %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestChainSourceEmitsRaisedNonces(t *testing.T) {
+	chain := &fakeChain{nonces: map[common.Address]uint64{bob: 2}}
+	s := NewChainSource(Config{Exchange: exchange}, chain, func() []common.Address {
+		return []common.Address{alice, bob}
+	})
+	ctx := context.Background()
+
+	if n, err := s.Nonce(ctx, bob); err != nil || n != 2 {
+		t.Fatalf("Nonce(bob) = %d, %v, want 2", n, err)
+	}
+
+	// The first poll reports makers whose nonce is above zero
+	if err := s.poll(ctx); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	if inc := <-s.Increments(); inc != (Increment{bob, 2}) {
+		t.Fatalf("first poll emitted %+v, want bob at 2", inc)
+	}
+
+	// Unchanged nonces are not emitted again
+	chain.nonces[alice] = 1
+	if err := s.poll(ctx); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	if inc := <-s.Increments(); inc != (Increment{alice, 1}) {
+		t.Fatalf("second poll emitted %+v, want alice at 1", inc)
+	}
+	select {
+	case inc := <-s.Increments():
+		t.Fatalf("unexpected increment %+v", inc)
+	default:
+	}
+}
+
+func TestChainSourceDisabledWithoutExchange(t *testing.T) {
+	if NewChainSource(Config{}, nil, nil).Enabled() {
+		t.Fatal("source without exchange is enabled")
+	}
+}
@@ -0,0 +1,389 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned when a batch cannot be enqueued because the queue is at capacity
+var ErrQueueFull = errors.New("submission queue full")
+
+// ErrClosed is returned when publishing to a pipeline that has been closed
+var ErrClosed = errors.New("submission pipeline closed")
+
+// Batch represents a matched batch awaiting signing and on-chain submission
+type Batch struct {
+	ID    uint64
+	Root  string
+	Fills []byte
+}
+
+// SignFunc produces an aggregate signature over a batch root
+type SignFunc func(root string) ([]byte, error)
+
+// SubmitFunc submits a signed batch on-chain and returns the transaction hash,
+// or an empty hash if the batch was already settled
+type SubmitFunc func(root string, fills []byte, aggSig []byte) (string, error)
+
+// Stage is a step a batch passes on its way on-chain
+type Stage string
+
+const (
+	StageSigned    Stage = "signed"    // the operators' aggregate signature over the root was produced
+	StageSubmitted Stage = "submitted" // the batch was submitted, or found already settled
+	StageFailed    Stage = "failed"    // submission failed permanently; the batch was parked and the pipeline halted
+	StageHeld      Stage = "held"      // the batch was not submitted because an earlier batch halted the pipeline
+)
+
+// StageFunc observes batches as they pass each stage. detail is the
+// transaction hash of a submitted batch, empty if it was already settled, and
+// the error of a failed batch.
+type StageFunc func(b Batch, stage Stage, detail string)
+
+// RetryableFunc reports whether a submission error is transient, so the same
+// batch may still land if it is submitted again
+type RetryableFunc func(err error) bool
+
+// ParkFunc records a batch whose submission failed permanently, with its
+// aggregate signature, the number of submissions attempted and the last error
+type ParkFunc func(b Batch, aggSig []byte, attempts int, err error)
+
+// Config holds the sizing parameters of the submission pipeline
+type Config struct {
+	QueueSize    int
+	Workers      int
+	RetryBackoff time.Duration // wait before signing and submitting a failed batch again
+}
+
+// LoadConfig reads the pipeline configuration from environment variables
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		QueueSize:    64,
+		Workers:      4,
+		RetryBackoff: 1000 * time.Millisecond,
+	}
+
+	if v := os.Getenv("PIPELINE_QUEUE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid PIPELINE_QUEUE_SIZE: %s (must be positive integer)", v)
+		}
+		cfg.QueueSize = n
+	}
+
+	if v := os.Getenv("PIPELINE_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid PIPELINE_WORKERS: %s (must be positive integer)", v)
+		}
+		cfg.Workers = n
+	}
+
+	if v := os.Getenv("PIPELINE_RETRY_BACKOFF_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid PIPELINE_RETRY_BACKOFF_MS: %s (must be positive integer)", v)
+		}
+		cfg.RetryBackoff = time.Duration(n) * time.Millisecond
+	}
+
+	return cfg, nil
+}
+
+// Pipeline is a bounded queue of batches drained by a pool of signing-and-submission workers
+type Pipeline struct {
+	queue   chan Batch
+	workers int
+	backoff time.Duration
+	sign    SignFunc
+	submit  SubmitFunc
+
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{} // closed by Close to stop retrying failed batches
+	onStage   StageFunc
+	retryable RetryableFunc
+	onPark    ParkFunc
+	wg        sync.WaitGroup
+
+	// Batches are signed concurrently but submitted strictly in ID order so
+	// that on-chain batch IDs follow the order in which batches were cut. A
+	// batch that fails transiently keeps the turn until it is submitted. One
+	// that fails permanently, or is still failing when the pipeline closes,
+	// halts the pipeline and no later batch is submitted.
+	turnMu     sync.Mutex
+	turn       *sync.Cond
+	nextSubmit uint64
+	halted     bool
+}
+
+// New creates a pipeline; call Start to launch the worker pool
+func New(cfg Config, sign SignFunc, submit SubmitFunc) *Pipeline {
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = 1
+	}
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.RetryBackoff < time.Millisecond {
+		cfg.RetryBackoff = time.Millisecond
+	}
+
+	p := &Pipeline{
+		queue:   make(chan Batch, cfg.QueueSize),
+		workers: cfg.Workers,
+		backoff: cfg.RetryBackoff,
+		sign:    sign,
+		submit:  submit,
+		done:    make(chan struct{}),
+	}
+	p.turn = sync.NewCond(&p.turnMu)
+	return p
+}
+
+// OnStage registers a hook run by the workers as each batch is signed and submitted
+func (p *Pipeline) OnStage(fn StageFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onStage = fn
+}
+
+// RetryIf registers the classifier of submission errors. Transient errors are
+// retried in turn; any other error parks the batch and halts the pipeline.
+// Without a classifier every error is treated as transient.
+func (p *Pipeline) RetryIf(fn RetryableFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retryable = fn
+}
+
+// OnPark registers a hook run once for a batch whose submission failed permanently
+func (p *Pipeline) OnPark(fn ParkFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onPark = fn
+}
+
+// isRetryable classifies a submission error with the registered classifier
+func (p *Pipeline) isRetryable(err error) bool {
+	p.mu.RLock()
+	fn := p.retryable
+	p.mu.RUnlock()
+	return fn == nil || fn(err)
+}
+
+// park records a permanently failed batch and halts the pipeline behind it
+func (p *Pipeline) park(b Batch, aggSig []byte, attempts int, err error) {
+	p.mu.RLock()
+	fn := p.onPark
+	p.mu.RUnlock()
+	if fn != nil {
+		fn(b, aggSig, attempts, err)
+	}
+	p.notify(b, StageFailed, err.Error())
+	p.halt()
+}
+
+// notify runs the stage hook, if any
+func (p *Pipeline) notify(b Batch, stage Stage, txHash string) {
+	p.mu.RLock()
+	fn := p.onStage
+	p.mu.RUnlock()
+	if fn != nil {
+		fn(b, stage, txHash)
+	}
+}
+
+// Start launches the worker pool
+func (p *Pipeline) Start() {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.worker(i)
+	}
+	log.Printf("Submission pipeline started - Workers: %d, Queue size: %d, Retry backoff: %v", p.workers, cap(p.queue), p.backoff)
+}
+
+// Publish enqueues a batch, blocking while the queue is full until space frees up or ctx is done.
+// Batches must be published in ascending ID order.
+func (p *Pipeline) Publish(ctx context.Context, b Batch) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	p.initTurn(b.ID)
+
+	select {
+	case p.queue <- b:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrQueueFull, ctx.Err())
+	}
+}
+
+// TryPublish enqueues a batch without blocking, returning ErrQueueFull when at capacity
+func (p *Pipeline) TryPublish(b Batch) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	p.initTurn(b.ID)
+
+	select {
+	case p.queue <- b:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// initTurn starts submission ordering at the first batch ever published
+func (p *Pipeline) initTurn(id uint64) {
+	p.turnMu.Lock()
+	if p.nextSubmit == 0 {
+		p.nextSubmit = id
+	}
+	p.turnMu.Unlock()
+}
+
+// waitTurn blocks until the batch with the given ID is next to be submitted.
+// It returns false if an earlier batch was halted, so id must not be submitted.
+func (p *Pipeline) waitTurn(id uint64) bool {
+	p.turnMu.Lock()
+	defer p.turnMu.Unlock()
+	for p.nextSubmit < id && !p.halted {
+		p.turn.Wait()
+	}
+	return p.nextSubmit >= id
+}
+
+// endTurn hands the submission slot to the batch after id
+func (p *Pipeline) endTurn(id uint64) {
+	p.turnMu.Lock()
+	if p.nextSubmit <= id {
+		p.nextSubmit = id + 1
+	}
+	p.turnMu.Unlock()
+	p.turn.Broadcast()
+}
+
+// halt gives up on the batch holding the turn, holding back every later batch
+func (p *Pipeline) halt() {
+	p.turnMu.Lock()
+	p.halted = true
+	p.turnMu.Unlock()
+	p.turn.Broadcast()
+}
+
+// retryWait waits out the retry backoff, or returns false once the pipeline closes
+func (p *Pipeline) retryWait() bool {
+	select {
+	case <-p.done:
+		return false
+	case <-time.After(p.backoff):
+		return true
+	}
+}
+
+// Len returns the number of batches waiting in the queue
+func (p *Pipeline) Len() int {
+	return len(p.queue)
+}
+
+// Cap returns the capacity of the queue
+func (p *Pipeline) Cap() int {
+	return cap(p.queue)
+}
+
+// Full reports whether the queue is at capacity
+func (p *Pipeline) Full() bool {
+	return len(p.queue) >= cap(p.queue)
+}
+
+// Close stops accepting batches and waits for the workers to drain the queue
+func (p *Pipeline) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	close(p.done)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	log.Printf("Submission pipeline stopped")
+}
+
+// worker signs and submits batches until the queue is closed and drained
+func (p *Pipeline) worker(id int) {
+	defer p.wg.Done()
+
+	for b := range p.queue {
+		p.process(id, b)
+	}
+}
+
+// process signs a single batch and submits it on-chain once its turn comes up.
+// A batch that cannot be signed or fails to submit with a transient error
+// keeps the turn and is signed and submitted again until it lands, so that no
+// later batch takes its on-chain ID. A permanent submission error parks the
+// batch and halts the pipeline instead.
+func (p *Pipeline) process(id int, b Batch) {
+	aggSig, err := p.sign(b.Root)
+
+	if !p.waitTurn(b.ID) {
+		log.Printf("Worker %d: batch %d (root %s) held back behind a halted batch", id, b.ID, b.Root)
+		p.notify(b, StageHeld, "")
+		return
+	}
+
+	signed := false
+	attempts := 0
+	for {
+		if err != nil {
+			log.Printf("Worker %d: BLS aggregate error for batch %d (root %s): %v", id, b.ID, b.Root, err)
+		} else {
+			if !signed {
+				p.notify(b, StageSigned, "")
+				signed = true
+			}
+
+			var txHash string
+			attempts++
+			if txHash, err = p.submit(b.Root, b.Fills, aggSig); err == nil {
+				if txHash == "" {
+					log.Printf("Worker %d: batch %d was already settled on-chain", id, b.ID)
+				} else {
+					log.Printf("Worker %d: batch %d submitted: %s", id, b.ID, txHash)
+				}
+				p.notify(b, StageSubmitted, txHash)
+				p.endTurn(b.ID)
+				return
+			}
+			if !p.isRetryable(err) {
+				log.Printf("Worker %d: batch %d (root %s) failed permanently, parking it and holding back later batches: %v", id, b.ID, b.Root, err)
+				p.park(b, aggSig, attempts, err)
+				return
+			}
+			log.Printf("Worker %d: error submitting batch %d (root %s): %v", id, b.ID, b.Root, err)
+		}
+
+		if !p.retryWait() {
+			log.Printf("Worker %d: pipeline closed before batch %d (root %s) was submitted; later batches are held back", id, b.ID, b.Root)
+			p.halt()
+			return
+		}
+		aggSig, err = p.sign(b.Root)
+	}
+}
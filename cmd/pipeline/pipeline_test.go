@@ -0,0 +1,377 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
+)
+
+func TestPipelineSubmitsPublishedBatches(t *testing.T) {
+	var mu sync.Mutex
+	submitted := map[string][]byte{}
+
+	sign := func(root string) ([]byte, error) {
+		return []byte("sig_" + root), nil
+	}
+	submit := func(root string, fills []byte, aggSig []byte) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		submitted[root] = aggSig
+		return "0xtx_" + root, nil
+	}
+
+	p := New(Config{QueueSize: 4, Workers: 2}, sign, submit)
+	p.Start()
+
+	for i, root := range []string{"aa", "bb", "cc"} {
+		if err := p.Publish(context.Background(), Batch{ID: uint64(i + 1), Root: root, Fills: []byte("[]")}); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", root, err)
+		}
+	}
+	p.Close()
+
+	if len(submitted) != 3 {
+		t.Fatalf("expected 3 submitted batches, got %d", len(submitted))
+	}
+	if string(submitted["bb"]) != "sig_bb" {
+		t.Errorf("batch bb submitted with signature %q", submitted["bb"])
+	}
+}
+
+func TestPipelineAppliesBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+
+	sign := func(root string) ([]byte, error) {
+		started <- struct{}{}
+		<-release
+		return []byte("sig"), nil
+	}
+	submit := func(root string, fills []byte, aggSig []byte) (string, error) {
+		return "0xtx", nil
+	}
+
+	p := New(Config{QueueSize: 1, Workers: 1}, sign, submit)
+	p.Start()
+	defer p.Close()
+
+	// The single worker picks up the first batch and blocks in sign
+	if err := p.Publish(context.Background(), Batch{ID: 1, Root: "first"}); err != nil {
+		t.Fatalf("Publish(first) failed: %v", err)
+	}
+	<-started
+
+	// The second batch occupies the only queue slot
+	if err := p.TryPublish(Batch{ID: 2, Root: "second"}); err != nil {
+		t.Fatalf("TryPublish(second) failed: %v", err)
+	}
+	if !p.Full() {
+		t.Fatalf("expected queue to be full")
+	}
+
+	if err := p.TryPublish(Batch{ID: 3, Root: "third"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull from TryPublish, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Publish(ctx, Batch{ID: 3, Root: "third"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull from Publish, got %v", err)
+	}
+
+	close(release)
+}
+
+func TestPipelineSubmitsInIDOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []uint64
+
+	// Later batches sign faster than earlier ones
+	sign := func(root string) ([]byte, error) {
+		if root == "slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return []byte("sig"), nil
+	}
+	ids := map[string]uint64{"slow": 1, "fast-a": 2, "fast-b": 3}
+	submit := func(root string, fills []byte, aggSig []byte) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, ids[root])
+		return "0xtx", nil
+	}
+
+	p := New(Config{QueueSize: 4, Workers: 3}, sign, submit)
+	p.Start()
+	for _, root := range []string{"slow", "fast-a", "fast-b"} {
+		if err := p.Publish(context.Background(), Batch{ID: ids[root], Root: root}); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", root, err)
+		}
+	}
+	p.Close()
+
+	for i, id := range order {
+		if id != uint64(i+1) {
+			t.Fatalf("batches submitted out of order: %v", order)
+		}
+	}
+}
+
+func TestPublishAfterCloseFails(t *testing.T) {
+	p := New(Config{QueueSize: 1, Workers: 1},
+		func(string) ([]byte, error) { return nil, nil },
+		func(string, []byte, []byte) (string, error) { return "", nil })
+	p.Start()
+	p.Close()
+
+	if err := p.TryPublish(Batch{Root: "late"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestPipelineReportsStages(t *testing.T) {
+	var signMu sync.Mutex
+	signFailures := 1
+	sign := func(root string) ([]byte, error) {
+		signMu.Lock()
+		defer signMu.Unlock()
+		if root == "bad" && signFailures > 0 {
+			signFailures--
+			return nil, errors.New("no quorum")
+		}
+		return []byte("sig"), nil
+	}
+	submit := func(root string, fills []byte, aggSig []byte) (string, error) {
+		if root == "settled" {
+			return "", nil
+		}
+		return "0xtx_" + root, nil
+	}
+
+	var mu sync.Mutex
+	var stages []string
+	p := New(Config{QueueSize: 4, Workers: 1, RetryBackoff: time.Millisecond}, sign, submit)
+	p.OnStage(func(b Batch, stage Stage, txHash string) {
+		mu.Lock()
+		defer mu.Unlock()
+		stages = append(stages, fmt.Sprintf("%d:%s:%s", b.ID, stage, txHash))
+	})
+	p.Start()
+	for i, root := range []string{"aa", "bad", "settled"} {
+		if err := p.Publish(context.Background(), Batch{ID: uint64(i + 1), Root: root}); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", root, err)
+		}
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(stages) == 6
+	})
+	p.Close()
+
+	want := []string{"1:signed:", "1:submitted:0xtx_aa", "2:signed:", "2:submitted:0xtx_bad", "3:signed:", "3:submitted:"}
+	if !reflect.DeepEqual(stages, want) {
+		t.Fatalf("stages %v, want %v", stages, want)
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPipelineHoldsLaterBatchesBehindFailedSubmission(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []string
+	failures := 3
+	submit := func(root string, fills []byte, aggSig []byte) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if root == "first" && failures > 0 {
+			failures--
+			attempts = append(attempts, root+":failed")
+			return "", errors.New("connection refused")
+		}
+		attempts = append(attempts, root)
+		return "0xtx_" + root, nil
+	}
+
+	p := New(Config{QueueSize: 4, Workers: 2, RetryBackoff: time.Millisecond},
+		func(string) ([]byte, error) { return []byte("sig"), nil }, submit)
+	p.Start()
+	for i, root := range []string{"first", "second"} {
+		if err := p.Publish(context.Background(), Batch{ID: uint64(i + 1), Root: root}); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", root, err)
+		}
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(attempts) == 5
+	})
+	p.Close()
+
+	// The second batch only lands after the first, so it keeps on-chain ID 2
+	want := []string{"first:failed", "first:failed", "first:failed", "first", "second"}
+	if !reflect.DeepEqual(attempts, want) {
+		t.Fatalf("submission attempts %v, want %v", attempts, want)
+	}
+}
+
+func TestPipelineClosedWithFailedBatchSubmitsNoLaterBatch(t *testing.T) {
+	var mu sync.Mutex
+	var submitted []string
+	failing := make(chan struct{}, 1)
+	submit := func(root string, fills []byte, aggSig []byte) (string, error) {
+		if root == "first" {
+			select {
+			case failing <- struct{}{}:
+			default:
+			}
+			return "", errors.New("connection refused")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		submitted = append(submitted, root)
+		return "0xtx_" + root, nil
+	}
+
+	p := New(Config{QueueSize: 4, Workers: 2, RetryBackoff: time.Millisecond},
+		func(string) ([]byte, error) { return []byte("sig"), nil }, submit)
+	p.Start()
+	for i, root := range []string{"first", "second", "third"} {
+		if err := p.Publish(context.Background(), Batch{ID: uint64(i + 1), Root: root}); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", root, err)
+		}
+	}
+	<-failing
+	p.Close()
+
+	if len(submitted) != 0 {
+		t.Fatalf("batches %v submitted after an earlier batch failed", submitted)
+	}
+}
+
+func TestPipelineParksPermanentFailureAndHoldsLaterBatches(t *testing.T) {
+	errPermanent := errors.New("invalid BLS signature")
+	var mu sync.Mutex
+	var attempts []string
+	submit := func(root string, fills []byte, aggSig []byte) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, root)
+		if root == "bad" {
+			return "", fmt.Errorf("batch submission failed: %w", errPermanent)
+		}
+		return "0xtx_" + root, nil
+	}
+
+	var parked []string
+	var stages []string
+	p := New(Config{QueueSize: 1, Workers: 2, RetryBackoff: time.Millisecond},
+		func(string) ([]byte, error) { return []byte("sig"), nil }, submit)
+	p.RetryIf(func(err error) bool { return !errors.Is(err, errPermanent) })
+	p.OnPark(func(b Batch, aggSig []byte, n int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		parked = append(parked, fmt.Sprintf("%d:%s:%d", b.ID, aggSig, n))
+	})
+	p.OnStage(func(b Batch, stage Stage, detail string) {
+		mu.Lock()
+		defer mu.Unlock()
+		stages = append(stages, fmt.Sprintf("%d:%s:%s", b.ID, stage, detail))
+	})
+	p.Start()
+
+	// Batches behind the parked one are drained without being submitted, so
+	// the queue never stays full
+	for i, root := range []string{"aa", "bad", "cc", "dd", "ee"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := p.Publish(ctx, Batch{ID: uint64(i + 1), Root: root})
+		cancel()
+		if err != nil {
+			t.Fatalf("Publish(%s) failed: %v", root, err)
+		}
+	}
+	p.Close()
+
+	if want := []string{"aa", "bad"}; !reflect.DeepEqual(attempts, want) {
+		t.Fatalf("submission attempts %v, want %v", attempts, want)
+	}
+	if want := []string{"2:sig:1"}; !reflect.DeepEqual(parked, want) {
+		t.Fatalf("parked %v, want %v", parked, want)
+	}
+	// Held batches are reported by whichever worker dequeued them, in any order
+	sort.Strings(stages[4:])
+	want := []string{"1:signed:", "1:submitted:0xtx_aa", "2:signed:",
+		"2:failed:batch submission failed: invalid BLS signature", "3:held:", "4:held:", "5:held:"}
+	if !reflect.DeepEqual(stages, want) {
+		t.Fatalf("stages %v, want %v", stages, want)
+	}
+}
+
+func TestPipelineRetriesTransientErrorsButNotReverts(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{"transient RPC error", errors.New("connection refused"), 3},
+		{"permanent revert", fmt.Errorf("transaction 0xabc reverted: %w", contracts.ErrInsufficientQuorum), 1},
+		{"undecoded revert", fmt.Errorf("failed to estimate gas: %w", contracts.ErrReverted), 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := 0
+			submit := func(root string, fills []byte, aggSig []byte) (string, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				if attempts < 3 {
+					return "", tt.err
+				}
+				return "0xtx", nil
+			}
+			parked := make(chan struct{}, 1)
+			p := New(Config{QueueSize: 1, Workers: 1, RetryBackoff: time.Millisecond},
+				func(string) ([]byte, error) { return []byte("sig"), nil }, submit)
+			p.RetryIf(func(err error) bool { return !contracts.IsPermanent(err) })
+			p.OnPark(func(Batch, []byte, int, error) { parked <- struct{}{} })
+			submitted := make(chan struct{}, 1)
+			p.OnStage(func(b Batch, stage Stage, detail string) {
+				if stage == StageSubmitted {
+					submitted <- struct{}{}
+				}
+			})
+			p.Start()
+			if err := p.Publish(context.Background(), Batch{ID: 1, Root: "aa"}); err != nil {
+				t.Fatalf("Publish failed: %v", err)
+			}
+			select {
+			case <-submitted:
+			case <-parked:
+			case <-time.After(time.Second):
+				t.Fatalf("batch neither submitted nor parked")
+			}
+			p.Close()
+
+			if attempts != tt.attempts {
+				t.Fatalf("%d submission attempts, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Action is the kind of request a limit applies to
+type Action string
+
+const (
+	ActionPlace  Action = "place"
+	ActionCancel Action = "cancel"
+)
+
+// Scope is what a request is counted against
+type Scope string
+
+const (
+	ScopeAPIKey Scope = "key"
+	ScopeMaker  Scope = "maker"
+	ScopeIP     Scope = "ip"
+)
+
+// Limit is a token bucket that refills at Rate tokens per second up to Burst
+// tokens. A zero Rate disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limits holds a limit per scope
+type Limits struct {
+	APIKey Limit
+	Maker  Limit
+	IP     Limit
+}
+
+// Config holds the rate limits of order placement and cancellation
+type Config struct {
+	Place  Limits
+	Cancel Limits
+}
+
+// LoadConfig reads the rate limits from environment variables named
+// RATE_LIMIT_<ACTION>_<SCOPE>_PER_S and RATE_LIMIT_<ACTION>_<SCOPE>_BURST
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		Place: Limits{
+			APIKey: Limit{Rate: 50, Burst: 100},
+			Maker:  Limit{Rate: 50, Burst: 100},
+			IP:     Limit{Rate: 100, Burst: 200},
+		},
+		Cancel: Limits{
+			APIKey: Limit{Rate: 100, Burst: 200},
+			Maker:  Limit{Rate: 100, Burst: 200},
+			IP:     Limit{Rate: 200, Burst: 400},
+		},
+	}
+
+	for _, l := range []struct {
+		name  string
+		limit *Limit
+	}{
+		{"PLACE_KEY", &cfg.Place.APIKey},
+		{"PLACE_MAKER", &cfg.Place.Maker},
+		{"PLACE_IP", &cfg.Place.IP},
+		{"CANCEL_KEY", &cfg.Cancel.APIKey},
+		{"CANCEL_MAKER", &cfg.Cancel.Maker},
+		{"CANCEL_IP", &cfg.Cancel.IP},
+	} {
+		if v := os.Getenv("RATE_LIMIT_" + l.name + "_PER_S"); v != "" {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || !(n >= 0) || math.IsInf(n, 0) {
+				return cfg, fmt.Errorf("invalid RATE_LIMIT_%s_PER_S: %s (must be non-negative number)", l.name, v)
+			}
+			l.limit.Rate = n
+		}
+		if v := os.Getenv("RATE_LIMIT_" + l.name + "_BURST"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return cfg, fmt.Errorf("invalid RATE_LIMIT_%s_BURST: %s (must be positive integer)", l.name, v)
+			}
+			l.limit.Burst = n
+		}
+	}
+
+	return cfg, nil
+}
+
+// Result is the state of a bucket after a request was counted against it
+type Result struct {
+	Allowed    bool
+	Limit      int           // bucket capacity; 0 if the request was not limited
+	Remaining  int           // whole tokens left
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, if the request was denied
+}
+
+// Tighter returns whichever of r and o leaves the client less room: a denial,
+// or else the fewer remaining tokens
+func (r Result) Tighter(o Result) Result {
+	switch {
+	case r.Limit == 0:
+		return o
+	case o.Limit == 0:
+		return r
+	case r.Allowed != o.Allowed:
+		if !r.Allowed {
+			return r
+		}
+		return o
+	case o.Remaining < r.Remaining:
+		return o
+	}
+	return r
+}
+
+// bucket is the token count of one key at the time it was last updated
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter applies one Limit to each key separately
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter creates a limiter for limit
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket if one is available
+func (l *Limiter) Allow(key string) Result {
+	if l.limit.Rate <= 0 {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	capacity := float64(l.limit.Burst)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*l.limit.Rate)
+	}
+	b.last = now
+
+	res := Result{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.refill(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = l.refill(capacity - b.tokens)
+	return res
+}
+
+// refill returns how long the bucket takes to gain tokens
+func (l *Limiter) refill(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.limit.Rate * float64(time.Second)))
+}
+
+// sweep drops buckets that have refilled completely, at most once per full
+// refill period, so idle keys do not accumulate; the caller must hold l.mu
+func (l *Limiter) sweep(now time.Time) {
+	period := l.refill(float64(l.limit.Burst))
+	if now.Sub(l.lastSweep) < period {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= period {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Limiters holds a Limiter per action and scope
+type Limiters struct {
+	limiters map[Action]map[Scope]*Limiter
+}
+
+// New creates the limiters of cfg
+func New(cfg Config) *Limiters {
+	limiters := func(l Limits) map[Scope]*Limiter {
+		return map[Scope]*Limiter{
+			ScopeAPIKey: NewLimiter(l.APIKey),
+			ScopeMaker:  NewLimiter(l.Maker),
+			ScopeIP:     NewLimiter(l.IP),
+		}
+	}
+	return &Limiters{limiters: map[Action]map[Scope]*Limiter{
+		ActionPlace:  limiters(cfg.Place),
+		ActionCancel: limiters(cfg.Cancel),
+	}}
+}
+
+// Allow counts a request against key's bucket for action and scope. Maker
+// addresses are compared case-insensitively.
+func (l *Limiters) Allow(action Action, scope Scope, key string) Result {
+	if scope == ScopeMaker {
+		key = strings.ToLower(key)
+	}
+	return l.limiters[action][scope].Allow(key)
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterRefills(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := NewLimiter(Limit{Rate: 2, Burst: 3})
+	l.now = func() time.Time { return now }
+
+	for i := 2; i >= 0; i-- {
+		if res := l.Allow("a"); !res.Allowed || res.Remaining != i || res.Limit != 3 {
+			t.Fatalf("request %d: got %+v, want allowed with %d remaining", 3-i, res, i)
+		}
+	}
+	res := l.Allow("a")
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond || res.Reset != 1500*time.Millisecond {
+		t.Fatalf("exhausted bucket: got %+v", res)
+	}
+
+	// Other keys have their own bucket
+	if res := l.Allow("b"); !res.Allowed {
+		t.Fatalf("other key denied: %+v", res)
+	}
+
+	// Half a second refills one token
+	now = now.Add(500 * time.Millisecond)
+	if res := l.Allow("a"); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after refill: got %+v", res)
+	}
+	if res := l.Allow("a"); res.Allowed {
+		t.Fatalf("refilled more than one token: %+v", res)
+	}
+
+	// Buckets never hold more than the burst, and idle ones are dropped
+	now = now.Add(time.Hour)
+	if res := l.Allow("a"); !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("after idling: got %+v", res)
+	}
+	if _, ok := l.buckets["b"]; ok {
+		t.Fatalf("idle bucket was not swept")
+	}
+}
+
+func TestLimitersScopes(t *testing.T) {
+	l := New(Config{
+		Place:  Limits{Maker: Limit{Rate: 1, Burst: 1}},
+		Cancel: Limits{Maker: Limit{Rate: 1, Burst: 2}},
+	})
+
+	if res := l.Allow(ActionPlace, ScopeMaker, "0xABC"); !res.Allowed {
+		t.Fatalf("first placement denied: %+v", res)
+	}
+	if res := l.Allow(ActionPlace, ScopeMaker, "0xabc"); res.Allowed {
+		t.Fatalf("maker limit is case-sensitive: %+v", res)
+	}
+
+	// Cancels are limited separately, and zero limits are disabled
+	if res := l.Allow(ActionCancel, ScopeMaker, "0xabc"); !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("cancel: got %+v", res)
+	}
+	if res := l.Allow(ActionPlace, ScopeIP, "127.0.0.1"); !res.Allowed || res.Limit != 0 {
+		t.Fatalf("disabled limit: got %+v", res)
+	}
+}
+
+func TestResultTighter(t *testing.T) {
+	open := Result{Allowed: true, Limit: 10, Remaining: 9}
+	low := Result{Allowed: true, Limit: 100, Remaining: 3}
+	denied := Result{Limit: 100, RetryAfter: time.Second}
+	unlimited := Result{Allowed: true}
+
+	for _, tt := range []struct {
+		name string
+		a, b Result
+		want Result
+	}{
+		{"fewer remaining", open, low, low},
+		{"denial wins", low, denied, denied},
+		{"denial wins in either order", denied, open, denied},
+		{"unlimited is ignored", unlimited, open, open},
+		{"unlimited on the right", open, unlimited, open},
+	} {
+		if got := tt.a.Tighter(tt.b); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/sequencer"
+)
+
+func main() {
+	action := flag.String("action", "run", "Action to perform: export or run")
+	inputsPath := flag.String("inputs", "", "Input log to replay (run) or write (export); defaults to stdin/stdout")
+	flag.Parse()
+
+	switch *action {
+	case "export":
+		exportInputs(*inputsPath)
+	case "run":
+		runInputs(*inputsPath)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", *action)
+		fmt.Fprintln(os.Stderr, "Available actions: export, run")
+		os.Exit(1)
+	}
+}
+
+// exportInputs writes the canonical input log held by the WAL in WAL_DIR.
+// Run it while the sequencer is stopped.
+func exportInputs(path string) {
+	cfg, err := sequencer.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid WAL configuration: %v", err)
+	}
+
+	seq, err := sequencer.Open(cfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to open WAL: %v", err)
+	}
+	defer seq.Close()
+
+	out := os.Stdout
+	if path != "" {
+		out, err = os.Create(path)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", path, err)
+		}
+		defer out.Close()
+	}
+
+	if err := seq.ExportInputs(out); err != nil {
+		log.Fatalf("Failed to export inputs: %v", err)
+	}
+}
+
+// runInputs re-executes an input log and prints the resulting fills, batch
+// roots and order book as JSON
+func runInputs(path string) {
+	in := os.Stdin
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", path, err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	inputs, err := sequencer.ReadInputs(in)
+	if err != nil {
+		log.Fatalf("Failed to read inputs: %v", err)
+	}
+
+	cfg, err := sequencer.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid sequencer configuration: %v", err)
+	}
+
+	result, err := sequencer.ReplayInputs(inputs, cfg.MaxFillsPerMatch, cfg.Markets)
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		log.Fatalf("Failed to encode result: %v", err)
+	}
+}
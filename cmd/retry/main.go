@@ -13,6 +13,7 @@ import (
 func main() {
 	action := flag.String("action", "status", "Action to perform: status, retry, or clear")
 	flag.Parse()
+	submitter.Init()
 
 	fmt.Println("=================================================================")
 	fmt.Println("              POLYMARKET CLOB - FAILED BATCH MANAGER")
@@ -51,6 +52,19 @@ func showStatus() {
 	for i, batch := range batches {
 		fmt.Printf("%d. Root: %s\n", i+1, batch.Root)
 		fmt.Printf("   Attempts: %d\n", batch.Attempts)
+		if batch.LastError != "" {
+			fmt.Printf("   Last Error: %s\n", batch.LastError)
+		}
+		if batch.Permanent {
+			fmt.Printf("   Permanent: yes (skipped by retry)\n")
+		}
+		if submitted, blockNumber, err := submitter.IsBatchSubmitted(batch.Root); err != nil {
+			fmt.Printf("   On-chain: unknown (%v)\n", err)
+		} else if submitted {
+			fmt.Printf("   On-chain: settled in block %d (will be cleared on retry)\n", blockNumber)
+		} else {
+			fmt.Printf("   On-chain: not submitted\n")
+		}
 		fmt.Printf("   Timestamp: %v\n", batch.Timestamp.Format("2006-01-02 15:04:05"))
 		fmt.Printf("   Fills Size: %d bytes\n", len(batch.Fills))
 		fmt.Printf("   Signature Size: %d bytes\n\n", len(batch.Sig))
@@ -0,0 +1,484 @@
+package sequencer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+)
+
+// InputType identifies the kind of a sequenced input
+type InputType string
+
+const (
+	InputOrder    InputType = "order"     // a new order
+	InputCancel   InputType = "cancel"    // cancellation of a resting order
+	InputBatch    InputType = "batch"     // a batch cut from the oldest unbatched fills
+	InputRecovery InputType = "recovery"  // a restart that requeued fills of unsettled batches
+	InputTickSize InputType = "tick_size" // a tick size change that cancels resting orders off the new tick
+	InputNonce    InputType = "nonce"     // an on-chain nonce increment that cancels the maker's orders below it
+	InputExpire   InputType = "expire"    // a sweep that removes orders expired at the input's time
+	InputSettle   InputType = "settle"    // batches up to SettledBatchID confirmed on chain, so their fills stop being pending
+)
+
+// Input is one entry of the canonical input log. Seq and Time are assigned by
+// the sequencer when the input is accepted; applying the same inputs in Seq
+// order from the same state always produces the same fills and batch roots.
+type Input struct {
+	Seq            uint64         `json:"seq"`
+	Time           int64          `json:"time"`
+	Type           InputType      `json:"type"`
+	Order          *matcher.Order `json:"order,omitempty"`
+	OrderHash      string         `json:"orderHash,omitempty"`
+	BatchID        uint64         `json:"batchId,omitempty"`
+	Fills          int            `json:"fills,omitempty"`
+	SettledBatchID uint64         `json:"settledBatchId,omitempty"`
+	Asset          string         `json:"asset,omitempty"`
+	TickSize       float64        `json:"tickSize,omitempty"`
+	Maker          string         `json:"maker,omitempty"`
+	Nonce          uint64         `json:"nonce,omitempty"`
+}
+
+// Output is the result of applying an input
+type Output struct {
+	Events    []matcher.Event
+	Fills     []matcher.Fill
+	Batch     *pipeline.Batch
+	Cancelled []matcher.Order // orders removed from the book without filling; each also has an event
+	Expired   []matcher.Order // orders removed from the book because they expired; each also has an event
+}
+
+// Engine is the sequencer state machine: the order book plus the fills that
+// still need settling. It has no clock and no I/O, so it can be re-executed
+// by anyone holding the input log; order expiry is judged by the sequencer
+// timestamp of each input.
+type Engine struct {
+	book     []matcher.Order
+	pending  pendingFills
+	lastSeq  uint64
+	maxFills int
+	markets  matcher.Markets
+	ticks    map[string]float64       // tick sizes changed by inputs, by asset
+	nonces   map[string]uint64        // current nonce of each maker, by lowercase address
+	hashes   map[string]AcceptedOrder // accepted orders by hash, so replays are rejected
+	expiry   int64                    // earliest expiration among hashes, or 0 if none expire
+}
+
+// AcceptedOrder is what the engine remembers of an accepted order to reject
+// its replay. It is forgotten once the order has expired or its maker's nonce
+// has passed it, since a replay is then rejected for that reason instead.
+type AcceptedOrder struct {
+	Hash       string
+	Maker      string
+	Nonce      uint64
+	Expiration int64
+}
+
+// NewEngine creates an engine with an empty book that charges fees at the rates of markets
+func NewEngine(maxFillsPerMatch int, markets matcher.Markets) *Engine {
+	return &Engine{
+		book:     make([]matcher.Order, 0),
+		maxFills: maxFillsPerMatch,
+		markets:  markets,
+		ticks:    make(map[string]float64),
+		nonces:   make(map[string]uint64),
+		hashes:   make(map[string]AcceptedOrder),
+	}
+}
+
+// LastSeq returns the sequence number of the last applied input
+func (e *Engine) LastSeq() uint64 {
+	return e.lastSeq
+}
+
+// Book returns a copy of the order book
+func (e *Engine) Book() []matcher.Order {
+	book := make([]matcher.Order, len(e.book))
+	copy(book, e.book)
+	return book
+}
+
+// Markets returns the market parameters in force, including tick size changes
+func (e *Engine) Markets() matcher.Markets {
+	return e.markets
+}
+
+// Validate checks an order against the current rules of its market, its
+// maker's nonce and the orders accepted before it
+func (e *Engine) Validate(o matcher.Order) error {
+	if err := e.markets.CheckOrder(o); err != nil {
+		return err
+	}
+	if current := e.Nonce(o.Maker); o.Nonce < current {
+		return fmt.Errorf("%w: order nonce %d is below %s's nonce %d", ErrInvalidNonce, o.Nonce, o.Maker, current)
+	}
+	hash := o.Hash
+	if hash == "" {
+		hash = matcher.OrderHash(o)
+	}
+	if _, ok := e.hashes[hash]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateOrder, hash)
+	}
+	return nil
+}
+
+// Nonce returns the current nonce of maker; orders below it are void
+func (e *Engine) Nonce(maker string) uint64 {
+	return e.nonces[strings.ToLower(maker)]
+}
+
+// HasOrder reports whether an order with the given hash is resting on the book
+func (e *Engine) HasOrder(hash string) bool {
+	return indexOf(e.book, hash) >= 0
+}
+
+// OpenOrders returns how many orders maker has resting in the market of asset
+func (e *Engine) OpenOrders(maker, asset string) int {
+	n := 0
+	for _, o := range e.book {
+		if o.TakerAsset == asset && strings.EqualFold(o.Maker, maker) {
+			n++
+		}
+	}
+	return n
+}
+
+// Expiring reports whether any resting order or remembered order hash is
+// expired at now, in unix milliseconds
+func (e *Engine) Expiring(now int64) bool {
+	if e.hashExpired(now) {
+		return true
+	}
+	for _, o := range e.book {
+		if o.Expired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply applies the next input, which must carry sequence number LastSeq()+1.
+// Orders expired at the input's time leave the book before an order input is
+// matched, so they are never filled.
+func (e *Engine) Apply(in Input) (Output, error) {
+	var out Output
+	if in.Seq != e.lastSeq+1 {
+		return out, fmt.Errorf("input seq %d out of order, expected %d", in.Seq, e.lastSeq+1)
+	}
+
+	switch in.Type {
+	case InputOrder:
+		if in.Order == nil {
+			return out, fmt.Errorf("input %d: order input without order", in.Seq)
+		}
+		o := *in.Order
+		o.Seq = in.Seq
+		if o.Hash == "" {
+			o.Hash = matcher.OrderHash(o)
+		}
+		e.remember(o)
+		e.expire(in.Time, &out)
+		if o.Expired(in.Time) {
+			out.Expired = append(out.Expired, o)
+			out.Events = append(out.Events, matcher.Event{Type: matcher.EventOrderExpired, Order: o})
+			break
+		}
+		var events []matcher.Event
+		if o.IsMarket() {
+			e.book, events = matcher.ExecuteMarket(e.book, o, e.maxFills, e.markets)
+		} else {
+			e.book, events = matcher.Execute(e.book, o, e.maxFills, e.markets)
+		}
+		out.Events = append(out.Events, events...)
+		out.Fills = matcher.Fills(events)
+		e.pending.add(out.Fills)
+
+	case InputCancel:
+		i := indexOf(e.book, in.OrderHash)
+		if i < 0 {
+			return out, fmt.Errorf("input %d: %w: %s", in.Seq, ErrOrderNotFound, in.OrderHash)
+		}
+		out.Cancelled = []matcher.Order{e.book[i]}
+		e.book = removeOrder(e.book, in.OrderHash)
+
+	case InputBatch:
+		fills, err := e.pending.cut(in.BatchID, in.Fills)
+		if err != nil {
+			return out, fmt.Errorf("input %d: %w", in.Seq, err)
+		}
+		root, fillsBytes, err := matcher.BuildBatch(fills)
+		if err != nil {
+			return out, fmt.Errorf("input %d: %w", in.Seq, err)
+		}
+		out.Batch = &pipeline.Batch{ID: in.BatchID, Root: root, Fills: fillsBytes}
+
+	case InputRecovery:
+		e.pending.requeue(in.SettledBatchID)
+
+	case InputTickSize:
+		if err := matcher.ValidTickSize(in.TickSize); err != nil {
+			return out, fmt.Errorf("input %d: %w", in.Seq, err)
+		}
+		out.Cancelled = e.setTickSize(in.Asset, in.TickSize)
+
+	case InputNonce:
+		if in.Maker == "" {
+			return out, fmt.Errorf("input %d: nonce input without maker", in.Seq)
+		}
+		out.Cancelled = e.setNonce(in.Maker, in.Nonce)
+
+	case InputExpire:
+		e.expire(in.Time, &out)
+
+	case InputSettle:
+		e.pending.settle(in.SettledBatchID)
+
+	default:
+		return out, fmt.Errorf("input %d: unknown input type %q", in.Seq, in.Type)
+	}
+
+	for _, o := range out.Cancelled {
+		out.Events = append(out.Events, matcher.Event{Type: matcher.EventOrderCancelled, Order: o})
+	}
+
+	e.lastSeq = in.Seq
+	return out, nil
+}
+
+// remember records an accepted order's hash so that its replay is rejected
+func (e *Engine) remember(o matcher.Order) {
+	e.hashes[o.Hash] = AcceptedOrder{Hash: o.Hash, Maker: o.Maker, Nonce: o.Nonce, Expiration: o.Expiration}
+	if o.Expiration != 0 && (e.expiry == 0 || o.Expiration < e.expiry) {
+		e.expiry = o.Expiration
+	}
+}
+
+// hashExpired reports whether a remembered order hash is expired at now
+func (e *Engine) hashExpired(now int64) bool {
+	return matcher.Order{Expiration: e.expiry}.Expired(now)
+}
+
+// forget drops the remembered hashes matching drop and recomputes the
+// earliest expiration among the rest
+func (e *Engine) forget(drop func(AcceptedOrder) bool) {
+	e.expiry = 0
+	for hash, a := range e.hashes {
+		if drop(a) {
+			delete(e.hashes, hash)
+			continue
+		}
+		if a.Expiration != 0 && (e.expiry == 0 || a.Expiration < e.expiry) {
+			e.expiry = a.Expiration
+		}
+	}
+}
+
+// expire removes the orders expired at now from the book and adds them and
+// their events to out, and forgets the hashes of expired orders
+func (e *Engine) expire(now int64, out *Output) {
+	if e.hashExpired(now) {
+		e.forget(func(a AcceptedOrder) bool {
+			return matcher.Order{Expiration: a.Expiration}.Expired(now)
+		})
+	}
+
+	var events []matcher.Event
+	e.book, events = matcher.Expire(e.book, now)
+	for _, ev := range events {
+		out.Expired = append(out.Expired, ev.Order)
+	}
+	out.Events = append(out.Events, events...)
+}
+
+// setTickSize changes the tick size of a market and removes the resting
+// orders whose price is no longer on the tick, returning them
+func (e *Engine) setTickSize(asset string, tick float64) []matcher.Order {
+	e.markets = e.markets.WithTickSize(asset, tick)
+	e.ticks[asset] = tick
+
+	var cancelled []matcher.Order
+	kept := e.book[:0]
+	for _, o := range e.book {
+		if o.TakerAsset == asset && !matcher.OnTick(o.Price, tick) {
+			cancelled = append(cancelled, o)
+			continue
+		}
+		kept = append(kept, o)
+	}
+	e.book = kept
+	return cancelled
+}
+
+// setNonce raises the nonce of maker and removes the maker's resting orders
+// below it, returning them, and forgets the hashes of the maker's orders below
+// it. A nonce at or below the current one changes nothing.
+func (e *Engine) setNonce(maker string, nonce uint64) []matcher.Order {
+	key := strings.ToLower(maker)
+	if nonce <= e.nonces[key] {
+		return nil
+	}
+	e.nonces[key] = nonce
+	e.forget(func(a AcceptedOrder) bool {
+		return a.Nonce < nonce && strings.EqualFold(a.Maker, maker)
+	})
+
+	var cancelled []matcher.Order
+	kept := e.book[:0]
+	for _, o := range e.book {
+		if o.Nonce < nonce && strings.EqualFold(o.Maker, maker) {
+			cancelled = append(cancelled, o)
+			continue
+		}
+		kept = append(kept, o)
+	}
+	e.book = kept
+	return cancelled
+}
+
+// ReplayResult is the outcome of re-executing an input log
+type ReplayResult struct {
+	Book    []matcher.Order `json:"book"`
+	Fills   []matcher.Fill  `json:"fills"`
+	Batches []ReplayedBatch `json:"batches"`
+}
+
+// ReplayedBatch is a batch rebuilt from the input log, with the exact fills payload that is signed and submitted
+type ReplayedBatch struct {
+	BatchID uint64          `json:"batchId"`
+	Root    string          `json:"root"`
+	Fills   json.RawMessage `json:"fills"`
+}
+
+// ReplayInputs re-executes a complete input log, starting at seq 1, from an
+// empty book. Fees depend on markets, so replay with the markets the log was
+// recorded under.
+func ReplayInputs(inputs []Input, maxFillsPerMatch int, markets matcher.Markets) (ReplayResult, error) {
+	var result ReplayResult
+	e := NewEngine(maxFillsPerMatch, markets)
+	for _, in := range inputs {
+		out, err := e.Apply(in)
+		if err != nil {
+			return result, err
+		}
+		result.Fills = append(result.Fills, out.Fills...)
+		if out.Batch != nil {
+			result.Batches = append(result.Batches, ReplayedBatch{
+				BatchID: out.Batch.ID,
+				Root:    out.Batch.Root,
+				Fills:   out.Batch.Fills,
+			})
+		}
+	}
+	result.Book = e.Book()
+	return result, nil
+}
+
+// ReadInputs parses an input log written as one JSON input per line
+func ReadInputs(r io.Reader) ([]Input, error) {
+	var inputs []Input
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var in Input
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("line %d: invalid input: %w", line, err)
+		}
+		inputs = append(inputs, in)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input log: %w", err)
+	}
+	return inputs, nil
+}
+
+// pendingFills tracks which fills still need settling
+type pendingFills struct {
+	unbatched      []matcher.Fill
+	batches        []PendingBatch
+	lastBatchID    uint64
+	settledBatchID uint64
+}
+
+// add queues newly matched fills
+func (p *pendingFills) add(fills []matcher.Fill) {
+	p.unbatched = append(p.unbatched, fills...)
+}
+
+// cut moves the oldest n unbatched fills into a batch and returns them
+func (p *pendingFills) cut(batchID uint64, n int) ([]matcher.Fill, error) {
+	if n < 1 || n > len(p.unbatched) {
+		return nil, fmt.Errorf("batch %d has %d fills but %d are unbatched", batchID, n, len(p.unbatched))
+	}
+	fills := p.unbatched[:n:n]
+	p.batches = append(p.batches, PendingBatch{ID: batchID, Fills: fills})
+	p.unbatched = p.unbatched[n:]
+	p.lastBatchID = batchID
+	return fills, nil
+}
+
+// settle drops batches with IDs up to settledBatchID
+func (p *pendingFills) settle(settledBatchID uint64) {
+	if settledBatchID < p.settledBatchID {
+		return
+	}
+	kept := p.batches[:0]
+	for _, b := range p.batches {
+		if b.ID > settledBatchID {
+			kept = append(kept, b)
+		}
+	}
+	p.batches = kept
+	p.settledBatchID = settledBatchID
+}
+
+// requeue drops settled batches and moves fills of unsettled ones back in front of the unbatched fills
+func (p *pendingFills) requeue(settledBatchID uint64) {
+	var requeued []matcher.Fill
+	for _, b := range p.batches {
+		if b.ID > settledBatchID {
+			requeued = append(requeued, b.Fills...)
+		}
+	}
+	p.unbatched = append(requeued, p.unbatched...)
+	p.batches = nil
+	p.settledBatchID = settledBatchID
+}
+
+// indexOf returns the position of the order with the given hash, or -1
+func indexOf(book []matcher.Order, hash string) int {
+	for i, o := range book {
+		if o.Hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeOrder returns the book without the order with the given hash
+func removeOrder(book []matcher.Order, hash string) []matcher.Order {
+	i := indexOf(book, hash)
+	if i < 0 {
+		return book
+	}
+	return append(book[:i], book[i+1:]...)
+}
+
+// sameFills reports whether two fill lists are identical
+func sameFills(a, b []matcher.Fill) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
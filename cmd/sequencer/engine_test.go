@@ -0,0 +1,226 @@
+package sequencer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+)
+
+var update = flag.Bool("update", false, "rewrite golden replay results in testdata")
+
+// TestReplayRecordedInputs re-executes recorded input logs and checks the
+// fills, batch roots and final book byte for byte against the golden results
+func TestReplayRecordedInputs(t *testing.T) {
+	logs, _ := filepath.Glob(filepath.Join("testdata", "*.inputs.jsonl"))
+	if len(logs) == 0 {
+		t.Fatalf("no recorded input logs in testdata")
+	}
+
+	for _, path := range logs {
+		name := strings.TrimSuffix(filepath.Base(path), ".inputs.jsonl")
+		t.Run(name, func(t *testing.T) {
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("failed to open input log: %v", err)
+			}
+			defer file.Close()
+
+			inputs, err := ReadInputs(file)
+			if err != nil {
+				t.Fatalf("ReadInputs failed: %v", err)
+			}
+
+			got := replayJSON(t, inputs)
+			if again := replayJSON(t, inputs); !bytes.Equal(got, again) {
+				t.Fatalf("replaying the same inputs twice gave different results")
+			}
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("failed to write golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("replay of %s diverges from %s:\n%s", path, golden, got)
+			}
+		})
+	}
+}
+
+func replayJSON(t *testing.T, inputs []Input) []byte {
+	t.Helper()
+	result, err := ReplayInputs(inputs, 100, matcher.Markets{})
+	if err != nil {
+		t.Fatalf("ReplayInputs failed: %v", err)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode replay result: %v", err)
+	}
+	return append(data, '\n')
+}
+
+// batchingSink cuts every fill it receives into its own batch through the sequencer
+type batchingSink struct {
+	s       *Sequencer
+	nextID  uint64
+	batches []pipeline.Batch
+}
+
+func (b *batchingSink) Add(fills []matcher.Fill) ([]uint64, error) {
+	root, fillsBytes, err := matcher.BuildBatch(fills)
+	if err != nil {
+		return nil, err
+	}
+	b.nextID++
+	batch := pipeline.Batch{ID: b.nextID, Root: root, Fills: fillsBytes}
+	b.batches = append(b.batches, batch)
+	ids := make([]uint64, len(fills))
+	for i := range ids {
+		ids[i] = batch.ID
+	}
+	return ids, b.s.RecordBatch(batch, fills)
+}
+
+func TestExportedInputsReplayToLiveBatches(t *testing.T) {
+	s, err := Open(testConfig(t.TempDir()), nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	sink := &batchingSink{s: s}
+	s.SetSink(sink)
+
+	bid, err := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.60, "10", 1))
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	for i, o := range []matcher.Order{
+		testOrder("0xbbbbbbbbbb", matcher.SideSell, 0.50, "4", 2),
+		testOrder("0xcccccccccc", matcher.SideSell, 0.55, "3", 3),
+	} {
+		if _, err := s.PlaceOrder(o); err != nil {
+			t.Fatalf("PlaceOrder %d failed: %v", i, err)
+		}
+	}
+	if err := s.CancelOrder(bid.Order.Hash); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	if _, err := s.PlaceOrder(testOrder("0xdddddddddd", matcher.SideBuy, 0.20, "2", 4)); err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(sink.batches) < 2 {
+		t.Fatalf("expected several batches, got %d", len(sink.batches))
+	}
+
+	var buf bytes.Buffer
+	if err := s.ExportInputs(&buf); err != nil {
+		t.Fatalf("ExportInputs failed: %v", err)
+	}
+	inputs, err := ReadInputs(&buf)
+	if err != nil {
+		t.Fatalf("ReadInputs failed: %v", err)
+	}
+	for i, in := range inputs {
+		if in.Seq != uint64(i+1) || in.Time == 0 {
+			t.Fatalf("input %d has seq %d and time %d", i, in.Seq, in.Time)
+		}
+	}
+
+	result, err := ReplayInputs(inputs, 100, matcher.Markets{})
+	if err != nil {
+		t.Fatalf("ReplayInputs failed: %v", err)
+	}
+	if len(result.Batches) != len(sink.batches) {
+		t.Fatalf("replayed %d batches, want %d", len(result.Batches), len(sink.batches))
+	}
+	for i, b := range sink.batches {
+		got := result.Batches[i]
+		if got.BatchID != b.ID || got.Root != b.Root || !bytes.Equal(got.Fills, b.Fills) {
+			t.Fatalf("replayed batch %+v, want %+v", got, b)
+		}
+	}
+	if !reflect.DeepEqual(result.Book, s.Orders()) {
+		t.Fatalf("replayed book %+v, want %+v", result.Book, s.Orders())
+	}
+}
+
+func TestEngineRejectsOutOfOrderInputs(t *testing.T) {
+	e := NewEngine(100, matcher.Markets{})
+	o := testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.5, "1", 1)
+	if _, err := e.Apply(Input{Seq: 2, Type: InputOrder, Order: &o}); err == nil {
+		t.Fatalf("expected a gap in sequence numbers to be rejected")
+	}
+	if _, err := e.Apply(Input{Seq: 1, Type: InputOrder, Order: &o}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, err := e.Apply(Input{Seq: 1, Type: InputOrder, Order: &o}); err == nil {
+		t.Fatalf("expected a repeated sequence number to be rejected")
+	}
+}
+
+func TestEngineForgetsHashesOfExpiredAndVoidOrders(t *testing.T) {
+	e := NewEngine(0, matcher.Markets{})
+	seq := uint64(0)
+	apply := func(in Input) {
+		t.Helper()
+		seq++
+		in.Seq = seq
+		if _, err := e.Apply(in); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	}
+	place := func(o matcher.Order, now int64) matcher.Order {
+		t.Helper()
+		o.Hash = matcher.OrderHash(o)
+		apply(Input{Type: InputOrder, Time: now, Order: &o})
+		return o
+	}
+
+	expiring := testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.5, "1", 1)
+	expiring.Expiration = 1_700_000_010
+	expiring = place(expiring, 1_700_000_000_000)
+	stale := place(testOrder("0xbbbbbbbbbb", matcher.SideBuy, 0.5, "1", 2), 1_700_000_000_000)
+	forever := place(testOrder("0xcccccccccc", matcher.SideBuy, 0.5, "1", 3), 1_700_000_000_000)
+	for _, o := range []matcher.Order{expiring, stale, forever} {
+		if err := e.Validate(o); !errors.Is(err, ErrDuplicateOrder) {
+			t.Fatalf("replayed order = %v, want ErrDuplicateOrder", err)
+		}
+	}
+
+	if e.Expiring(1_700_000_010_000) || !e.Expiring(1_700_000_011_000) {
+		t.Fatalf("expected the expiring order to be due exactly after its expiration")
+	}
+	apply(Input{Type: InputExpire, Time: 1_700_000_011_000})
+	apply(Input{Type: InputNonce, Maker: "0xBBBBBBBBBB", Nonce: 1})
+
+	// A snapshot carries exactly the hashes still remembered
+	e = newSnapshot(0, e).engine(0, matcher.Markets{})
+	var remembered []string
+	for _, a := range newSnapshot(0, e).Accepted {
+		remembered = append(remembered, a.Hash)
+	}
+	if !reflect.DeepEqual(remembered, []string{forever.Hash}) {
+		t.Fatalf("remembered %v, want only the order that neither expires nor was voided", remembered)
+	}
+	if e.Expiring(1_800_000_000_000) {
+		t.Fatalf("expected no hash left to expire")
+	}
+}
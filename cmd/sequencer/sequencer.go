@@ -0,0 +1,753 @@
+package sequencer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/wal"
+)
+
+// WAL record types written by the sequencer. Order, cancel, batch, recovery,
+// tick size, nonce, expiry and settle records hold an Input; match records
+// hold the resulting fills.
+const (
+	RecordOrder    wal.RecordType = 1 // an accepted order
+	RecordCancel   wal.RecordType = 2 // a cancelled order
+	RecordMatch    wal.RecordType = 3 // fills produced by matching an order
+	RecordBatch    wal.RecordType = 4 // a batch cut from the oldest unbatched fills
+	RecordRecovery wal.RecordType = 5 // a restart that requeued fills of unsettled batches
+	RecordTickSize wal.RecordType = 6 // a tick size change
+	RecordNonce    wal.RecordType = 7 // a maker nonce increment
+	RecordExpire   wal.RecordType = 8 // a sweep of expired orders
+	RecordSettle   wal.RecordType = 9 // batches confirmed settled on chain
+)
+
+// recordTypes maps each input type to the WAL record that stores it
+var recordTypes = map[InputType]wal.RecordType{
+	InputOrder:    RecordOrder,
+	InputCancel:   RecordCancel,
+	InputBatch:    RecordBatch,
+	InputRecovery: RecordRecovery,
+	InputTickSize: RecordTickSize,
+	InputNonce:    RecordNonce,
+	InputExpire:   RecordExpire,
+	InputSettle:   RecordSettle,
+}
+
+// Errors returned when placing or cancelling orders
+var (
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrTooManyOrders  = errors.New("too many open orders")
+	ErrInvalidNonce   = errors.New("order nonce is no longer valid")
+	ErrDuplicateOrder = errors.New("order already accepted")
+	ErrOrderExpired   = errors.New("order expired")
+)
+
+// matchEntry is the payload of a RecordMatch
+type matchEntry struct {
+	Seq       uint64         `json:"seq"`
+	OrderHash string         `json:"orderHash"`
+	Fills     []matcher.Fill `json:"fills"`
+}
+
+// FillSink receives fills produced by matching, typically the batcher. Add
+// returns the ID of the batch each fill was assigned to.
+type FillSink interface {
+	Add(fills []matcher.Fill) ([]uint64, error)
+}
+
+// Placement is the outcome of placing an order
+type Placement struct {
+	Order matcher.Order // the order with its hash and sequence number
+	matcher.OrderResult
+	Fills    []matcher.Fill // every fill matching the order produced
+	BatchIDs []uint64       // the batch each fill was assigned to, parallel to Fills; empty without a sink
+}
+
+// TradeFunc observes fills as they are matched, and again when they are replayed on recovery
+type TradeFunc func(taker matcher.Order, fills []matcher.Fill, at time.Time)
+
+// BookFunc observes the order book after every sequenced order, cancel, tick
+// size change, nonce increment and expiry sweep. markets are the assets whose
+// orders the input added, filled or removed, sorted. It runs while the engine is locked, so books arrive in
+// sequence order; it must not call back into the sequencer.
+type BookFunc func(seq uint64, book []matcher.Order, markets []string)
+
+// Config holds the sequencer configuration
+type Config struct {
+	WAL              wal.Options
+	MaxFillsPerMatch int
+	SnapshotDir      string
+	SnapshotInterval time.Duration
+	SnapshotRetain   int
+	Markets          matcher.Markets
+	MaxOpenOrders    int // per maker per market; 0 is unlimited
+	SweepInterval    time.Duration
+}
+
+// LoadConfig reads the sequencer and WAL configuration from environment variables
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		WAL: wal.Options{
+			Dir:          "data/wal",
+			SegmentSize:  64 << 20,
+			Sync:         wal.SyncAlways,
+			SyncInterval: 100 * time.Millisecond,
+		},
+		MaxFillsPerMatch: 100,
+		SnapshotDir:      "data/snapshots",
+		SnapshotInterval: 60 * time.Second,
+		SnapshotRetain:   2,
+		Markets:          matcher.Markets{Default: matcher.MarketParams{TickSize: 0.01}},
+		MaxOpenOrders:    500,
+		SweepInterval:    time.Second,
+	}
+
+	if v := os.Getenv("WAL_DIR"); v != "" {
+		cfg.WAL.Dir = v
+	}
+
+	if v := os.Getenv("WAL_SEGMENT_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid WAL_SEGMENT_BYTES: %s (must be positive integer)", v)
+		}
+		cfg.WAL.SegmentSize = n
+	}
+
+	if v := os.Getenv("WAL_SYNC"); v != "" {
+		policy, err := wal.ParseSyncPolicy(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid WAL_SYNC: %w", err)
+		}
+		cfg.WAL.Sync = policy
+	}
+
+	if v := os.Getenv("WAL_SYNC_INTERVAL_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid WAL_SYNC_INTERVAL_MS: %s (must be positive integer)", v)
+		}
+		cfg.WAL.SyncInterval = time.Duration(n) * time.Millisecond
+	}
+
+	if v := os.Getenv("SNAPSHOT_DIR"); v != "" {
+		cfg.SnapshotDir = v
+	}
+
+	if v := os.Getenv("SNAPSHOT_INTERVAL_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid SNAPSHOT_INTERVAL_MS: %s (must be positive integer)", v)
+		}
+		cfg.SnapshotInterval = time.Duration(n) * time.Millisecond
+	}
+
+	if v := os.Getenv("SNAPSHOT_RETAIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid SNAPSHOT_RETAIN: %s (must be positive integer)", v)
+		}
+		cfg.SnapshotRetain = n
+	}
+
+	if v := os.Getenv("MARKETS_FILE"); v != "" {
+		data, err := os.ReadFile(v)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read MARKETS_FILE: %w", err)
+		}
+		if err := json.Unmarshal(data, &cfg.Markets.Assets); err != nil {
+			return cfg, fmt.Errorf("invalid MARKETS_FILE %s: %w", v, err)
+		}
+	}
+
+	if v := os.Getenv("TICK_SIZE"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			err = matcher.ValidTickSize(n)
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("invalid TICK_SIZE: %s (must be between 0 and 1 exclusive)", v)
+		}
+		cfg.Markets.Default.TickSize = n
+	}
+
+	if v := os.Getenv("MIN_ORDER_SIZE"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(n >= 0) || math.IsInf(n, 0) {
+			return cfg, fmt.Errorf("invalid MIN_ORDER_SIZE: %s (must be non-negative number)", v)
+		}
+		cfg.Markets.Default.MinSize = n
+	}
+
+	if v := os.Getenv("MAKER_FEE_BPS"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n > 10000 {
+			return cfg, fmt.Errorf("invalid MAKER_FEE_BPS: %s (must be 0-10000)", v)
+		}
+		cfg.Markets.Default.MakerFeeBps = n
+	}
+
+	if v := os.Getenv("TAKER_FEE_BPS"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n > 10000 {
+			return cfg, fmt.Errorf("invalid TAKER_FEE_BPS: %s (must be 0-10000)", v)
+		}
+		cfg.Markets.Default.TakerFeeBps = n
+	}
+
+	if v := os.Getenv("MAX_OPEN_ORDERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid MAX_OPEN_ORDERS: %s (must be non-negative integer)", v)
+		}
+		cfg.MaxOpenOrders = n
+	}
+
+	if v := os.Getenv("EXPIRY_SWEEP_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid EXPIRY_SWEEP_MS: %s (must be positive integer)", v)
+		}
+		cfg.SweepInterval = time.Duration(n) * time.Millisecond
+	}
+
+	return cfg, nil
+}
+
+// Sequencer assigns every input a sequence number and timestamp, logs it to
+// the WAL and applies it to the engine before acknowledging it
+type Sequencer struct {
+	cfg Config
+	log *wal.Log
+
+	// mu serializes order entry so fills reach the sink in sequence order
+	mu       sync.Mutex
+	sink     FillSink
+	onTrade  TradeFunc
+	observer matcher.Observer
+
+	// stateMu guards the engine and WAL appends; RecordBatch takes it without holding mu
+	stateMu      sync.Mutex
+	engine       *Engine
+	onBook       BookFunc
+	lastSnapshot uint64
+	now          func() time.Time
+}
+
+// Open opens the WAL; call Recover before accepting orders
+func Open(cfg Config, onTrade TradeFunc) (*Sequencer, error) {
+	walLog, err := wal.Open(cfg.WAL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+
+	if onTrade == nil {
+		onTrade = func(matcher.Order, []matcher.Fill, time.Time) {}
+	}
+
+	return &Sequencer{
+		cfg:      cfg,
+		log:      walLog,
+		onTrade:  onTrade,
+		observer: matcher.LogObserver,
+		engine:   NewEngine(cfg.MaxFillsPerMatch, cfg.Markets),
+		now:      time.Now,
+	}, nil
+}
+
+// SetObserver sets the observer that receives matching events of live orders.
+// Events replayed during Recover are not observed.
+func (s *Sequencer) SetObserver(o matcher.Observer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observer = o
+}
+
+// OnBook registers a hook that observes the book after each input that
+// changes it. Inputs replayed during Recover are not observed.
+func (s *Sequencer) OnBook(fn BookFunc) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.onBook = fn
+}
+
+// SetSink sets the destination for matched fills
+func (s *Sequencer) SetSink(sink FillSink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sink = sink
+}
+
+// Close closes the WAL
+func (s *Sequencer) Close() error {
+	return s.log.Close()
+}
+
+// PlaceOrder sequences an order, adds it to the book and matches it. The
+// placed order carries its hash, which identifies it for cancellation, and
+// its sequence number.
+func (s *Sequencer) PlaceOrder(o matcher.Order) (Placement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o.Seq = 0
+	o.Hash = matcher.OrderHash(o)
+	p := Placement{Order: o}
+
+	s.stateMu.Lock()
+	if err := s.engine.Validate(o); err != nil {
+		s.stateMu.Unlock()
+		return p, err
+	}
+	if o.Expired(s.now().UnixMilli()) {
+		s.stateMu.Unlock()
+		return p, fmt.Errorf("%w: expiration %d has passed", ErrOrderExpired, o.Expiration)
+	}
+	if max := s.cfg.MaxOpenOrders; max > 0 && !o.IsMarket() && s.engine.OpenOrders(o.Maker, o.TakerAsset) >= max {
+		s.stateMu.Unlock()
+		return p, fmt.Errorf("%w: %s has %d orders resting in market %s", ErrTooManyOrders, o.Maker, max, o.TakerAsset)
+	}
+	in, out, err := s.sequence(Input{Type: InputOrder, Order: &o})
+	if err == nil && len(out.Fills) > 0 {
+		err = s.append(RecordMatch, matchEntry{Seq: in.Seq, OrderHash: o.Hash, Fills: out.Fills})
+	}
+	p.OrderResult = matcher.Summarize(o, out.Events, s.engine.book)
+	size := len(s.engine.book)
+	s.stateMu.Unlock()
+	if err != nil {
+		return p, err
+	}
+
+	p.Order.Seq = in.Seq
+	p.Fills = out.Fills
+	for _, e := range out.Events {
+		s.observer.Observe(e)
+	}
+	log.Printf("Order %d added to orderbook. Total orders: %d", in.Seq, size)
+
+	if len(out.Fills) == 0 {
+		return p, nil
+	}
+
+	// Trades are stamped with the sequencer timestamp of the taker order
+	s.onTrade(p.Order, out.Fills, time.UnixMilli(in.Time))
+
+	if s.sink != nil {
+		ids, err := s.sink.Add(out.Fills)
+		if err != nil {
+			log.Printf("Error adding fills to batch: %v", err)
+		}
+		p.BatchIDs = ids
+	}
+
+	return p, nil
+}
+
+// CancelOrder sequences a cancel and removes the order from the book
+func (s *Sequencer) CancelOrder(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stateMu.Lock()
+	if !s.engine.HasOrder(hash) {
+		s.stateMu.Unlock()
+		return ErrOrderNotFound
+	}
+	in, out, err := s.sequence(Input{Type: InputCancel, OrderHash: hash})
+	size := len(s.engine.book)
+	s.stateMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range out.Events {
+		s.observer.Observe(e)
+	}
+	log.Printf("Order %s cancelled (seq %d). Total orders: %d", hash, in.Seq, size)
+	return nil
+}
+
+// SetTickSize sequences a tick size change for the market of asset. Resting
+// orders of that market whose price is not on the new tick are cancelled and
+// returned.
+func (s *Sequencer) SetTickSize(asset string, tick float64) ([]matcher.Order, error) {
+	if err := matcher.ValidTickSize(tick); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stateMu.Lock()
+	in, out, err := s.sequence(Input{Type: InputTickSize, Asset: asset, TickSize: tick})
+	size := len(s.engine.book)
+	s.stateMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range out.Events {
+		s.observer.Observe(e)
+	}
+	log.Printf("Tick size of %s set to %v (seq %d). Cancelled orders: %d, Total orders: %d",
+		asset, tick, in.Seq, len(out.Cancelled), size)
+	return out.Cancelled, nil
+}
+
+// SetNonce sequences an increment of maker's on-chain nonce. The maker's
+// resting orders with a lower nonce are cancelled and returned. A nonce at or
+// below the one already known is ignored and not logged.
+func (s *Sequencer) SetNonce(maker string, nonce uint64) ([]matcher.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stateMu.Lock()
+	if nonce <= s.engine.Nonce(maker) {
+		s.stateMu.Unlock()
+		return nil, nil
+	}
+	in, out, err := s.sequence(Input{Type: InputNonce, Maker: maker, Nonce: nonce})
+	size := len(s.engine.book)
+	s.stateMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range out.Events {
+		s.observer.Observe(e)
+	}
+	log.Printf("Nonce of %s raised to %d (seq %d). Cancelled orders: %d, Total orders: %d",
+		maker, nonce, in.Seq, len(out.Cancelled), size)
+	return out.Cancelled, nil
+}
+
+// SweepExpired sequences an expiry sweep if any resting order is past its
+// expiration, and returns the orders it removed. Without expired orders
+// nothing is logged.
+func (s *Sequencer) SweepExpired() ([]matcher.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stateMu.Lock()
+	if !s.engine.Expiring(s.now().UnixMilli()) {
+		s.stateMu.Unlock()
+		return nil, nil
+	}
+	in, out, err := s.sequence(Input{Type: InputExpire})
+	size := len(s.engine.book)
+	s.stateMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range out.Events {
+		s.observer.Observe(e)
+	}
+	log.Printf("Expiry sweep (seq %d). Expired orders: %d, Total orders: %d", in.Seq, len(out.Expired), size)
+	return out.Expired, nil
+}
+
+// RunSweeper sweeps expired orders off the book every SweepInterval until ctx is cancelled
+func (s *Sequencer) RunSweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SweepExpired(); err != nil {
+				log.Printf("Error sweeping expired orders: %v", err)
+			}
+		}
+	}
+}
+
+// Nonce returns the last nonce of maker the sequencer knows of
+func (s *Sequencer) Nonce(maker string) uint64 {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.engine.Nonce(maker)
+}
+
+// Markets returns the market parameters in force, including tick size changes
+func (s *Sequencer) Markets() matcher.Markets {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.engine.Markets()
+}
+
+// RecordBatch sequences a batch cut from the oldest unbatched fills. It is
+// the batcher's cut hook and runs before the batch is published.
+func (s *Sequencer) RecordBatch(b pipeline.Batch, fills []matcher.Fill) error {
+	// Does not take s.mu: the batcher calls this while PlaceOrder holds it
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	_, out, err := s.sequence(Input{Type: InputBatch, BatchID: b.ID, Fills: len(fills)})
+	if err != nil {
+		return err
+	}
+	if out.Batch.Root != b.Root {
+		log.Printf("⚠️  Batch %d root %s differs from sequenced root %s", b.ID, b.Root, out.Batch.Root)
+	}
+	return nil
+}
+
+// Orders returns a copy of the order book
+func (s *Sequencer) Orders() []matcher.Order {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.engine.Book()
+}
+
+// Order returns the resting order with the given hash
+func (s *Sequencer) Order(hash string) (matcher.Order, bool) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if i := indexOf(s.engine.book, hash); i >= 0 {
+		return s.engine.book[i], true
+	}
+	return matcher.Order{}, false
+}
+
+// LastSeq returns the sequence number of the last accepted input
+func (s *Sequencer) LastSeq() uint64 {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.engine.LastSeq()
+}
+
+// sequence stamps an input with the next sequence number and the sequencer
+// clock, logs it and applies it to the engine; the caller must hold s.stateMu
+func (s *Sequencer) sequence(in Input) (Input, Output, error) {
+	in.Seq = s.engine.LastSeq() + 1
+	in.Time = s.now().UnixMilli()
+
+	if err := s.append(recordTypes[in.Type], in); err != nil {
+		return in, Output{}, err
+	}
+
+	out, err := s.engine.Apply(in)
+	if err != nil {
+		// The input is already durable; replay will hit the same error
+		return in, out, fmt.Errorf("failed to apply input %d: %w", in.Seq, err)
+	}
+
+	if s.onBook != nil && in.Type != InputBatch && in.Type != InputRecovery && in.Type != InputSettle {
+		s.onBook(in.Seq, s.engine.Book(), touchedMarkets(out.Events))
+	}
+	return in, out, nil
+}
+
+// touchedMarkets returns the sorted assets of the orders in events
+func touchedMarkets(events []matcher.Event) []string {
+	seen := make(map[string]bool)
+	var markets []string
+	for _, e := range events {
+		for _, asset := range []string{e.Order.TakerAsset, e.Bid.TakerAsset, e.Ask.TakerAsset} {
+			if asset != "" && !seen[asset] {
+				seen[asset] = true
+				markets = append(markets, asset)
+			}
+		}
+	}
+	sort.Strings(markets)
+	return markets
+}
+
+// Recover rebuilds the engine from the latest valid snapshot and the WAL
+// records after it. It returns, in order, the fills that still need to be
+// settled: fills never cut into a batch plus fills of batches with IDs above
+// settledBatchID.
+func (s *Sequencer) Recover(settledBatchID uint64) ([]matcher.Fill, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	start := time.Now()
+	from := uint64(1)
+
+	snap, ok, err := loadLatestSnapshot(s.cfg.SnapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if snap.Index > s.log.LastIndex() {
+			return nil, fmt.Errorf("snapshot at WAL index %d is ahead of the WAL (last index %d)", snap.Index, s.log.LastIndex())
+		}
+		s.engine = snap.engine(s.cfg.MaxFillsPerMatch, s.cfg.Markets)
+		s.lastSnapshot = snap.Index
+		from = snap.Index + 1
+		log.Printf("Loaded snapshot at WAL index %d - Seq: %d, Book size: %d, Unbatched fills: %d, Pending batches: %d",
+			snap.Index, snap.LastSeq, len(snap.Book), len(snap.Unbatched), len(snap.Batches))
+	}
+
+	if first := s.log.FirstIndex(); from < first {
+		return nil, fmt.Errorf("WAL starts at index %d but replay needs index %d; no usable snapshot covers the gap", first, from)
+	}
+
+	var last Input
+	var replayed []matcher.Fill
+	var inputs, matches int
+
+	err = s.log.Replay(from, func(rec wal.Record) error {
+		if rec.Type == RecordMatch {
+			var m matchEntry
+			if err := json.Unmarshal(rec.Data, &m); err != nil {
+				return fmt.Errorf("record %d: invalid match: %w", rec.Index, err)
+			}
+			if m.Seq != last.Seq || last.Order == nil {
+				return fmt.Errorf("record %d: match for seq %d does not follow its order", rec.Index, m.Seq)
+			}
+			if !sameFills(replayed, m.Fills) {
+				log.Printf("⚠️  WAL record %d: replayed match for order %d diverges from logged fills", rec.Index, m.Seq)
+			}
+			order := *last.Order
+			order.Seq = last.Seq
+			s.onTrade(order, replayed, time.UnixMilli(last.Time))
+			matches++
+			return nil
+		}
+
+		var in Input
+		if err := json.Unmarshal(rec.Data, &in); err != nil {
+			return fmt.Errorf("record %d: invalid input: %w", rec.Index, err)
+		}
+		if recordTypes[in.Type] != rec.Type {
+			return fmt.Errorf("record %d: %q input in record of type %d", rec.Index, in.Type, rec.Type)
+		}
+		out, err := s.engine.Apply(in)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", rec.Index, err)
+		}
+		last, replayed = in, out.Fills
+		inputs++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay WAL: %w", err)
+	}
+
+	if _, _, err := s.sequence(Input{Type: InputRecovery, SettledBatchID: settledBatchID}); err != nil {
+		return nil, err
+	}
+
+	unsettled := append([]matcher.Fill(nil), s.engine.pending.unbatched...)
+	log.Printf("Recovered from WAL in %v - Replayed from index: %d, Inputs: %d, Matches: %d, Seq: %d, Book size: %d, Unsettled fills: %d",
+		time.Since(start), from, inputs, matches, s.engine.LastSeq(), len(s.engine.book), len(unsettled))
+
+	return unsettled, nil
+}
+
+// ExportInputs writes the inputs held by the WAL as a canonical input log,
+// one JSON input per line in sequence order
+func (s *Sequencer) ExportInputs(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	return s.log.Replay(s.log.FirstIndex(), func(rec wal.Record) error {
+		if rec.Type == RecordMatch {
+			return nil
+		}
+		var in Input
+		if err := json.Unmarshal(rec.Data, &in); err != nil {
+			return fmt.Errorf("record %d: invalid input: %w", rec.Index, err)
+		}
+		return enc.Encode(in)
+	})
+}
+
+// WriteSnapshot writes a snapshot of the current state, drops snapshots beyond
+// the retention limit and deletes WAL segments no retained snapshot needs.
+// Batches with IDs up to settledBatchID are left out of the snapshot; if
+// that settles batches not yet known to be, a settle input is sequenced first.
+func (s *Sequencer) WriteSnapshot(settledBatchID uint64) error {
+	s.stateMu.Lock()
+	if settledBatchID > s.engine.pending.settledBatchID {
+		if _, _, err := s.sequence(Input{Type: InputSettle, SettledBatchID: settledBatchID}); err != nil {
+			s.stateMu.Unlock()
+			return err
+		}
+	}
+	index := s.log.LastIndex()
+	if index == s.lastSnapshot {
+		s.stateMu.Unlock()
+		return nil
+	}
+	snap := newSnapshot(index, s.engine)
+	s.lastSnapshot = index
+	s.stateMu.Unlock()
+
+	// Encoding and writing happen outside the lock so orders keep flowing
+	path, err := writeSnapshot(s.cfg.SnapshotDir, snap)
+	if err != nil {
+		return err
+	}
+
+	oldest, err := pruneSnapshots(s.cfg.SnapshotDir, s.cfg.SnapshotRetain)
+	if err != nil {
+		return err
+	}
+	removed, err := s.log.TruncateFront(oldest + 1)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("📸 Snapshot written to %s - WAL index: %d, Seq: %d, Book size: %d, Pending batches: %d, WAL segments removed: %d",
+		path, snap.Index, snap.LastSeq, len(snap.Book), len(snap.Batches), removed)
+	return nil
+}
+
+// RunSnapshots writes a snapshot every SnapshotInterval until ctx is cancelled.
+// settled reports the number of batches settled on chain.
+func (s *Sequencer) RunSnapshots(ctx context.Context, settled func() (uint64, error)) {
+	ticker := time.NewTicker(s.cfg.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.stateMu.Lock()
+			settledID := s.engine.pending.settledBatchID
+			s.stateMu.Unlock()
+
+			if n, err := settled(); err != nil {
+				log.Printf("Warning: Could not read settled batch count for snapshot: %v", err)
+			} else {
+				settledID = n
+			}
+
+			if err := s.WriteSnapshot(settledID); err != nil {
+				log.Printf("Error writing snapshot: %v", err)
+			}
+		}
+	}
+}
+
+// append encodes a payload as JSON and writes it to the WAL
+func (s *Sequencer) append(typ wal.RecordType, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode WAL record: %w", err)
+	}
+	if _, err := s.log.Append(typ, data); err != nil {
+		return fmt.Errorf("failed to write WAL record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,572 @@
+package sequencer
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/pipeline"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/wal"
+)
+
+func testConfig(dir string) Config {
+	return Config{
+		WAL:              wal.Options{Dir: dir, Sync: wal.SyncNever},
+		MaxFillsPerMatch: 100,
+	}
+}
+
+func testOrder(maker string, side matcher.Side, price float64, amount string, ts int64) matcher.Order {
+	return matcher.Order{
+		Maker:      maker,
+		TakerAsset: "0xasset",
+		MakeAmount: amount,
+		TakeAmount: amount,
+		Price:      price,
+		Timestamp:  ts,
+		Side:       side,
+		Signature:  "0xsig",
+	}
+}
+
+// fillSink records fills handed to it
+type fillSink struct {
+	fills []matcher.Fill
+}
+
+func (f *fillSink) Add(fills []matcher.Fill) ([]uint64, error) {
+	f.fills = append(f.fills, fills...)
+	return nil, nil
+}
+
+// populate matches a crossing pair, cancels the partially filled bid and rests one more order
+func populate(t *testing.T, s *Sequencer) []matcher.Fill {
+	t.Helper()
+	sink := &fillSink{}
+	s.SetSink(sink)
+
+	place := func(o matcher.Order) matcher.Order {
+		p, err := s.PlaceOrder(o)
+		if err != nil {
+			t.Fatalf("PlaceOrder failed: %v", err)
+		}
+		return p.Order
+	}
+
+	bid := place(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.60, "10", 1))
+	place(testOrder("0xbbbbbbbbbb", matcher.SideSell, 0.50, "4", 2))
+	if len(sink.fills) == 0 {
+		t.Fatalf("expected the crossing orders to match")
+	}
+
+	if err := s.CancelOrder(bid.Hash); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	if err := s.CancelOrder(bid.Hash); err != ErrOrderNotFound {
+		t.Fatalf("second cancel = %v, want ErrOrderNotFound", err)
+	}
+
+	place(testOrder("0xcccccccccc", matcher.SideBuy, 0.30, "7", 3))
+	if len(s.Orders()) != 1 {
+		t.Fatalf("expected one resting order, got %+v", s.Orders())
+	}
+	return sink.fills
+}
+
+func TestRecoverRebuildsBookAndUnbatchedFills(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(testConfig(dir), nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover on empty WAL failed: %v", err)
+	}
+	fills := populate(t, s)
+	book := s.Orders()
+	s.Close()
+
+	var traded int
+	s, err = Open(testConfig(dir), func(taker matcher.Order, f []matcher.Fill, at time.Time) {
+		traded += len(f)
+	})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer s.Close()
+
+	unsettled, err := s.Recover(0)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if !reflect.DeepEqual(s.Orders(), book) {
+		t.Fatalf("recovered book %+v, want %+v", s.Orders(), book)
+	}
+	if !reflect.DeepEqual(unsettled, fills) {
+		t.Fatalf("unsettled fills %+v, want %+v", unsettled, fills)
+	}
+	if traded != len(fills) {
+		t.Errorf("replayed %d trades, want %d", traded, len(fills))
+	}
+}
+
+func TestRecoverDropsSettledBatches(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(testConfig(dir), nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	fills := populate(t, s)
+	if err := s.RecordBatch(pipeline.Batch{ID: 1, Root: "0xroot"}, fills); err != nil {
+		t.Fatalf("RecordBatch failed: %v", err)
+	}
+	s.Close()
+
+	// Batch 1 never settled: its fills are requeued on the first restart
+	s, _ = Open(testConfig(dir), nil)
+	unsettled, err := s.Recover(0)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if !reflect.DeepEqual(unsettled, fills) {
+		t.Fatalf("unsettled fills %+v, want %+v", unsettled, fills)
+	}
+	if err := s.RecordBatch(pipeline.Batch{ID: 1, Root: "0xroot"}, unsettled); err != nil {
+		t.Fatalf("RecordBatch failed: %v", err)
+	}
+	s.Close()
+
+	// Once it settles on chain nothing is left to submit
+	s, _ = Open(testConfig(dir), nil)
+	defer s.Close()
+	unsettled, err = s.Recover(1)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if len(unsettled) != 0 {
+		t.Fatalf("expected no unsettled fills, got %+v", unsettled)
+	}
+}
+
+func TestPlaceOrderChargesMarketFees(t *testing.T) {
+	cfg := testConfig(t.TempDir())
+	cfg.Markets = matcher.Markets{Default: matcher.MarketParams{MakerFeeBps: 20, TakerFeeBps: 100}}
+	s, err := Open(cfg, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	// An order that signs less than the taker rate is rejected before it is sequenced
+	last := s.LastSeq()
+	if _, err := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.60, "10", 1)); !errors.Is(err, matcher.ErrFeeRateTooLow) {
+		t.Fatalf("expected ErrFeeRateTooLow, got %v", err)
+	}
+	if s.LastSeq() != last {
+		t.Fatalf("rejected order was sequenced")
+	}
+
+	bid := testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.60, "10", 1)
+	bid.FeeRateBps = 100
+	ask := testOrder("0xbbbbbbbbbb", matcher.SideSell, 0.50, "10", 2)
+	ask.FeeRateBps = 100
+	s.PlaceOrder(bid)
+	placed, err := s.PlaceOrder(ask)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+
+	// The resting bid buys at 0.60 and pays the maker rate in tokens; the taker sells and pays in collateral
+	if fills := placed.Fills; len(fills) != 1 || fills[0].MakerFee != "0.01333333" || fills[0].TakerFee != "0.04000000" {
+		t.Fatalf("unexpected fees: %+v", fills)
+	}
+}
+
+func TestSetTickSizeCancelsOffTickOrders(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(filepath.Join(dir, "wal"))
+	cfg.SnapshotDir = filepath.Join(dir, "snapshots")
+	cfg.Markets = matcher.Markets{Default: matcher.MarketParams{TickSize: 0.01}}
+
+	s, _ := Open(cfg, nil)
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if _, err := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.555, "10", 1)); !errors.Is(err, matcher.ErrInvalidTick) {
+		t.Fatalf("expected ErrInvalidTick, got %v", err)
+	}
+	offTick, _ := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.55, "10", 1))
+
+	cancelled, err := s.SetTickSize("0xasset", 0.1)
+	if err != nil {
+		t.Fatalf("SetTickSize failed: %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0].Hash != offTick.Order.Hash || len(s.Orders()) != 0 {
+		t.Fatalf("expected the 0.55 order to be cancelled, got %+v", cancelled)
+	}
+	if _, err := s.PlaceOrder(testOrder("0xbbbbbbbbbb", matcher.SideBuy, 0.57, "10", 2)); !errors.Is(err, matcher.ErrInvalidTick) {
+		t.Fatalf("expected ErrInvalidTick on the new tick, got %v", err)
+	}
+	if _, err := s.PlaceOrder(testOrder("0xbbbbbbbbbb", matcher.SideBuy, 0.6, "10", 2)); err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if err := s.WriteSnapshot(0); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	s.Close()
+
+	// The tick size change is part of the recovered state
+	s, _ = Open(cfg, nil)
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if tick := s.Markets().Params("0xasset").TickSize; tick != 0.1 {
+		t.Fatalf("recovered tick size %v, want 0.1", tick)
+	}
+	if len(s.Orders()) != 1 {
+		t.Fatalf("expected 1 resting order after recovery, got %d", len(s.Orders()))
+	}
+}
+
+// batchIDSink assigns every fill to the same batch
+type batchIDSink struct {
+	id uint64
+}
+
+func (b batchIDSink) Add(fills []matcher.Fill) ([]uint64, error) {
+	ids := make([]uint64, len(fills))
+	for i := range ids {
+		ids[i] = b.id
+	}
+	return ids, nil
+}
+
+func TestPlaceOrderReportsFillResults(t *testing.T) {
+	s, _ := Open(testConfig(t.TempDir()), nil)
+	defer s.Close()
+	s.SetSink(batchIDSink{id: 7})
+
+	rest, err := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideSell, 0.40, "3", 1))
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if rest.Status != matcher.StatusLive || rest.Remaining != "3.00000000" || len(rest.Fills) != 0 {
+		t.Fatalf("unexpected placement of a resting order: %+v", rest)
+	}
+
+	taker, err := s.PlaceOrder(testOrder("0xbbbbbbbbbb", matcher.SideBuy, 0.60, "5", 2))
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if taker.Status != matcher.StatusPartiallyFilled || taker.Filled != "3.00000000" || taker.Remaining != "2.00000000" {
+		t.Fatalf("unexpected placement of a crossing order: %+v", taker)
+	}
+	if len(taker.Fills) != 1 || taker.Fills[0].Price != "0.40000000" || !reflect.DeepEqual(taker.BatchIDs, []uint64{7}) {
+		t.Fatalf("unexpected fills %+v in batches %v", taker.Fills, taker.BatchIDs)
+	}
+}
+
+func TestOnBookObservesBookChanges(t *testing.T) {
+	s, _ := Open(testConfig(t.TempDir()), nil)
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	var seqs []uint64
+	var sizes []int
+	var touched [][]string
+	s.OnBook(func(seq uint64, book []matcher.Order, markets []string) {
+		seqs = append(seqs, seq)
+		sizes = append(sizes, len(book))
+		touched = append(touched, markets)
+	})
+
+	// A resting order, a taker that fills it, and a resting order that is cancelled
+	first := s.LastSeq() + 1
+	var placed Placement
+	for i, o := range []matcher.Order{
+		testOrder("0xaaaaaaaaaa", matcher.SideSell, 0.4, "10", 1),
+		testOrder("0xbbbbbbbbbb", matcher.SideBuy, 0.5, "10", 2),
+		testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.3, "10", 3),
+	} {
+		var err error
+		if placed, err = s.PlaceOrder(o); err != nil {
+			t.Fatalf("PlaceOrder %d failed: %v", i, err)
+		}
+	}
+	if err := s.CancelOrder(placed.Order.Hash); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(seqs, []uint64{first, first + 1, first + 2, first + 3}) || !reflect.DeepEqual(sizes, []int{1, 0, 1, 0}) {
+		t.Fatalf("observed seqs %v with book sizes %v", seqs, sizes)
+	}
+	for i, markets := range touched {
+		if !reflect.DeepEqual(markets, []string{"0xasset"}) {
+			t.Fatalf("input %d touched markets %v, want [0xasset]", seqs[i], markets)
+		}
+	}
+
+	// A nonce increment of a maker without resting orders touches no market
+	touched = nil
+	if _, err := s.SetNonce("0xcccccccccc", 1); err != nil {
+		t.Fatalf("SetNonce failed: %v", err)
+	}
+	if len(touched) != 1 || len(touched[0]) != 0 {
+		t.Fatalf("nonce increment touched markets %v, want none", touched)
+	}
+}
+
+func TestCancelsAreObserved(t *testing.T) {
+	s, _ := Open(testConfig(t.TempDir()), nil)
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	var cancelled []string
+	s.SetObserver(matcher.ObserverFunc(func(e matcher.Event) {
+		if e.Type == matcher.EventOrderCancelled {
+			cancelled = append(cancelled, e.Order.Hash)
+		}
+	}))
+
+	first, _ := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.4, "10", 1))
+	if err := s.CancelOrder(first.Order.Hash); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	offTick, _ := s.PlaceOrder(testOrder("0xbbbbbbbbbb", matcher.SideBuy, 0.45, "10", 2))
+	if _, err := s.SetTickSize("0xasset", 0.1); err != nil {
+		t.Fatalf("SetTickSize failed: %v", err)
+	}
+
+	if want := []string{first.Order.Hash, offTick.Order.Hash}; !reflect.DeepEqual(cancelled, want) {
+		t.Fatalf("observed cancels %v, want %v", cancelled, want)
+	}
+}
+
+func TestPlaceOrderEnforcesOpenOrderQuota(t *testing.T) {
+	cfg := testConfig(t.TempDir())
+	cfg.MaxFillsPerMatch = 0 // keep every order resting
+	cfg.MaxOpenOrders = 2
+	s, _ := Open(cfg, nil)
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	first, err := s.PlaceOrder(testOrder("0xAAAAAAAAAA", matcher.SideBuy, 0.4, "10", 1))
+	if err != nil {
+		t.Fatalf("first order: %v", err)
+	}
+	if _, err := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.5, "10", 2)); err != nil {
+		t.Fatalf("second order: %v", err)
+	}
+	if _, err := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.6, "10", 3)); !errors.Is(err, ErrTooManyOrders) {
+		t.Fatalf("third order = %v, want ErrTooManyOrders", err)
+	}
+
+	// Other makers and other markets have their own quota
+	if _, err := s.PlaceOrder(testOrder("0xbbbbbbbbbb", matcher.SideBuy, 0.6, "10", 4)); err != nil {
+		t.Fatalf("other maker: %v", err)
+	}
+	other := testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.6, "10", 5)
+	other.TakerAsset = "0xother"
+	if _, err := s.PlaceOrder(other); err != nil {
+		t.Fatalf("other market: %v", err)
+	}
+
+	// Cancelling frees a slot
+	if err := s.CancelOrder(first.Order.Hash); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	if _, err := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.6, "10", 6)); err != nil {
+		t.Fatalf("order after cancel: %v", err)
+	}
+}
+
+func TestNoncesCancelAndRejectOrders(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.MaxFillsPerMatch = 0 // keep every order resting
+	s, _ := Open(cfg, nil)
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	nonced := func(maker string, nonce uint64, ts int64) matcher.Order {
+		o := testOrder(maker, matcher.SideBuy, 0.5, "10", ts)
+		o.Nonce = nonce
+		return o
+	}
+	stale, _ := s.PlaceOrder(nonced("0xAAAAAAAAAA", 0, 1))
+	current, _ := s.PlaceOrder(nonced("0xaaaaaaaaaa", 1, 2))
+	other, _ := s.PlaceOrder(nonced("0xbbbbbbbbbb", 0, 3))
+
+	// Replaying an accepted order is rejected even after it left the book
+	if _, err := s.PlaceOrder(nonced("0xaaaaaaaaaa", 1, 2)); !errors.Is(err, ErrDuplicateOrder) {
+		t.Fatalf("replayed order = %v, want ErrDuplicateOrder", err)
+	}
+
+	cancelled, err := s.SetNonce("0xaaaaaaaaaa", 1)
+	if err != nil {
+		t.Fatalf("SetNonce failed: %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0].Hash != stale.Order.Hash {
+		t.Fatalf("cancelled %+v, want only the order with nonce 0", cancelled)
+	}
+	if _, err := s.PlaceOrder(nonced("0xaaaaaaaaaa", 0, 4)); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("order below nonce = %v, want ErrInvalidNonce", err)
+	}
+	if cancelled, err := s.SetNonce("0xaaaaaaaaaa", 1); err != nil || cancelled != nil {
+		t.Fatalf("repeated SetNonce = %v, %v, want a no-op", cancelled, err)
+	}
+	s.Close()
+
+	// Nonces and accepted hashes survive a restart
+	s, _ = Open(cfg, nil)
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	var hashes []string
+	for _, o := range s.Orders() {
+		hashes = append(hashes, o.Hash)
+	}
+	if want := []string{current.Order.Hash, other.Order.Hash}; !reflect.DeepEqual(hashes, want) {
+		t.Fatalf("recovered book %v, want %v", hashes, want)
+	}
+	if n := s.Nonce("0xAAAAAAAAAA"); n != 1 {
+		t.Fatalf("recovered nonce %d, want 1", n)
+	}
+	if _, err := s.PlaceOrder(nonced("0xaaaaaaaaaa", 0, 1)); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("stale order after restart = %v, want ErrInvalidNonce", err)
+	}
+	if _, err := s.PlaceOrder(nonced("0xbbbbbbbbbb", 0, 3)); !errors.Is(err, ErrDuplicateOrder) {
+		t.Fatalf("replayed order after restart = %v, want ErrDuplicateOrder", err)
+	}
+}
+
+func TestExpiredOrdersAreSweptAndNeverFilled(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	s, _ := Open(cfg, nil)
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+	var expired []string
+	s.SetObserver(matcher.ObserverFunc(func(e matcher.Event) {
+		if e.Type == matcher.EventOrderExpired {
+			expired = append(expired, e.Order.Hash)
+		}
+	}))
+
+	expiring := func(maker string, price float64, ts, expiration int64) matcher.Order {
+		o := testOrder(maker, matcher.SideBuy, price, "5", ts)
+		o.Expiration = expiration
+		return o
+	}
+	bid, err := s.PlaceOrder(expiring("0xaaaaaaaaaa", 0.6, 1, 1_700_000_010))
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if _, err := s.PlaceOrder(expiring("0xaaaaaaaaaa", 0.6, 2, 1_699_999_999)); !errors.Is(err, ErrOrderExpired) {
+		t.Fatalf("expired order = %v, want ErrOrderExpired", err)
+	}
+
+	// A crossing ask after the bid expired does not fill against it
+	now = time.Unix(1_700_000_011, 0)
+	ask, err := s.PlaceOrder(testOrder("0xbbbbbbbbbb", matcher.SideSell, 0.5, "5", 3))
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if len(ask.Fills) != 0 || ask.Status != matcher.StatusLive {
+		t.Fatalf("ask filled against an expired bid: %+v", ask)
+	}
+	if !reflect.DeepEqual(expired, []string{bid.Order.Hash}) {
+		t.Fatalf("expired %v, want the bid", expired)
+	}
+
+	// The sweeper removes orders that expire while the book is idle
+	if err := s.CancelOrder(ask.Order.Hash); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	late, err := s.PlaceOrder(expiring("0xcccccccccc", 0.2, 4, 1_700_000_020))
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	if swept, err := s.SweepExpired(); err != nil || len(swept) != 0 {
+		t.Fatalf("early sweep = %+v, %v, want nothing", swept, err)
+	}
+	now = time.Unix(1_700_000_021, 0)
+	swept, err := s.SweepExpired()
+	if err != nil || len(swept) != 1 || swept[0].Hash != late.Order.Hash {
+		t.Fatalf("sweep = %+v, %v, want the late order", swept, err)
+	}
+	seq := s.LastSeq()
+	if swept, err := s.SweepExpired(); err != nil || swept != nil || s.LastSeq() != seq {
+		t.Fatalf("idle sweep = %+v, %v, logged seq %d", swept, err, s.LastSeq())
+	}
+	s.Close()
+
+	// Expiry sweeps are replayed from the WAL
+	s, _ = Open(cfg, nil)
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if book := s.Orders(); len(book) != 0 {
+		t.Fatalf("recovered book %+v, want it empty", book)
+	}
+}
+
+func TestMarketOrdersNeverRest(t *testing.T) {
+	cfg := testConfig(t.TempDir())
+	cfg.MaxOpenOrders = 1
+	s, _ := Open(cfg, nil)
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	bid, err := s.PlaceOrder(testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.6, "10", 1))
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	market := func(side matcher.Side, amount string, ts int64) matcher.Order {
+		o := testOrder("0xbbbbbbbbbb", side, 0, amount, ts)
+		o.TakeAmount, o.Type = "", matcher.OrderMarket
+		return o
+	}
+
+	sell, err := s.PlaceOrder(market(matcher.SideSell, "4", 2))
+	if err != nil {
+		t.Fatalf("market sell failed: %v", err)
+	}
+	if sell.Status != matcher.StatusFilled || len(sell.Fills) != 1 || sell.Fills[0].Price != "0.60000000" {
+		t.Fatalf("unexpected market sell %+v", sell)
+	}
+
+	// Nothing asks, so a market buy is cancelled outright; the open order
+	// quota does not count it
+	buy, err := s.PlaceOrder(market(matcher.SideBuy, "1", 3))
+	if err != nil {
+		t.Fatalf("market buy failed: %v", err)
+	}
+	if buy.Status != matcher.StatusCancelled || buy.Remaining != "1.00000000" || len(buy.Fills) != 0 {
+		t.Fatalf("unexpected market buy %+v", buy)
+	}
+	if book := s.Orders(); len(book) != 1 || book[0].Hash != bid.Order.Hash || book[0].MakeAmount != "6.00000000" {
+		t.Fatalf("unexpected book %+v", book)
+	}
+}
@@ -0,0 +1,550 @@
+package sequencer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+)
+
+// snapshotMagic identifies a snapshot file
+const snapshotMagic = "CLOBSNAP"
+
+// snapshotVersion is the current snapshot encoding; bump it when the layout changes
+const snapshotVersion uint16 = 9
+
+// Older snapshots are still read; fields added since are left empty
+const (
+	snapshotVersionFillPrice uint16 = 3 // fills carry their price and aggressor side
+	snapshotVersionFees      uint16 = 4 // orders carry feeRateBps; fills carry addresses and fees
+	snapshotVersionTickSizes uint16 = 5 // tick size changes are included
+	snapshotVersionOrderSide uint16 = 6 // orders carry their declared side
+	snapshotVersionNonces    uint16 = 7 // orders carry their nonce; maker nonces and accepted order hashes are included
+	snapshotVersionExpiry    uint16 = 8 // orders carry their expiration
+	snapshotVersionAccepted  uint16 = 9 // accepted order hashes carry their maker, nonce and expiration
+)
+
+// snapshotHeaderSize is magic, version, WAL index, payload length and CRC32C
+const snapshotHeaderSize = len(snapshotMagic) + 2 + 8 + 4 + 4
+
+// snapshotExt is the file extension of snapshots
+const snapshotExt = ".snap"
+
+var snapshotCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// errSnapshotInvalid is returned when a snapshot fails validation
+var errSnapshotInvalid = errors.New("invalid snapshot")
+
+// Snapshot is the sequencer state after applying every WAL record up to and including Index
+type Snapshot struct {
+	Index          uint64
+	LastSeq        uint64
+	LastBatchID    uint64
+	SettledBatchID uint64
+	Book           []matcher.Order
+	Unbatched      []matcher.Fill
+	Batches        []PendingBatch
+	TickSizes      map[string]float64 // tick sizes changed by inputs, by asset
+	Nonces         map[string]uint64  // maker nonces raised by inputs, by lowercase address
+	Accepted       []AcceptedOrder    // accepted orders whose replay is still rejected by hash, sorted by hash
+}
+
+// PendingBatch is a cut batch not known to have settled on chain
+type PendingBatch struct {
+	ID    uint64
+	Fills []matcher.Fill
+}
+
+// newSnapshot copies the state of an engine that has applied every WAL record up to index
+func newSnapshot(index uint64, e *Engine) Snapshot {
+	return Snapshot{
+		Index:          index,
+		LastSeq:        e.lastSeq,
+		LastBatchID:    e.pending.lastBatchID,
+		SettledBatchID: e.pending.settledBatchID,
+		Book:           e.Book(),
+		Unbatched:      append([]matcher.Fill(nil), e.pending.unbatched...),
+		Batches:        append([]PendingBatch(nil), e.pending.batches...),
+		TickSizes:      copyTickSizes(e.ticks),
+		Nonces:         copyNonces(e.nonces),
+		Accepted:       sortedAccepted(e.hashes),
+	}
+}
+
+// copyTickSizes copies a tick size map, returning nil for an empty one
+func copyTickSizes(ticks map[string]float64) map[string]float64 {
+	if len(ticks) == 0 {
+		return nil
+	}
+	out := make(map[string]float64, len(ticks))
+	for asset, tick := range ticks {
+		out[asset] = tick
+	}
+	return out
+}
+
+// copyNonces copies a nonce map, returning nil for an empty one
+func copyNonces(nonces map[string]uint64) map[string]uint64 {
+	if len(nonces) == 0 {
+		return nil
+	}
+	out := make(map[string]uint64, len(nonces))
+	for maker, nonce := range nonces {
+		out[maker] = nonce
+	}
+	return out
+}
+
+// sortedAccepted returns accepted orders sorted by hash, or nil for none
+func sortedAccepted(hashes map[string]AcceptedOrder) []AcceptedOrder {
+	if len(hashes) == 0 {
+		return nil
+	}
+	out := make([]AcceptedOrder, 0, len(hashes))
+	for _, a := range hashes {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hash < out[j].Hash })
+	return out
+}
+
+// engine restores an engine from the snapshot
+func (snap Snapshot) engine(maxFillsPerMatch int, markets matcher.Markets) *Engine {
+	e := NewEngine(maxFillsPerMatch, markets)
+	for asset, tick := range snap.TickSizes {
+		e.markets = e.markets.WithTickSize(asset, tick)
+		e.ticks[asset] = tick
+	}
+	for maker, nonce := range snap.Nonces {
+		e.nonces[maker] = nonce
+	}
+	for _, a := range snap.Accepted {
+		e.remember(matcher.Order{Hash: a.Hash, Maker: a.Maker, Nonce: a.Nonce, Expiration: a.Expiration})
+	}
+	e.book = append(e.book, snap.Book...)
+	e.lastSeq = snap.LastSeq
+	e.pending = pendingFills{
+		unbatched:      snap.Unbatched,
+		batches:        snap.Batches,
+		lastBatchID:    snap.LastBatchID,
+		settledBatchID: snap.SettledBatchID,
+	}
+	return e
+}
+
+// encodeSnapshot serializes a snapshot as a versioned, checksummed binary blob
+func encodeSnapshot(snap Snapshot) []byte {
+	var w snapshotWriter
+	w.uint64(snap.LastSeq)
+	w.uint64(snap.LastBatchID)
+	w.uint64(snap.SettledBatchID)
+
+	w.uint32(uint32(len(snap.Book)))
+	for _, o := range snap.Book {
+		w.string(o.Hash)
+		w.uint64(o.Seq)
+		w.string(o.Maker)
+		w.string(o.TakerAsset)
+		w.string(o.MakeAmount)
+		w.string(o.TakeAmount)
+		w.uint64(math.Float64bits(o.Price))
+		w.uint64(uint64(o.Timestamp))
+		w.uint64(o.FeeRateBps)
+		w.string(o.Signature)
+		w.string(string(o.Side))
+		w.uint64(o.Nonce)
+		w.uint64(uint64(o.Expiration))
+	}
+
+	w.fills(snap.Unbatched)
+
+	w.uint32(uint32(len(snap.Batches)))
+	for _, b := range snap.Batches {
+		w.uint64(b.ID)
+		w.fills(b.Fills)
+	}
+
+	assets := make([]string, 0, len(snap.TickSizes))
+	for asset := range snap.TickSizes {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	w.uint32(uint32(len(assets)))
+	for _, asset := range assets {
+		w.string(asset)
+		w.uint64(math.Float64bits(snap.TickSizes[asset]))
+	}
+
+	makers := make([]string, 0, len(snap.Nonces))
+	for maker := range snap.Nonces {
+		makers = append(makers, maker)
+	}
+	sort.Strings(makers)
+	w.uint32(uint32(len(makers)))
+	for _, maker := range makers {
+		w.string(maker)
+		w.uint64(snap.Nonces[maker])
+	}
+
+	w.uint32(uint32(len(snap.Accepted)))
+	for _, a := range snap.Accepted {
+		w.string(a.Hash)
+		w.string(a.Maker)
+		w.uint64(a.Nonce)
+		w.uint64(uint64(a.Expiration))
+	}
+
+	payload := w.buf.Bytes()
+	out := make([]byte, snapshotHeaderSize, snapshotHeaderSize+len(payload))
+	copy(out, snapshotMagic)
+	offset := len(snapshotMagic)
+	binary.LittleEndian.PutUint16(out[offset:], snapshotVersion)
+	binary.LittleEndian.PutUint64(out[offset+2:], snap.Index)
+	binary.LittleEndian.PutUint32(out[offset+10:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(out[offset+14:], crc32.Checksum(payload, snapshotCRC))
+	return append(out, payload...)
+}
+
+// decodeSnapshot parses and validates a snapshot blob
+func decodeSnapshot(data []byte) (Snapshot, error) {
+	var snap Snapshot
+	if len(data) < snapshotHeaderSize || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return snap, fmt.Errorf("%w: bad header", errSnapshotInvalid)
+	}
+
+	offset := len(snapshotMagic)
+	version := binary.LittleEndian.Uint16(data[offset:])
+	if version < 2 || version > snapshotVersion {
+		return snap, fmt.Errorf("%w: unsupported version %d", errSnapshotInvalid, version)
+	}
+	snap.Index = binary.LittleEndian.Uint64(data[offset+2:])
+	length := binary.LittleEndian.Uint32(data[offset+10:])
+	checksum := binary.LittleEndian.Uint32(data[offset+14:])
+
+	payload := data[snapshotHeaderSize:]
+	if uint32(len(payload)) != length {
+		return snap, fmt.Errorf("%w: payload is %d bytes, header says %d", errSnapshotInvalid, len(payload), length)
+	}
+	if crc32.Checksum(payload, snapshotCRC) != checksum {
+		return snap, fmt.Errorf("%w: checksum mismatch", errSnapshotInvalid)
+	}
+
+	r := snapshotReader{data: payload, version: version}
+	snap.LastSeq = r.uint64()
+	snap.LastBatchID = r.uint64()
+	snap.SettledBatchID = r.uint64()
+
+	n := r.count()
+	snap.Book = make([]matcher.Order, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		o := matcher.Order{
+			Hash:       r.string(),
+			Seq:        r.uint64(),
+			Maker:      r.string(),
+			TakerAsset: r.string(),
+			MakeAmount: r.string(),
+			TakeAmount: r.string(),
+			Price:      math.Float64frombits(r.uint64()),
+			Timestamp:  int64(r.uint64()),
+		}
+		if r.version >= snapshotVersionFees {
+			o.FeeRateBps = r.uint64()
+		}
+		o.Signature = r.string()
+		if r.version >= snapshotVersionOrderSide {
+			o.Side = matcher.Side(r.string())
+		}
+		if r.version >= snapshotVersionNonces {
+			o.Nonce = r.uint64()
+		}
+		if r.version >= snapshotVersionExpiry {
+			o.Expiration = int64(r.uint64())
+		}
+		snap.Book = append(snap.Book, o)
+	}
+
+	snap.Unbatched = r.fills()
+
+	n = r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		snap.Batches = append(snap.Batches, PendingBatch{ID: r.uint64(), Fills: r.fills()})
+	}
+
+	if r.version >= snapshotVersionTickSizes {
+		n = r.count()
+		for i := 0; i < n && r.err == nil; i++ {
+			if snap.TickSizes == nil {
+				snap.TickSizes = make(map[string]float64, n)
+			}
+			snap.TickSizes[r.string()] = math.Float64frombits(r.uint64())
+		}
+	}
+
+	if r.version >= snapshotVersionNonces {
+		n = r.count()
+		for i := 0; i < n && r.err == nil; i++ {
+			if snap.Nonces == nil {
+				snap.Nonces = make(map[string]uint64, n)
+			}
+			snap.Nonces[r.string()] = r.uint64()
+		}
+		// Hashes from before their order details were kept are never forgotten
+		n = r.count()
+		for i := 0; i < n && r.err == nil; i++ {
+			a := AcceptedOrder{Hash: r.string()}
+			if r.version >= snapshotVersionAccepted {
+				a.Maker = r.string()
+				a.Nonce = r.uint64()
+				a.Expiration = int64(r.uint64())
+			}
+			snap.Accepted = append(snap.Accepted, a)
+		}
+	}
+
+	if r.err == nil && len(r.data) != 0 {
+		r.err = fmt.Errorf("%d trailing bytes", len(r.data))
+	}
+	if r.err != nil {
+		return snap, fmt.Errorf("%w: %v", errSnapshotInvalid, r.err)
+	}
+	return snap, nil
+}
+
+// writeSnapshot atomically writes a snapshot to dir, named by its WAL index
+func writeSnapshot(dir string, snap Snapshot) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%020d%s", snap.Index, snapshotExt))
+	tmp := path + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	if _, err := file.Write(encodeSnapshot(snap)); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to close snapshot: %w", err)
+	}
+
+	// Rename is atomic, so a crash leaves either the old or the new snapshot
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to rename snapshot: %w", err)
+	}
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return path, nil
+}
+
+// listSnapshots returns the WAL indexes of the snapshots in dir, newest first
+func listSnapshots(dir string) ([]uint64, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	var indexes []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(name, snapshotExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] > indexes[j]
+	})
+	return indexes, nil
+}
+
+// loadLatestSnapshot returns the newest snapshot in dir that decodes cleanly,
+// skipping corrupted ones. ok is false when there is no usable snapshot.
+func loadLatestSnapshot(dir string) (snap Snapshot, ok bool, err error) {
+	indexes, err := listSnapshots(dir)
+	if err != nil {
+		return snap, false, err
+	}
+
+	for _, index := range indexes {
+		path := filepath.Join(dir, fmt.Sprintf("%020d%s", index, snapshotExt))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("⚠️  Skipping unreadable snapshot %s: %v", filepath.Base(path), err)
+			continue
+		}
+		snap, err := decodeSnapshot(data)
+		if err != nil {
+			log.Printf("⚠️  Skipping snapshot %s: %v", filepath.Base(path), err)
+			continue
+		}
+		if snap.Index != index {
+			log.Printf("⚠️  Skipping snapshot %s: header index %d does not match file name", filepath.Base(path), snap.Index)
+			continue
+		}
+		return snap, true, nil
+	}
+	return snap, false, nil
+}
+
+// pruneSnapshots deletes all but the newest retain snapshots and returns the
+// WAL index of the oldest one kept, or 0 if there are none
+func pruneSnapshots(dir string, retain int) (uint64, error) {
+	indexes, err := listSnapshots(dir)
+	if err != nil || len(indexes) == 0 {
+		return 0, err
+	}
+	if retain < 1 {
+		retain = 1
+	}
+
+	for _, index := range indexes[min(retain, len(indexes)):] {
+		path := filepath.Join(dir, fmt.Sprintf("%020d%s", index, snapshotExt))
+		if err := os.Remove(path); err != nil {
+			return 0, fmt.Errorf("failed to remove snapshot: %w", err)
+		}
+	}
+	return indexes[min(retain, len(indexes))-1], nil
+}
+
+// snapshotWriter appends little-endian fields to a buffer
+type snapshotWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *snapshotWriter) uint32(v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	w.buf.Write(b[:])
+}
+
+func (w *snapshotWriter) uint64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	w.buf.Write(b[:])
+}
+
+func (w *snapshotWriter) string(s string) {
+	w.uint32(uint32(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *snapshotWriter) fills(fills []matcher.Fill) {
+	w.uint32(uint32(len(fills)))
+	for _, f := range fills {
+		w.string(f.MakerHash)
+		w.string(f.TakerHash)
+		w.string(f.Quantity)
+		w.string(f.Price)
+		w.string(string(f.Side))
+		w.string(f.Maker)
+		w.string(f.Taker)
+		w.string(f.MakerFee)
+		w.string(f.TakerFee)
+	}
+}
+
+// snapshotReader consumes fields written by snapshotWriter, recording the first error
+type snapshotReader struct {
+	data    []byte
+	version uint16
+	err     error
+}
+
+func (r *snapshotReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = errors.New("unexpected end of payload")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *snapshotReader) uint32() uint32 {
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *snapshotReader) uint64() uint64 {
+	b := r.take(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// count reads a collection length, rejecting lengths the remaining payload cannot hold
+func (r *snapshotReader) count() int {
+	n := r.uint32()
+	if int64(n) > int64(len(r.data)) {
+		if r.err == nil {
+			r.err = fmt.Errorf("count %d exceeds payload", n)
+		}
+		return 0
+	}
+	return int(n)
+}
+
+func (r *snapshotReader) string() string {
+	return string(r.take(int(r.uint32())))
+}
+
+func (r *snapshotReader) fills() []matcher.Fill {
+	n := r.count()
+	fills := make([]matcher.Fill, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		f := matcher.Fill{MakerHash: r.string(), TakerHash: r.string(), Quantity: r.string()}
+		if r.version >= snapshotVersionFillPrice {
+			f.Price = r.string()
+			f.Side = matcher.Side(r.string())
+		}
+		if r.version >= snapshotVersionFees {
+			f.Maker, f.Taker = r.string(), r.string()
+			f.MakerFee, f.TakerFee = r.string(), r.string()
+		}
+		fills = append(fills, f)
+	}
+	return fills
+}
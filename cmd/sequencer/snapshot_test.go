@@ -0,0 +1,218 @@
+package sequencer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/wal"
+)
+
+func testSnapshot(index uint64) Snapshot {
+	fill := matcher.Fill{MakerHash: "aa", TakerHash: "bb", Quantity: "1.00000000", Price: "0.42000000", Side: matcher.SideBuy}
+	order := testOrder("0xaaaaaaaaaa", matcher.SideSell, 0.42, "3", 9)
+	order.Nonce = 3
+	order.Expiration = 1_900_000_000
+	return Snapshot{
+		Index:          index,
+		LastBatchID:    7,
+		SettledBatchID: 5,
+		Book:           []matcher.Order{order},
+		Unbatched:      []matcher.Fill{fill},
+		Batches:        []PendingBatch{{ID: 6, Fills: []matcher.Fill{fill, fill}}, {ID: 7, Fills: []matcher.Fill{}}},
+		TickSizes:      map[string]float64{"0xasset": 0.001, "0xother": 0.1},
+		Nonces:         map[string]uint64{"0xaaaaaaaaaa": 3},
+		Accepted: []AcceptedOrder{
+			{Hash: "0a", Maker: "0xaaaaaaaaaa", Nonce: 3, Expiration: 1_900_000_000},
+			{Hash: "1b", Maker: "0xbbbbbbbbbb"},
+		},
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	snap := testSnapshot(42)
+	decoded, err := decodeSnapshot(encodeSnapshot(snap))
+	if err != nil {
+		t.Fatalf("decodeSnapshot failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, snap) {
+		t.Fatalf("decoded %+v, want %+v", decoded, snap)
+	}
+
+	data := encodeSnapshot(snap)
+	data[len(data)-1] ^= 0xff
+	if _, err := decodeSnapshot(data); !errors.Is(err, errSnapshotInvalid) {
+		t.Fatalf("expected checksum failure, got %v", err)
+	}
+
+	data = encodeSnapshot(snap)
+	data[len(snapshotMagic)] = 99
+	if _, err := decodeSnapshot(data); !errors.Is(err, errSnapshotInvalid) {
+		t.Fatalf("expected unsupported version, got %v", err)
+	}
+}
+
+func TestLoadLatestSnapshotSkipsCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	for _, index := range []uint64{10, 20} {
+		if _, err := writeSnapshot(dir, testSnapshot(index)); err != nil {
+			t.Fatalf("writeSnapshot failed: %v", err)
+		}
+	}
+
+	// Truncate the newest snapshot as if the disk lost its tail
+	newest := filepath.Join(dir, "00000000000000000020.snap")
+	data, _ := os.ReadFile(newest)
+	os.WriteFile(newest, data[:len(data)/2], 0o644)
+
+	snap, ok, err := loadLatestSnapshot(dir)
+	if err != nil || !ok {
+		t.Fatalf("loadLatestSnapshot = %v, %v", ok, err)
+	}
+	if snap.Index != 10 {
+		t.Fatalf("loaded snapshot at index %d, want fallback to 10", snap.Index)
+	}
+}
+
+func TestRecoverFromSnapshotAndCompactedWAL(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(filepath.Join(dir, "wal"))
+	cfg.WAL.SegmentSize = 256
+	cfg.SnapshotDir = filepath.Join(dir, "snapshots")
+	cfg.SnapshotRetain = 1
+
+	s, err := Open(cfg, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	fills := populate(t, s)
+	if err := s.WriteSnapshot(0); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	if s.log.FirstIndex() == 1 {
+		t.Fatalf("expected WAL segments covered by the snapshot to be removed")
+	}
+
+	// Records after the snapshot are replayed on top of it
+	placed, err := s.PlaceOrder(testOrder("0xeeeeeeeeee", matcher.SideBuy, 0.10, "2", 5))
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	fills = append(fills, placed.Fills...)
+	book := s.Orders()
+	s.Close()
+
+	s, err = Open(cfg, nil)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer s.Close()
+
+	unsettled, err := s.Recover(0)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if !reflect.DeepEqual(s.Orders(), book) {
+		t.Fatalf("recovered book %+v, want %+v", s.Orders(), book)
+	}
+	if !reflect.DeepEqual(unsettled, fills) {
+		t.Fatalf("unsettled fills %+v, want %+v", unsettled, fills)
+	}
+}
+
+func TestRecoverFailsWhenWALGapHasNoSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	l, err := wal.Open(wal.Options{Dir: dir, SegmentSize: 32, Sync: wal.SyncNever})
+	if err != nil {
+		t.Fatalf("wal.Open failed: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Append(RecordCancel, []byte(`{"orderHash":"x"}`))
+	}
+	l.TruncateFront(3)
+	l.Close()
+
+	s, err := Open(testConfig(dir), nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.Recover(0); err == nil {
+		t.Fatalf("expected Recover to fail when early WAL segments are missing")
+	}
+}
+
+func TestSnapshotSettlesThroughTheInputLog(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(filepath.Join(dir, "wal"))
+	cfg.SnapshotDir = filepath.Join(dir, "snapshots")
+	s, err := Open(cfg, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.Recover(0); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	sink := &batchingSink{s: s}
+	s.SetSink(sink)
+	for i, o := range []matcher.Order{
+		testOrder("0xaaaaaaaaaa", matcher.SideBuy, 0.60, "10", 1),
+		testOrder("0xbbbbbbbbbb", matcher.SideSell, 0.50, "4", 2),
+		testOrder("0xcccccccccc", matcher.SideSell, 0.55, "3", 3),
+	} {
+		if _, err := s.PlaceOrder(o); err != nil {
+			t.Fatalf("PlaceOrder %d failed: %v", i, err)
+		}
+	}
+	if len(sink.batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(sink.batches))
+	}
+
+	if err := s.WriteSnapshot(1); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+
+	// Replaying the input log reaches the state the snapshot holds
+	var buf bytes.Buffer
+	if err := s.ExportInputs(&buf); err != nil {
+		t.Fatalf("ExportInputs failed: %v", err)
+	}
+	inputs, err := ReadInputs(&buf)
+	if err != nil {
+		t.Fatalf("ReadInputs failed: %v", err)
+	}
+	if last := inputs[len(inputs)-1]; last.Type != InputSettle || last.SettledBatchID != 1 {
+		t.Fatalf("last input %+v, want a settle of batch 1", last)
+	}
+	e := NewEngine(cfg.MaxFillsPerMatch, cfg.Markets)
+	for _, in := range inputs {
+		if _, err := e.Apply(in); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	}
+	snap, ok, err := loadLatestSnapshot(cfg.SnapshotDir)
+	if err != nil || !ok {
+		t.Fatalf("loadLatestSnapshot = %v, %v", ok, err)
+	}
+	replayed := newSnapshot(snap.Index, e)
+	if !bytes.Equal(encodeSnapshot(replayed), encodeSnapshot(snap)) {
+		t.Fatalf("replayed state %+v, want snapshot %+v", replayed, snap)
+	}
+	if len(snap.Batches) != 1 || snap.Batches[0].ID != 2 || snap.SettledBatchID != 1 {
+		t.Fatalf("snapshot keeps batches %+v settled up to %d, want only batch 2", snap.Batches, snap.SettledBatchID)
+	}
+
+	// Settling nothing new logs nothing
+	seq := s.LastSeq()
+	if err := s.WriteSnapshot(1); err != nil || s.LastSeq() != seq {
+		t.Fatalf("repeated WriteSnapshot = %v, seq %d, want no new input after %d", err, s.LastSeq(), seq)
+	}
+}
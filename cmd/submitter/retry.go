@@ -0,0 +1,21 @@
+package submitter
+
+import (
+	"errors"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
+)
+
+// ErrRevertNotReproduced is returned when a reverted transaction succeeds when
+// replayed against its parent block, so the revert depended on transactions
+// ahead of it in the same block, or when the replay fails without revert data.
+// Either way no deterministic reason is known, and resubmitting may succeed.
+var ErrRevertNotReproduced = errors.New("revert not reproduced at parent block")
+
+// IsRetryable reports whether a submission error is transient (RPC, nonce or
+// gas pricing issues, timeouts) rather than a deterministic contract revert.
+// The submission pipeline uses it to decide whether to retry a batch in turn
+// or park it.
+func IsRetryable(err error) bool {
+	return err != nil && !contracts.IsPermanent(err)
+}
@@ -0,0 +1,124 @@
+package submitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxSettledBatches bounds the number of settled batches kept in memory
+const maxSettledBatches = 10000
+
+// SettledBatch records a batch root confirmed on-chain
+type SettledBatch struct {
+	Root             string
+	TxHash           string
+	BlockNumber      uint64
+	AlreadySubmitted bool
+	Timestamp        time.Time
+}
+
+// Settled batch registry, oldest first for eviction
+var (
+	settledBatches = make(map[string]SettledBatch)
+	settledOrder   []string
+	settledMutex   sync.RWMutex
+)
+
+// IsBatchSubmitted calls BatchSettlement.isBatchSubmitted(root) and returns
+// whether the root is on-chain and the block it was submitted in
+func IsBatchSubmitted(root string) (bool, uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	contract := bind.NewBoundContract(contractAddr, contractABI, ethClient, ethClient, ethClient)
+
+	var out []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx}, &out, "isBatchSubmitted", common.HexToHash(root)); err != nil {
+		return false, 0, fmt.Errorf("failed to call isBatchSubmitted: %w", err)
+	}
+
+	submitted, ok := out[0].(bool)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected isBatchSubmitted result type %T", out[0])
+	}
+	blockNumber, ok := out[1].(*big.Int)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected isBatchSubmitted block type %T", out[1])
+	}
+
+	return submitted, blockNumber.Uint64(), nil
+}
+
+// GetSettledBatch returns the settlement record for a root confirmed by this submitter
+func GetSettledBatch(root string) (SettledBatch, bool) {
+	settledMutex.RLock()
+	defer settledMutex.RUnlock()
+
+	batch, ok := settledBatches[root]
+	return batch, ok
+}
+
+// submitOnce makes a single idempotent submission attempt. A root that is
+// already on-chain counts as success and no transaction is sent.
+func submitOnce(root string, fills []byte, aggSig []byte) (string, error) {
+	if settledOnChain(root) {
+		return "", nil
+	}
+
+	txHash, err := attemptSubmitBatch(root, fills, aggSig)
+
+	// A previous attempt may have landed after we stopped waiting for it
+	if errors.Is(err, contracts.ErrDuplicateBatchRoot) && settledOnChain(root) {
+		return "", nil
+	}
+
+	return txHash, err
+}
+
+// settledOnChain checks isBatchSubmitted and records the root if it is already settled.
+// Lookup failures are logged and treated as not settled so submission can proceed.
+func settledOnChain(root string) bool {
+	submitted, blockNumber, err := IsBatchSubmitted(root)
+	if err != nil {
+		log.Printf("⚠️  Could not check whether batch %s is settled: %v", root, err)
+		return false
+	}
+	if !submitted {
+		return false
+	}
+
+	log.Printf("♻️  Batch %s already settled in block %d, skipping submission", root, blockNumber)
+	recordSettled(root, "", blockNumber, true)
+	return true
+}
+
+// recordSettled adds a batch to the settled registry, evicting the oldest entry when full
+func recordSettled(root, txHash string, blockNumber uint64, alreadySubmitted bool) {
+	settledMutex.Lock()
+	defer settledMutex.Unlock()
+
+	if _, exists := settledBatches[root]; !exists {
+		settledOrder = append(settledOrder, root)
+		if len(settledOrder) > maxSettledBatches {
+			delete(settledBatches, settledOrder[0])
+			settledOrder = settledOrder[1:]
+		}
+	}
+
+	settledBatches[root] = SettledBatch{
+		Root:             root,
+		TxHash:           txHash,
+		BlockNumber:      blockNumber,
+		AlreadySubmitted: alreadySubmitted,
+		Timestamp:        time.Now(),
+	}
+}
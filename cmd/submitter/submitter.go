@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/contracts"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -20,21 +21,6 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-// Contract ABI for BatchSettlement.submitBatch function
-const batchSettlementABI = `[
-	{
-		"inputs": [
-			{"name": "root", "type": "bytes32"},
-			{"name": "fills", "type": "bytes"},
-			{"name": "aggSig", "type": "bytes"}
-		],
-		"name": "submitBatch",
-		"outputs": [],
-		"stateMutability": "nonpayable",
-		"type": "function"
-	}
-]`
-
 // Global configuration variables
 var (
 	ethClient    *ethclient.Client
@@ -56,10 +42,14 @@ type FailedBatch struct {
 	Sig       []byte
 	Timestamp time.Time
 	Attempts  int
+	LastError string
+	Permanent bool
 }
 
-// init initializes the submitter package with environment configuration
-func init() {
+// Init configures the submitter from environment variables. It must be
+// called before any other function of the package and exits on invalid
+// configuration.
+func Init() {
 	var err error
 	
 	// Initialize Ethereum client
@@ -92,11 +82,8 @@ func init() {
 	}
 	contractAddr = common.HexToAddress(contractAddrStr)
 	
-	// Parse contract ABI
-	contractABI, err = abi.JSON(strings.NewReader(batchSettlementABI))
-	if err != nil {
-		log.Fatalf("Failed to parse contract ABI: %v", err)
-	}
+	// Use the full embedded BatchSettlement ABI so custom errors and events can be decoded
+	contractABI = contracts.BatchSettlementABI
 	
 	// Load private key
 	privateKeyHex := os.Getenv("PRIVATE_KEY")
@@ -136,21 +123,34 @@ func init() {
 		rpcURL, contractAddr.Hex(), maxRetries, backoffMS)
 }
 
-// SubmitBatch submits a batch to the BatchSettlement contract with retry logic
+// SubmitBatch submits a batch to the BatchSettlement contract with retry logic.
+// A batch that still fails is left to the caller, which records it with
+// RecordFailedBatch once it gives up.
 func SubmitBatch(root string, fills []byte, aggSig []byte) (string, error) {
 	log.Printf("Submitting batch - Root: %s, Fills length: %d, Signature length: %d",
 		root, len(fills), len(aggSig))
 
+	var lastErr error
+	attempts := 0
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		txHash, err := attemptSubmitBatch(root, fills, aggSig)
+		attempts = attempt
+		txHash, err := submitOnce(root, fills, aggSig)
 		if err == nil {
-			log.Printf("✅ Batch submitted successfully on attempt %d: https://explorer.testnet.io/tx/%s", 
-				attempt, txHash)
+			if txHash != "" {
+				log.Printf("✅ Batch submitted successfully on attempt %d: https://explorer.testnet.io/tx/%s", 
+					attempt, txHash)
+			}
 			return txHash, nil
 		}
+		lastErr = err
 		
 		log.Printf("❌ Attempt %d/%d failed: %v", attempt, maxRetries, err)
 		
+		if !IsRetryable(err) {
+			log.Printf("🛑 Permanent failure for batch %s, not retrying", root)
+			break
+		}
+		
 		if attempt < maxRetries {
 			backoffDuration := time.Duration(backoffMS*attempt) * time.Millisecond
 			log.Printf("⏳ Waiting %v before retry %d...", backoffDuration, attempt+1)
@@ -158,13 +158,21 @@ func SubmitBatch(root string, fills []byte, aggSig []byte) (string, error) {
 		}
 	}
 	
-	// All retries failed - add to durable queue
+	log.Printf("🚨 Batch submission failed after %d attempts. Root: %s", attempts, root)
+	return "", fmt.Errorf("batch submission failed after %d attempts: %w", attempts, lastErr)
+}
+
+// RecordFailedBatch adds a batch that could not be submitted to the failed
+// batch queue. The submission pipeline calls it once for each batch it parks.
+func RecordFailedBatch(root string, fills []byte, aggSig []byte, attempts int, err error) {
 	failedBatch := FailedBatch{
 		Root:      root,
 		Fills:     fills,
 		Sig:       aggSig,
 		Timestamp: time.Now(),
-		Attempts:  maxRetries,
+		Attempts:  attempts,
+		LastError: err.Error(),
+		Permanent: !IsRetryable(err),
 	}
 	
 	failedMutex.Lock()
@@ -172,10 +180,7 @@ func SubmitBatch(root string, fills []byte, aggSig []byte) (string, error) {
 	queueLength := len(failedBatches)
 	failedMutex.Unlock()
 	
-	log.Printf("🚨 Batch submission failed after %d attempts. Root: %s, Queue length: %d", 
-		maxRetries, root, queueLength)
-	
-	return "", fmt.Errorf("batch submission failed after %d attempts", maxRetries)
+	log.Printf("🚨 Batch %s added to the failed batch queue. Queue length: %d", root, queueLength)
 }
 
 // attemptSubmitBatch makes a single attempt to submit a batch
@@ -206,7 +211,7 @@ func attemptSubmitBatch(root string, fills []byte, aggSig []byte) (string, error
 		Data: data,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to estimate gas: %w", err)
+		return "", fmt.Errorf("failed to estimate gas: %w", contracts.DecodeBatchSettlementError(err))
 	}
 	
 	// Apply 20% buffer to gas estimate
@@ -253,19 +258,67 @@ func attemptSubmitBatch(root string, fills []byte, aggSig []byte) (string, error
 	if err != nil {
 		// Return the tx hash even if we can't wait for confirmation
 		log.Printf("⚠️  Transaction submitted but couldn't wait for confirmation: %v", err)
+		if submitted, blockNumber, checkErr := IsBatchSubmitted(root); checkErr == nil && submitted {
+			recordSettled(root, tx.Hash().Hex(), blockNumber, false)
+		}
 		return tx.Hash().Hex(), nil
 	}
 	
 	if receipt.Status == 0 {
-		return "", fmt.Errorf("transaction failed with status 0 (reverted)")
+		return "", fmt.Errorf("transaction %s reverted: %w", tx.Hash().Hex(),
+			revertReason(ctx, fromAddr, data, receipt.BlockNumber))
 	}
 	
 	log.Printf("⛏️  Transaction mined in block %d, gas used: %d", 
 		receipt.BlockNumber.Uint64(), receipt.GasUsed)
+	recordSettled(root, tx.Hash().Hex(), receipt.BlockNumber.Uint64(), false)
 	
 	return tx.Hash().Hex(), nil
 }
 
+// revertReason replays a reverted transaction with eth_call against the parent
+// block state to recover and decode its revert data. A replay that succeeds,
+// or fails without revert data, yields ErrRevertNotReproduced, which stays
+// retryable.
+func revertReason(ctx context.Context, from common.Address, data []byte, blockNumber *big.Int) error {
+	parent := new(big.Int).Sub(blockNumber, big.NewInt(1))
+
+	_, err := ethClient.CallContract(ctx, ethereum.CallMsg{
+		From: from,
+		To:   &contractAddr,
+		Data: data,
+	}, parent)
+	if err == nil {
+		return ErrRevertNotReproduced
+	}
+
+	if revertData, ok := contracts.RevertData(err); ok {
+		return contracts.DecodeBatchSettlementRevert(revertData)
+	}
+	// The replay failed without revert data, so the reason is unknown
+	return fmt.Errorf("%w: replay failed: %v", ErrRevertNotReproduced, err)
+}
+
+// TotalBatchesSubmitted returns the BatchSettlement.totalBatchesSubmitted counter
+func TotalBatchesSubmitted() (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	contract := bind.NewBoundContract(contractAddr, contractABI, ethClient, ethClient, ethClient)
+
+	var out []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx}, &out, "totalBatchesSubmitted"); err != nil {
+		return 0, fmt.Errorf("failed to call totalBatchesSubmitted: %w", err)
+	}
+
+	total, ok := out[0].(*big.Int)
+	if !ok {
+		return 0, fmt.Errorf("unexpected totalBatchesSubmitted result type %T", out[0])
+	}
+
+	return total.Uint64(), nil
+}
+
 // RetryFailedBatches attempts to resubmit all failed batches
 func RetryFailedBatches() error {
 	failedMutex.Lock()
@@ -282,19 +335,29 @@ func RetryFailedBatches() error {
 	
 	log.Printf("🔄 Retrying %d failed batches...", len(batchesToRetry))
 	
-	var successCount, failCount int
+	var successCount, failCount, skippedCount int
 	var successfulIndices []int
+	permanentFailures := make(map[int]error)
 	
 	for i, batch := range batchesToRetry {
+		if batch.Permanent {
+			log.Printf("Skipping batch %d/%d (Root: %s): permanent failure: %s", 
+				i+1, len(batchesToRetry), batch.Root, batch.LastError)
+			skippedCount++
+			continue
+		}
+		
 		log.Printf("Retrying batch %d/%d (Root: %s, Previous attempts: %d)", 
 			i+1, len(batchesToRetry), batch.Root, batch.Attempts)
 		
 		// Try to submit with exponential backoff
 		for attempt := 1; attempt <= maxRetries; attempt++ {
-			txHash, err := attemptSubmitBatch(batch.Root, batch.Fills, batch.Sig)
+			txHash, err := submitOnce(batch.Root, batch.Fills, batch.Sig)
 			if err == nil {
-				log.Printf("✅ Retry successful for batch %s: https://explorer.testnet.io/tx/%s", 
-					batch.Root, txHash)
+				if txHash != "" {
+					log.Printf("✅ Retry successful for batch %s: https://explorer.testnet.io/tx/%s", 
+						batch.Root, txHash)
+				}
 				successfulIndices = append(successfulIndices, i)
 				successCount++
 				break
@@ -303,6 +366,11 @@ func RetryFailedBatches() error {
 			log.Printf("❌ Retry attempt %d/%d failed for batch %s: %v", 
 				attempt, maxRetries, batch.Root, err)
 			
+			if !IsRetryable(err) {
+				permanentFailures[i] = err
+				break
+			}
+			
 			if attempt < maxRetries {
 				backoffDuration := time.Duration(backoffMS*attempt) * time.Millisecond
 				time.Sleep(backoffDuration)
@@ -314,6 +382,18 @@ func RetryFailedBatches() error {
 		}
 	}
 	
+	// Record permanent failures so later retries skip them
+	if len(permanentFailures) > 0 {
+		failedMutex.Lock()
+		for idx, err := range permanentFailures {
+			if idx < len(failedBatches) {
+				failedBatches[idx].Permanent = true
+				failedBatches[idx].LastError = err.Error()
+			}
+		}
+		failedMutex.Unlock()
+	}
+	
 	// Remove successful batches from the failed queue
 	if len(successfulIndices) > 0 {
 		failedMutex.Lock()
@@ -327,6 +407,9 @@ func RetryFailedBatches() error {
 		failedMutex.Unlock()
 	}
 	
+	if skippedCount > 0 {
+		log.Printf("⏭️  Skipped %d batches with permanent failures", skippedCount)
+	}
 	log.Printf("🔄 Retry completed - Success: %d, Failed: %d, Remaining in queue: %d", 
 		successCount, failCount, len(failedBatches))
 	
@@ -0,0 +1,81 @@
+package ticker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+)
+
+// Trade is the last trade of a market. Price is the maker's price and Side
+// the side of the taker, as in the feed's trade prints.
+type Trade struct {
+	Price     string       `json:"price"`
+	Size      string       `json:"size"`
+	Side      matcher.Side `json:"side"`
+	Timestamp int64        `json:"timestamp"` // unix milliseconds
+}
+
+// Cache holds the top of book and last trade of every market, so summary
+// endpoints do not walk the book on each request. The sequencer keeps it up
+// to date: Update receives the book after each change and RecordTrades its fills.
+type Cache struct {
+	mu     sync.RWMutex
+	tops   map[string]matcher.Top
+	trades map[string]Trade
+}
+
+// New creates an empty cache
+func New() *Cache {
+	return &Cache{
+		tops:   make(map[string]matcher.Top),
+		trades: make(map[string]Trade),
+	}
+}
+
+// Update recomputes the top of book of every market from orders. It has the
+// signature of a sequencer book hook without the sequence number.
+func (c *Cache) Update(orders []matcher.Order) {
+	tops := matcher.TopOfBook(orders)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tops = tops
+}
+
+// RecordTrades records the last of the fills of taker as the last trade of
+// its market, stamped with at, the sequencer time of the taker order. It has
+// the signature of a sequencer trade hook, so trades replayed on recovery
+// keep the time they were matched at.
+func (c *Cache) RecordTrades(taker matcher.Order, fills []matcher.Fill, at time.Time) {
+	if len(fills) == 0 {
+		return
+	}
+	last := fills[len(fills)-1]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trades[taker.TakerAsset] = Trade{
+		Price:     last.Price,
+		Size:      last.Quantity,
+		Side:      last.Side,
+		Timestamp: at.UnixMilli(),
+	}
+}
+
+// Top returns the top of book of the market for asset; its fields are empty
+// if the market has no orders
+func (c *Cache) Top(asset string) matcher.Top {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tops[asset]
+}
+
+// LastTrade returns the last trade of the market for asset matched since the
+// book was recovered. ok is false if it has not traded.
+func (c *Cache) LastTrade(asset string) (trade Trade, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	trade, ok = c.trades[asset]
+	return trade, ok
+}
@@ -0,0 +1,52 @@
+package ticker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/hourglass-avs-template/cmd/matcher"
+)
+
+func testOrder(maker, asset string, side matcher.Side, price float64, amount string) matcher.Order {
+	o := matcher.Order{Maker: maker, TakerAsset: asset, MakeAmount: amount, TakeAmount: amount, Price: price, Side: side, Signature: "0xsig"}
+	o.Hash = matcher.OrderHash(o)
+	return o
+}
+
+func TestCacheTracksTopOfBook(t *testing.T) {
+	c := New()
+	c.Update([]matcher.Order{
+		testOrder("0xaaaaaaaaaa", "0xasset", matcher.SideBuy, 0.4, "1"),
+		testOrder("0xbbbbbbbbbb", "0xasset", matcher.SideSell, 0.6, "1"),
+	})
+	want := matcher.Top{BestBid: "0.40000000", BestAsk: "0.60000000", Midpoint: "0.50000000", Spread: "0.20000000"}
+	if top := c.Top("0xasset"); top != want {
+		t.Fatalf("top = %+v, want %+v", top, want)
+	}
+
+	// Markets that empty out lose their top of book
+	c.Update(nil)
+	if top := c.Top("0xasset"); top != (matcher.Top{}) {
+		t.Fatalf("top of an empty market = %+v", top)
+	}
+}
+
+func TestCacheRecordsLastTrade(t *testing.T) {
+	c := New()
+	if _, ok := c.LastTrade("0xasset"); ok {
+		t.Fatalf("untraded market has a last trade")
+	}
+
+	// Trades are stamped with the sequencer time of the taker, not the clock
+	taker := testOrder("0xaaaaaaaaaa", "0xasset", matcher.SideBuy, 0.6, "2")
+	c.RecordTrades(taker, []matcher.Fill{
+		{Price: "0.40000000", Quantity: "1.00000000", Side: matcher.SideBuy},
+		{Price: "0.50000000", Quantity: "1.00000000", Side: matcher.SideBuy},
+	}, time.UnixMilli(1000))
+
+	trade, ok := c.LastTrade("0xasset")
+	want := Trade{Price: "0.50000000", Size: "1.00000000", Side: matcher.SideBuy, Timestamp: 1000}
+	if !ok || trade != want {
+		t.Fatalf("last trade = %+v, %v, want %+v", trade, ok, want)
+	}
+}
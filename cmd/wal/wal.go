@@ -0,0 +1,480 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// headerSize is the size of a record frame header: payload length, CRC32C and record type
+const headerSize = 9
+
+// maxRecordSize guards against reading garbage lengths from a corrupted frame
+const maxRecordSize = 16 << 20
+
+// segmentExt is the file extension of WAL segments
+const segmentExt = ".wal"
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrCorrupt is returned when a record before the tail of the log fails validation
+var ErrCorrupt = errors.New("wal: corrupt record")
+
+// ErrClosed is returned when using a closed log
+var ErrClosed = errors.New("wal: log closed")
+
+// RecordType identifies the kind of entry stored in a record
+type RecordType uint8
+
+// Record is a single entry of the log
+type Record struct {
+	Index uint64
+	Type  RecordType
+	Data  []byte
+}
+
+// SyncPolicy controls when appended records are fsynced to disk
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs after every append, before Append returns
+	SyncAlways SyncPolicy = iota
+	// SyncInterval fsyncs in the background every SyncInterval
+	SyncInterval
+	// SyncNever leaves flushing to the operating system
+	SyncNever
+)
+
+// ParseSyncPolicy parses "always", "interval" or "never"
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch strings.ToLower(s) {
+	case "always":
+		return SyncAlways, nil
+	case "interval":
+		return SyncInterval, nil
+	case "never", "none":
+		return SyncNever, nil
+	}
+	return SyncAlways, fmt.Errorf("unknown sync policy %q (expected always, interval or never)", s)
+}
+
+// Options configures a log
+type Options struct {
+	Dir          string
+	SegmentSize  int64
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+}
+
+// segment is a log file holding records starting at index first
+type segment struct {
+	first uint64
+	path  string
+}
+
+// Log is a segmented, append-only, checksummed write-ahead log.
+// Record indexes start at 1 and increase by one per append.
+type Log struct {
+	opts Options
+
+	mu       sync.Mutex
+	segments []segment
+	file     *os.File
+	size     int64
+	next     uint64
+	dirty    bool
+	closed   bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open opens or creates the log in opts.Dir, truncating a torn or corrupted tail
+func Open(opts Options) (*Log, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = 64 << 20
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = 100 * time.Millisecond
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+
+	segments, err := listSegments(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{opts: opts, segments: segments}
+
+	if len(segments) == 0 {
+		if err := l.createSegment(1); err != nil {
+			return nil, err
+		}
+	} else {
+		last := segments[len(segments)-1]
+		count, validSize, err := recoverTail(last.path)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open WAL segment: %w", err)
+		}
+		l.file = file
+		l.size = validSize
+		l.next = last.first + count
+	}
+
+	if opts.Sync == SyncInterval {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.syncLoop()
+	}
+
+	return l, nil
+}
+
+// Append writes a record and returns its index. With SyncAlways the record is
+// durable when Append returns.
+func (l *Log) Append(typ RecordType, data []byte) (uint64, error) {
+	if len(data) > maxRecordSize {
+		return 0, fmt.Errorf("wal: record of %d bytes exceeds maximum %d", len(data), maxRecordSize)
+	}
+
+	frame := encodeFrame(typ, data)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, ErrClosed
+	}
+
+	if l.size > 0 && l.size+int64(len(frame)) > l.opts.SegmentSize {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := l.file.Write(frame); err != nil {
+		return 0, fmt.Errorf("failed to write WAL record: %w", err)
+	}
+	l.size += int64(len(frame))
+
+	if l.opts.Sync == SyncAlways {
+		if err := l.file.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync WAL: %w", err)
+		}
+	} else {
+		l.dirty = true
+	}
+
+	index := l.next
+	l.next++
+	return index, nil
+}
+
+// Replay calls fn for every record with index >= from, in order. Records
+// appended while replaying are not visited. fn must not call Append.
+func (l *Log) Replay(from uint64, fn func(Record) error) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	segments := append([]segment(nil), l.segments...)
+	end := l.next
+	l.mu.Unlock()
+
+	for i, seg := range segments {
+		// Skip segments that end before from
+		if i+1 < len(segments) && segments[i+1].first <= from {
+			continue
+		}
+		if err := replaySegment(seg, from, end, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastIndex returns the index of the last appended record, or 0 if the log is empty
+func (l *Log) LastIndex() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next - 1
+}
+
+// FirstIndex returns the index of the oldest record still held by the log
+func (l *Log) FirstIndex() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.segments[0].first
+}
+
+// TruncateFront deletes sealed segments holding only records with index below
+// index and returns how many were removed. The active segment is never removed.
+func (l *Log) TruncateFront(index uint64) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, ErrClosed
+	}
+
+	removed := 0
+	for len(l.segments) > 1 && l.segments[1].first <= index {
+		if err := os.Remove(l.segments[0].path); err != nil {
+			return removed, fmt.Errorf("failed to remove WAL segment: %w", err)
+		}
+		l.segments = l.segments[1:]
+		removed++
+	}
+	return removed, nil
+}
+
+// Sync flushes appended records to disk
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	return l.syncLocked()
+}
+
+// Close syncs and closes the log
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	err := l.syncLocked()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.mu.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+	}
+	return err
+}
+
+// syncLocked fsyncs the active segment if it has unsynced writes; the caller must hold l.mu
+func (l *Log) syncLocked() error {
+	if !l.dirty {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	l.dirty = false
+	return nil
+}
+
+// syncLoop fsyncs periodically under the interval policy
+func (l *Log) syncLoop() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			if !l.closed {
+				if err := l.syncLocked(); err != nil {
+					log.Printf("WAL background sync failed: %v", err)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// rotate seals the active segment and starts a new one; the caller must hold l.mu
+func (l *Log) rotate() error {
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL segment: %w", err)
+	}
+	l.dirty = false
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close WAL segment: %w", err)
+	}
+	return l.createSegment(l.next)
+}
+
+// createSegment creates and activates a new segment starting at index first
+func (l *Log) createSegment(first uint64) error {
+	path := filepath.Join(l.opts.Dir, fmt.Sprintf("%020d%s", first, segmentExt))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL segment: %w", err)
+	}
+
+	// Persist the directory entry so the new segment survives a crash
+	if dir, err := os.Open(l.opts.Dir); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	l.segments = append(l.segments, segment{first: first, path: path})
+	l.file = file
+	l.size = 0
+	l.next = first
+	return nil
+}
+
+// listSegments returns the segments in dir ordered by first index
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WAL directory: %w", err)
+	}
+
+	var segments []segment
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{first: first, path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].first < segments[j].first
+	})
+	return segments, nil
+}
+
+// recoverTail counts the valid records of the last segment and truncates
+// anything after them, such as a torn write from a crash
+func recoverTail(path string) (uint64, int64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open WAL segment: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to stat WAL segment: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	var count uint64
+	var offset int64
+	for {
+		_, _, n, err := readFrame(reader)
+		if err != nil {
+			break
+		}
+		count++
+		offset += int64(n)
+	}
+
+	if offset < info.Size() {
+		log.Printf("⚠️  WAL segment %s has %d bytes of corrupted tail, truncating to %d bytes",
+			filepath.Base(path), info.Size()-offset, offset)
+		if err := file.Truncate(offset); err != nil {
+			return 0, 0, fmt.Errorf("failed to truncate corrupted WAL tail: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			return 0, 0, fmt.Errorf("failed to sync truncated WAL segment: %w", err)
+		}
+	}
+
+	return count, offset, nil
+}
+
+// replaySegment visits the records of one segment with index in [from, end)
+func replaySegment(seg segment, from, end uint64, fn func(Record) error) error {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL segment: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for index := seg.first; index < end; index++ {
+		typ, data, _, err := readFrame(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: segment %s, index %d: %v", ErrCorrupt, filepath.Base(seg.path), index, err)
+		}
+		if index < from {
+			continue
+		}
+		if err := fn(Record{Index: index, Type: typ, Data: data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeFrame serializes a record as length, checksum, type and payload
+func encodeFrame(typ RecordType, data []byte) []byte {
+	frame := make([]byte, headerSize+len(data))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(data)))
+	frame[8] = byte(typ)
+	copy(frame[headerSize:], data)
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.Checksum(frame[8:], crcTable))
+	return frame
+}
+
+// readFrame reads and validates one frame, returning io.EOF at a clean end of file
+func readFrame(r io.Reader) (RecordType, []byte, int, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return 0, nil, 0, io.EOF
+		}
+		return 0, nil, 0, fmt.Errorf("truncated header: %w", err)
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:4])
+	if length > maxRecordSize {
+		return 0, nil, 0, fmt.Errorf("record length %d exceeds maximum", length)
+	}
+
+	body := make([]byte, 1+length)
+	body[0] = header[8]
+	if _, err := io.ReadFull(r, body[1:]); err != nil {
+		return 0, nil, 0, fmt.Errorf("truncated payload: %w", err)
+	}
+
+	if crc32.Checksum(body, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return 0, nil, 0, errors.New("checksum mismatch")
+	}
+
+	return RecordType(header[8]), body[1:], headerSize + int(length), nil
+}
@@ -0,0 +1,166 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readAll(t *testing.T, l *Log, from uint64) []Record {
+	t.Helper()
+	var records []Record
+	if err := l.Replay(from, func(r Record) error {
+		records = append(records, r)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	return records
+}
+
+func TestAppendReplayAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(Options{Dir: dir, SegmentSize: 64, Sync: SyncAlways})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		index, err := l.Append(RecordType(i%3), []byte(fmt.Sprintf("record-%02d", i)))
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		if index != uint64(i) {
+			t.Fatalf("Append returned index %d, want %d", index, i)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	segments, _ := listSegments(dir)
+	if len(segments) < 2 {
+		t.Fatalf("expected the log to rotate into several segments, got %d", len(segments))
+	}
+
+	l, err = Open(Options{Dir: dir, SegmentSize: 64, Sync: SyncAlways})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer l.Close()
+
+	if l.LastIndex() != 10 {
+		t.Fatalf("LastIndex() = %d, want 10", l.LastIndex())
+	}
+
+	records := readAll(t, l, 4)
+	if len(records) != 7 || records[0].Index != 4 || string(records[6].Data) != "record-10" {
+		t.Fatalf("unexpected replay from index 4: %+v", records)
+	}
+
+	if index, err := l.Append(1, []byte("record-11")); err != nil || index != 11 {
+		t.Fatalf("Append after reopen = %d, %v; want 11", index, err)
+	}
+}
+
+func TestOpenTruncatesCorruptedTail(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(Options{Dir: dir, Sync: SyncNever})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		if _, err := l.Append(1, []byte(fmt.Sprintf("record-%d", i))); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	l.Close()
+
+	// Simulate a torn write: a partial frame after the last complete record
+	path := filepath.Join(dir, fmt.Sprintf("%020d%s", 1, segmentExt))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("failed to open segment: %v", err)
+	}
+	f.Write(encodeFrame(1, []byte("record-4"))[:12])
+	f.Close()
+
+	l, err = Open(Options{Dir: dir, Sync: SyncNever})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer l.Close()
+
+	if l.LastIndex() != 3 {
+		t.Fatalf("LastIndex() = %d after truncating tail, want 3", l.LastIndex())
+	}
+	if index, err := l.Append(1, []byte("record-4")); err != nil || index != 4 {
+		t.Fatalf("Append after recovery = %d, %v; want 4", index, err)
+	}
+	if records := readAll(t, l, 1); len(records) != 4 || string(records[3].Data) != "record-4" {
+		t.Fatalf("unexpected records after recovery: %+v", records)
+	}
+}
+
+func TestReplayDetectsCorruptionInSealedSegment(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(Options{Dir: dir, SegmentSize: 32, Sync: SyncAlways})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for i := 1; i <= 6; i++ {
+		if _, err := l.Append(1, []byte(fmt.Sprintf("record-%d", i))); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// Flip a payload byte in the first, sealed segment
+	path := filepath.Join(dir, fmt.Sprintf("%020d%s", 1, segmentExt))
+	data, _ := os.ReadFile(path)
+	data[headerSize] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+
+	err = l.Replay(1, func(Record) error { return nil })
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	l.Close()
+}
+
+func TestTruncateFrontRemovesSealedSegments(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(Options{Dir: dir, SegmentSize: 32, Sync: SyncNever})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for i := 1; i <= 6; i++ {
+		if _, err := l.Append(1, []byte(fmt.Sprintf("record-%d", i))); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	removed, err := l.TruncateFront(4)
+	if err != nil {
+		t.Fatalf("TruncateFront failed: %v", err)
+	}
+	if removed == 0 || l.FirstIndex() > 4 {
+		t.Fatalf("TruncateFront(4) removed %d segments, first index now %d", removed, l.FirstIndex())
+	}
+	if records := readAll(t, l, 4); len(records) != 3 || records[0].Index != 4 {
+		t.Fatalf("unexpected records after truncation: %+v", records)
+	}
+
+	// The active segment survives even when every record is below the index
+	l.TruncateFront(100)
+	l.Close()
+	l, err = Open(Options{Dir: dir, SegmentSize: 32, Sync: SyncNever})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer l.Close()
+	if l.LastIndex() != 6 {
+		t.Fatalf("LastIndex() = %d after truncation, want 6", l.LastIndex())
+	}
+}
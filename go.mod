@@ -7,6 +7,7 @@ require (
 	github.com/Layr-Labs/protocol-apis v1.12.1
 	github.com/cbergoon/merkletree v0.2.0
 	github.com/ethereum/go-ethereum v1.15.11
+	github.com/gorilla/websocket v1.4.2
 	github.com/joho/godotenv v1.5.1
 	go.uber.org/zap v1.27.0
 )
@@ -28,7 +29,6 @@ require (
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
-	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/holiman/uint256 v1.3.2 // indirect
 	github.com/mmcloughlin/addchain v0.4.0 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
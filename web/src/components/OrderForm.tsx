@@ -1,6 +1,5 @@
 import { useState } from "react";
-import axios from "axios";
-import { ethers } from "ethers";
+import { createOrder, postOrder } from "../services/clobClient";
 
 interface OrderFormData {
   price: string;
@@ -27,38 +26,6 @@ export default function OrderForm() {
     setFormData((prev) => ({ ...prev, [name]: value }));
   };
 
-  const createEIP712Order = (price: number, quantity: number, side: string) => {
-    const now = Date.now();
-    const demoKey =
-      import.meta.env.VITE_DEMO_KEY ||
-      "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80";
-    const wallet = new ethers.Wallet(demoKey);
-
-    // Create EIP-712 compatible order
-    const order = {
-      maker: wallet.address,
-      takerAsset: "0x1234567890123456789012345678901234567890", // Mock asset address
-      makeAmount: quantity.toString(),
-      takeAmount: (quantity * price).toString(),
-      price: price,
-      side: side, // Use the side parameter
-      timestamp: now,
-      signature: "", // Will be filled after signing
-    };
-
-    // Create a simple signature (in production, this would be proper EIP-712)
-    const orderHash = ethers.keccak256(
-      ethers.toUtf8Bytes(
-        `${order.maker}:${order.takerAsset}:${order.makeAmount}:${order.takeAmount}:${order.price}:${order.timestamp}`
-      )
-    );
-
-    // Sign the hash
-    order.signature = wallet.signMessageSync(orderHash);
-
-    return order;
-  };
-
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setLoading(true);
@@ -72,17 +39,8 @@ export default function OrderForm() {
         throw new Error("Price and quantity must be positive numbers");
       }
 
-      // Create and sign the order
-      const order = createEIP712Order(price, quantity, formData.side);
-
-      // Submit to sequencer
-      const apiUrl = import.meta.env.VITE_API_URL || "http://localhost:8081";
-      await axios.post(`${apiUrl}/orders`, order, {
-        timeout: 5000,
-        headers: {
-          "Content-Type": "application/json",
-        },
-      });
+      // Create the order and submit it in a request signed with the API key
+      await postOrder(createOrder(price, quantity, formData.side));
 
       setMessage({ type: "success", text: "Order submitted successfully!" });
       setFormData({ price: "", quantity: "", side: "buy" });
@@ -91,7 +49,7 @@ export default function OrderForm() {
       setMessage({
         type: "error",
         text:
-          error.response?.data?.error ||
+          error.response?.data?.message ||
           error.message ||
           "Failed to submit order",
       });
@@ -169,8 +127,8 @@ export default function OrderForm() {
             name="price"
             value={formData.price}
             onChange={handleInputChange}
-            placeholder="1.25"
-            step="0.001"
+            placeholder="0.50"
+            step="0.01"
             min="0"
             required
             style={{
@@ -1,6 +1,3 @@
-import axios from "axios";
-import { ethers } from "ethers";
-
 interface SimulatedOrder {
   price: number;
   quantity: number;
@@ -11,43 +8,8 @@ class TradingSimulator {
   private isRunning = false;
   private marketPrice = 1.25; // Starting market price
   private priceVolatility = 0.02; // 2% volatility
-  private apiUrl: string;
   private intervalId?: NodeJS.Timeout;
 
-  constructor(apiUrl: string = "http://localhost:8081") {
-    this.apiUrl = apiUrl;
-  }
-
-  private createEIP712Order(price: number, quantity: number, side: string) {
-    const now = Date.now();
-    const demoKey =
-      import.meta.env.VITE_DEMO_KEY ||
-      "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80";
-    const wallet = new ethers.Wallet(demoKey);
-
-    // Create EIP-712 compatible order
-    const order = {
-      maker: wallet.address,
-      takerAsset: "0x1234567890123456789012345678901234567890", // Mock asset address
-      makeAmount: quantity.toString(),
-      takeAmount: (quantity * price).toString(),
-      price: price,
-      side: side, // Use the side parameter
-      timestamp: now + Math.floor(Math.random() * 1000), // Add small random delay
-      signature: "", // Will be filled after signing
-    };
-
-    // Create a simple signature
-    const orderHash = ethers.keccak256(
-      ethers.toUtf8Bytes(
-        `${order.maker}:${order.takerAsset}:${order.makeAmount}:${order.takeAmount}:${order.price}:${order.timestamp}`
-      )
-    );
-
-    order.signature = wallet.signMessageSync(orderHash);
-    return order;
-  }
-
   private generateRealisticOrder(): SimulatedOrder {
     // Simulate market movement
     const priceChange = (Math.random() - 0.5) * this.priceVolatility;
@@ -78,29 +40,13 @@ class TradingSimulator {
     };
   }
 
-  private async submitOrder(orderData: SimulatedOrder): Promise<boolean> {
-    try {
-      const order = this.createEIP712Order(
-        orderData.price,
-        orderData.quantity,
-        orderData.side
-      );
-
-      await axios.post(`${this.apiUrl}/orders`, order, {
-        timeout: 3000,
-        headers: {
-          "Content-Type": "application/json",
-        },
-      });
-
-      console.log(
-        `[TradingBot] Submitted ${orderData.side} order: ${orderData.quantity} @ ${orderData.price}`
-      );
-      return true;
-    } catch (error) {
-      console.warn(`[TradingBot] Failed to submit order:`, error);
-      return false;
-    }
+  // The simulated orders only move the displayed market price. They are not
+  // sent to the sequencer, so the simulator, which starts with the page, never
+  // places orders from the demo wallet; use the order form for that.
+  private logOrder(orderData: SimulatedOrder): void {
+    console.debug(
+      `[TradingBot] Simulated ${orderData.side} order: ${orderData.quantity} @ ${orderData.price}`
+    );
   }
 
   private async simulationLoop() {
@@ -112,7 +58,7 @@ class TradingSimulator {
 
       for (let i = 0; i < orderCount; i++) {
         const order = this.generateRealisticOrder();
-        await this.submitOrder(order);
+        this.logOrder(order);
 
         // Small delay between orders (reduced)
         if (i < orderCount - 1) {
@@ -163,8 +109,6 @@ class TradingSimulator {
 }
 
 // Export singleton instance
-export const tradingSimulator = new TradingSimulator(
-  import.meta.env.VITE_API_URL || "http://localhost:8081"
-);
+export const tradingSimulator = new TradingSimulator();
 
 export default TradingSimulator;
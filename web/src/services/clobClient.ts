@@ -0,0 +1,193 @@
+import axios from "axios";
+import { ethers } from "ethers";
+
+// Client for the sequencer's authenticated trading API. The demo wallet
+// proves control of its address with an L1 ClobAuth signature to obtain an
+// API key, then every trading request is signed with that key's secret in
+// the POLY_* L2 headers (see cmd/README.md).
+
+export interface Credentials {
+  apiKey: string;
+  secret: string;
+  passphrase: string;
+}
+
+export interface OrderRequest {
+  maker: string;
+  takerAsset: string;
+  makeAmount: string;
+  takeAmount: string;
+  price: number;
+  side: "buy" | "sell";
+  timestamp: number;
+  signature: string;
+}
+
+const apiUrl = import.meta.env.VITE_API_URL || "http://localhost:8081";
+const chainId = Number(import.meta.env.VITE_CHAIN_ID || 31337);
+const attestMessage = "This message attests that I control the given wallet";
+
+let wallet: ethers.Wallet | undefined;
+let credentials: Promise<Credentials> | undefined;
+
+// demoWallet returns the wallet of VITE_DEMO_KEY, a local test key
+export function demoWallet(): ethers.Wallet {
+  if (!wallet) {
+    const key = import.meta.env.VITE_DEMO_KEY;
+    if (!key) {
+      throw new Error("Set VITE_DEMO_KEY to place orders from the dashboard");
+    }
+    wallet = new ethers.Wallet(key);
+  }
+  return wallet;
+}
+
+const unixSeconds = () => Math.floor(Date.now() / 1000).toString();
+
+// l1Headers signs the EIP-712 ClobAuth message for nonce 0
+async function l1Headers(signer: ethers.Wallet): Promise<Record<string, string>> {
+  const timestamp = unixSeconds();
+  const signature = await signer.signTypedData(
+    { name: "ClobAuthDomain", version: "1", chainId },
+    {
+      ClobAuth: [
+        { name: "address", type: "address" },
+        { name: "timestamp", type: "string" },
+        { name: "nonce", type: "uint256" },
+        { name: "message", type: "string" },
+      ],
+    },
+    { address: signer.address, timestamp, nonce: 0, message: attestMessage }
+  );
+  return {
+    POLY_ADDRESS: signer.address,
+    POLY_SIGNATURE: signature,
+    POLY_TIMESTAMP: timestamp,
+    POLY_NONCE: "0",
+  };
+}
+
+// apiCredentials derives the demo wallet's API key, creating it on first use
+export function apiCredentials(): Promise<Credentials> {
+  if (!credentials) {
+    credentials = (async () => {
+      const signer = demoWallet();
+      try {
+        const res = await axios.get<Credentials>(`${apiUrl}/auth/derive-api-key`, {
+          headers: await l1Headers(signer),
+          timeout: 5000,
+        });
+        return res.data;
+      } catch (error: any) {
+        if (error.response?.status !== 404) {
+          throw error;
+        }
+      }
+      const res = await axios.post<Credentials>(`${apiUrl}/auth/api-key`, null, {
+        headers: await l1Headers(signer),
+        timeout: 5000,
+      });
+      return res.data;
+    })();
+    // Let a later request retry if the key could not be obtained
+    credentials.catch(() => {
+      credentials = undefined;
+    });
+  }
+  return credentials;
+}
+
+function fromBase64Url(s: string): Uint8Array {
+  const binary = atob(s.replace(/-/g, "+").replace(/_/g, "/"));
+  return Uint8Array.from(binary, (c) => c.charCodeAt(0));
+}
+
+function toBase64Url(bytes: Uint8Array): string {
+  let binary = "";
+  bytes.forEach((b) => (binary += String.fromCharCode(b)));
+  return btoa(binary).replace(/\+/g, "-").replace(/\//g, "_");
+}
+
+// signRequest returns the base64url HMAC-SHA256, keyed by the decoded secret,
+// of timestamp, method, path and body concatenated
+export async function signRequest(
+  secret: string,
+  timestamp: string,
+  method: string,
+  path: string,
+  body: string
+): Promise<string> {
+  const key = await crypto.subtle.importKey(
+    "raw",
+    fromBase64Url(secret),
+    { name: "HMAC", hash: "SHA-256" },
+    false,
+    ["sign"]
+  );
+  const mac = await crypto.subtle.sign(
+    "HMAC",
+    key,
+    new TextEncoder().encode(timestamp + method + path + body)
+  );
+  return toBase64Url(new Uint8Array(mac));
+}
+
+// l2Headers signs a request with the demo wallet's API key
+async function l2Headers(
+  method: string,
+  path: string,
+  body: string
+): Promise<Record<string, string>> {
+  const creds = await apiCredentials();
+  const timestamp = unixSeconds();
+  return {
+    POLY_ADDRESS: demoWallet().address,
+    POLY_API_KEY: creds.apiKey,
+    POLY_PASSPHRASE: creds.passphrase,
+    POLY_TIMESTAMP: timestamp,
+    POLY_SIGNATURE: await signRequest(creds.secret, timestamp, method, path, body),
+  };
+}
+
+// createOrder builds an order of the demo wallet. Both amounts are the size
+// in shares: the matcher fills bids by makeAmount and asks by takeAmount.
+export function createOrder(
+  price: number,
+  quantity: number,
+  side: "buy" | "sell"
+): OrderRequest {
+  const signer = demoWallet();
+  const order: OrderRequest = {
+    maker: signer.address,
+    takerAsset: "0x1234567890123456789012345678901234567890", // Mock asset address
+    makeAmount: quantity.toString(),
+    takeAmount: quantity.toString(),
+    price,
+    side,
+    timestamp: Math.floor(Date.now() / 1000),
+    signature: "",
+  };
+
+  const orderHash = ethers.keccak256(
+    ethers.toUtf8Bytes(
+      `${order.maker}:${order.takerAsset}:${order.makeAmount}:${order.takeAmount}:${order.price}:${order.side}:${order.timestamp}`
+    )
+  );
+  order.signature = signer.signMessageSync(orderHash);
+  return order;
+}
+
+// postOrder submits an order in a request signed with the L2 headers. The
+// signature covers the exact body sent.
+export async function postOrder(order: OrderRequest) {
+  const path = "/orders";
+  const body = JSON.stringify(order);
+  const res = await axios.post(`${apiUrl}${path}`, body, {
+    timeout: 5000,
+    headers: {
+      "Content-Type": "application/json",
+      ...(await l2Headers("POST", path, body)),
+    },
+  });
+  return res.data;
+}
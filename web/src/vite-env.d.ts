@@ -4,6 +4,8 @@ interface ImportMetaEnv {
   readonly VITE_RPC_URL: string;
   readonly VITE_API_URL: string;
   readonly VITE_CONTRACT_ADDRESS: string;
+  readonly VITE_CHAIN_ID?: string;
+  readonly VITE_DEMO_KEY?: string;
 }
 
 interface ImportMeta {